@@ -7,6 +7,7 @@
 package autoscaling
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/xml"
 	"fmt"
@@ -30,11 +31,41 @@ var timeNow = time.Now
 type AutoScaling struct {
 	aws.Auth
 	aws.Region
+	provider aws.CredentialsProvider
+	ctx      context.Context
 }
 
 // New creates a new AutoScaling Client.
 func New(auth aws.Auth, region aws.Region) *AutoScaling {
-	return &AutoScaling{auth, region}
+	return &AutoScaling{Auth: auth, Region: region}
+}
+
+// NewWithProvider creates a new AutoScaling whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) *AutoScaling {
+	c := New(aws.Auth{}, region)
+	c.provider = provider
+	return c
+}
+
+// credentials returns the credentials to sign the next request with.
+func (as *AutoScaling) credentials() (aws.Auth, error) {
+	if as.provider == nil {
+		return as.Auth.Current(), nil
+	}
+	return as.provider.Credentials()
+}
+
+// WithContext returns a copy of as whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (as *AutoScaling) WithContext(ctx context.Context) *AutoScaling {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *as
+	c.ctx = ctx
+	return &c
 }
 
 // ----------------------------------------------------------------------------
@@ -68,7 +99,17 @@ type xmlErrors struct {
 	Errors    []Error `xml:"Error"`
 }
 
+// query makes the request described by params, retrying it once with
+// fresh credentials if the ones it was signed with had expired.
 func (as *AutoScaling) query(params map[string]string, resp interface{}) error {
+	err := as.queryOnce(params, resp)
+	if e, ok := err.(*Error); ok && aws.ExpireCredentials(e.Code, as.provider, as.Auth) {
+		err = as.queryOnce(params, resp)
+	}
+	return err
+}
+
+func (as *AutoScaling) queryOnce(params map[string]string, resp interface{}) error {
 	params["Version"] = "2011-01-01"
 	data := strings.NewReader(multimap(params).Encode())
 
@@ -79,17 +120,24 @@ func (as *AutoScaling) query(params map[string]string, resp interface{}) error {
 
 	hreq.Header.Set("Content-Type", "application/x-www-form-urlencoded; param=value")
 
-	token := as.Auth.Token()
+	auth, err := as.credentials()
+	if err != nil {
+		return err
+	}
+	token := auth.Token()
 	if token != "" {
 		hreq.Header.Set("X-Amz-Security-Token", token)
 	}
 
-	signer := aws.NewV4Signer(as.Auth, "autoscaling", as.Region)
+	signer := aws.NewV4Signer(auth, "autoscaling", as.Region)
 	signer.Sign(hreq)
 
 	if debug {
 		log.Printf("%v -> {\n", hreq)
 	}
+	if as.ctx != nil {
+		hreq = hreq.WithContext(as.ctx)
+	}
 	r, err := http.DefaultClient.Do(hreq)
 
 	if err != nil {
@@ -1,6 +1,7 @@
 package aws_test
 
 import (
+	"context"
 	"time"
 
 	"github.com/goamz/goamz/aws"
@@ -56,3 +57,25 @@ func (S) TestAttemptNextHasNext(c *C) {
 	c.Assert(a.HasNext(), Equals, false)
 	c.Assert(a.Next(), Equals, false)
 }
+
+func (S) TestAttemptWithContext(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	a := aws.AttemptStrategy{Total: 1e9, Min: 5}.StartWithContext(ctx)
+	c.Assert(a.Next(), Equals, true)
+	c.Assert(a.HasNext(), Equals, true)
+	cancel()
+	// HasNext promised another attempt.
+	c.Assert(a.Next(), Equals, true)
+	c.Assert(a.HasNext(), Equals, false)
+	c.Assert(a.Next(), Equals, false)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 0.1e9)
+	defer cancel()
+	a = aws.AttemptStrategy{Total: 5e9, Delay: 1e9}.StartWithContext(ctx)
+	t0 := time.Now()
+	for a.Next() {
+	}
+	if d := time.Since(t0); d > 0.5e9 {
+		c.Errorf("attempts took %v after the context expired", d)
+	}
+}
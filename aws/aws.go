@@ -10,6 +10,8 @@
 package aws
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -18,6 +20,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -58,6 +61,8 @@ type Region struct {
 	STSEndpoint            string
 	CloudFormationEndpoint string
 	ECSEndpoint            string
+	S3Signer               uint // V2Signature or V4Signature, the signature to sign S3 requests with.
+	KMSEndpoint            string
 }
 
 var Regions = map[string]Region{
@@ -94,11 +99,20 @@ type AWSService interface {
 	BuildError(r *http.Response) error
 }
 
+// An AWSService whose queries can be bound to a context, so that they
+// are abandoned when the context is cancelled or its deadline expires.
+type ContextAWSService interface {
+	AWSService
+	// Like Query, but the request is made with ctx.
+	QueryWithContext(ctx context.Context, method, path string, params map[string]string) (*http.Response, error)
+}
+
 // Implements a Server Query/Post API to easily query AWS services and build
 // errors when desired
 type Service struct {
-	service ServiceInfo
-	signer  Signer
+	service  ServiceInfo
+	signer   Signer
+	provider CredentialsProvider
 }
 
 // Create a base set of params for an action
@@ -110,7 +124,29 @@ func MakeParams(action string) map[string]string {
 
 // Create a new AWS server to handle making requests
 func NewService(auth Auth, service ServiceInfo) (s *Service, err error) {
-	var signer Signer
+	signer, err := newSigner(auth, service)
+	if err != nil {
+		return
+	}
+	s = &Service{service: service, signer: signer}
+	if auth.renew != nil {
+		s.provider = auth.renew
+	}
+	return
+}
+
+// NewServiceWithProvider is like NewService, but each request is signed
+// with the credentials supplied by provider at the time it is made.
+func NewServiceWithProvider(provider CredentialsProvider, service ServiceInfo) (s *Service, err error) {
+	// Check the signer is supported before any credentials are needed.
+	if _, err = newSigner(Auth{}, service); err != nil {
+		return
+	}
+	s = &Service{service: service, provider: provider}
+	return
+}
+
+func newSigner(auth Auth, service ServiceInfo) (signer Signer, err error) {
 	switch service.Signer {
 	case V2Signature:
 		signer, err = NewV2Signer(auth, service)
@@ -119,14 +155,37 @@ func NewService(auth Auth, service ServiceInfo) (s *Service, err error) {
 	default:
 		err = fmt.Errorf("Unsupported signer for service")
 	}
-	if err != nil {
+	return
+}
+
+func (s *Service) Query(method, path string, params map[string]string) (resp *http.Response, err error) {
+	return s.QueryWithContext(context.Background(), method, path, params)
+}
+
+// QueryWithContext is like Query, but the request is made with ctx. A
+// request failing because the credentials it was signed with had expired
+// is retried once with fresh credentials, if the service's provider can
+// supply them.
+func (s *Service) QueryWithContext(ctx context.Context, method, path string, params map[string]string) (resp *http.Response, err error) {
+	resp, err = s.query(ctx, method, path, params)
+	if err != nil || resp.StatusCode < 400 || s.provider == nil {
 		return
 	}
-	s = &Service{service: service, signer: signer}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	errors := ErrorResponse{}
+	xml.Unmarshal(body, &errors)
+	if ExpireCredentials(errors.Errors.Code, s.provider, Auth{}) {
+		return s.query(ctx, method, path, params)
+	}
 	return
 }
 
-func (s *Service) Query(method, path string, params map[string]string) (resp *http.Response, err error) {
+func (s *Service) query(ctx context.Context, method, path string, params map[string]string) (resp *http.Response, err error) {
 	params["Timestamp"] = time.Now().UTC().Format(time.RFC3339)
 	u, err := url.Parse(s.service.Endpoint)
 	if err != nil {
@@ -134,15 +193,33 @@ func (s *Service) Query(method, path string, params map[string]string) (resp *ht
 	}
 	u.Path = path
 
-	s.signer.Sign(method, path, params)
+	signer := s.signer
+	if s.provider != nil {
+		auth, err := s.provider.Credentials()
+		if err != nil {
+			return nil, err
+		}
+		if signer, err = newSigner(auth, s.service); err != nil {
+			return nil, err
+		}
+	}
+	signer.Sign(method, path, params)
+	var req *http.Request
 	if method == "GET" {
 		u.RawQuery = multimap(params).Encode()
-		resp, err = http.Get(u.String())
+		req, err = http.NewRequest("GET", u.String(), nil)
 	} else if method == "POST" {
-		resp, err = http.PostForm(u.String(), multimap(params))
+		req, err = http.NewRequest("POST", u.String(), strings.NewReader(multimap(params).Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		return
 	}
-
-	return
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req.WithContext(ctx))
 }
 
 func (s *Service) BuildError(r *http.Response) error {
@@ -181,18 +258,37 @@ type Auth struct {
 	AccessKey, SecretKey string
 	token                string
 	expiration           time.Time
+	renew                *RefreshingProvider // see Current
 }
 
+// Token returns the session token of temporary credentials, or an empty
+// string for long-term ones. As Current, it first renews instance role
+// credentials obtained by GetAuth that are about to expire, but stores
+// the renewed credentials in a, so that its keys match the token
+// returned. Requests sharing a should use Current instead.
 func (a *Auth) Token() string {
-	if a.token == "" {
-		return ""
-	}
-	if time.Since(a.expiration) >= -30*time.Second { //in an ideal world this should be zero assuming the instance is synching it's clock
-		*a, _ = GetAuth("", "", "", time.Time{})
+	if a.renew != nil {
+		*a = a.Current()
 	}
 	return a.token
 }
 
+// Current returns the credentials to sign a request with: a itself or,
+// if a holds instance role credentials obtained by GetAuth that are about
+// to expire, renewed ones. Current does not modify a, so requests sharing
+// a may call it concurrently.
+func (a Auth) Current() Auth {
+	if a.renew == nil {
+		return a
+	}
+	auth, err := a.renew.Credentials()
+	if err != nil {
+		return a
+	}
+	auth.renew = a.renew
+	return auth
+}
+
 func (a *Auth) Expiration() time.Time {
 	return a.expiration
 }
@@ -269,6 +365,23 @@ func GetMetaData(path string) (contents []byte, err error) {
 	return []byte(body), err
 }
 
+// auth returns the Auth for the instance credentials cred.
+func (cred credentials) auth() (auth Auth, err error) {
+	auth.AccessKey = cred.AccessKeyId
+	auth.SecretKey = cred.SecretAccessKey
+	auth.token = cred.Token
+	exptdate, err := time.Parse("2006-01-02T15:04:05Z", cred.Expiration)
+	if err != nil {
+		err = fmt.Errorf("Error Parseing expiration date: cred.Expiration :%s , error: %s \n", cred.Expiration, err)
+	}
+	auth.expiration = exptdate
+	return auth, err
+}
+
+// instanceCredentials caches the instance role credentials returned by
+// GetAuth, so they can be renewed before they expire.
+var instanceCredentials = NewRefreshingProvider(InstanceRoleProvider{})
+
 func getInstanceCredentials() (cred credentials, err error) {
 	credentialPath := "iam/security-credentials/"
 
@@ -293,7 +406,7 @@ func getInstanceCredentials() (cred credentials, err error) {
 func GetAuth(accessKey string, secretKey, token string, expiration time.Time) (auth Auth, err error) {
 	// First try passed in credentials
 	if accessKey != "" && secretKey != "" {
-		return Auth{accessKey, secretKey, token, expiration}, nil
+		return Auth{AccessKey: accessKey, SecretKey: secretKey, token: token, expiration: expiration}, nil
 	}
 
 	// Next try to get auth from the environment
@@ -304,18 +417,11 @@ func GetAuth(accessKey string, secretKey, token string, expiration time.Time) (a
 	}
 
 	// Next try getting auth from the instance role
-	cred, err := getInstanceCredentials()
+	auth, err = instanceCredentials.Credentials()
 	if err == nil {
 		// Found auth, return
-		auth.AccessKey = cred.AccessKeyId
-		auth.SecretKey = cred.SecretAccessKey
-		auth.token = cred.Token
-		exptdate, err := time.Parse("2006-01-02T15:04:05Z", cred.Expiration)
-		if err != nil {
-			err = fmt.Errorf("Error Parseing expiration date: cred.Expiration :%s , error: %s \n", cred.Expiration, err)
-		}
-		auth.expiration = exptdate
-		return auth, err
+		auth.renew = instanceCredentials
+		return
 	}
 	err = errors.New("No valid AWS authentication found")
 	return auth, err
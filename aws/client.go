@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"context"
 	"math"
 	"net"
 	"net/http"
@@ -35,8 +36,9 @@ type ResilientTransport struct {
 // Convenience method for creating an http client
 func NewClient(rt *ResilientTransport) *http.Client {
 	rt.transport = &http.Transport{
-		Dial: func(netw, addr string) (net.Conn, error) {
-			c, err := net.DialTimeout(netw, addr, rt.DialTimeout)
+		DialContext: func(ctx context.Context, netw, addr string) (net.Conn, error) {
+			d := net.Dialer{Timeout: rt.DialTimeout}
+			c, err := d.DialContext(ctx, netw, addr)
 			if err != nil {
 				return nil, err
 			}
@@ -73,24 +75,48 @@ func (t *ResilientTransport) RoundTrip(req *http.Request) (*http.Response, error
 // We'll only retry if the proper criteria are met.
 // If a wait function is specified, wait that amount of time
 // In between requests.
+// Retrying stops as soon as the request's context is done.
 func (t *ResilientTransport) tries(req *http.Request) (res *http.Response, err error) {
+	ctx := req.Context()
 	for try := 0; try < t.MaxTries; try += 1 {
 		res, err = t.transport.RoundTrip(req)
 
-		if !t.ShouldRetry(req, res, err) {
+		if ctx.Err() != nil || !t.ShouldRetry(req, res, err) {
 			break
 		}
 		if res != nil {
 			res.Body.Close()
 		}
 		if t.Wait != nil {
-			t.Wait(try)
+			if err := t.wait(ctx, try); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	return
 }
 
+// wait calls t.Wait, returning early with the context's error
+// if ctx is done before the wait is over.
+func (t *ResilientTransport) wait(ctx context.Context, try int) error {
+	if ctx.Done() == nil {
+		t.Wait(try)
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		t.Wait(try)
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func ExpBackoff(try int) {
 	time.Sleep(100 * time.Millisecond *
 		time.Duration(math.Exp2(float64(try))))
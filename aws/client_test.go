@@ -1,6 +1,7 @@
 package aws_test
 
 import (
+	"context"
 	"fmt"
 	"github.com/goamz/goamz/aws"
 	"io/ioutil"
@@ -119,3 +120,26 @@ func TestClient_fails(t *testing.T) {
 		t.Fatal("Didn't retry enough")
 	}
 }
+
+func TestClient_contextStopsRetries(t *testing.T) {
+	tries := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tries += 1
+		cancel()
+		http.Error(w, "error", 500)
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = aws.RetryingClient.Do(req.WithContext(ctx))
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	if tries != 1 {
+		t.Fatalf("should only try once: %d", tries)
+	}
+}
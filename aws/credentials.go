@@ -0,0 +1,305 @@
+package aws
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A CredentialsProvider supplies the credentials used to sign requests.
+//
+// Clients built with a provider ask it for credentials before every
+// request, so credentials rotated by the provider are picked up without
+// rebuilding the client. Implementations must be safe for concurrent use.
+type CredentialsProvider interface {
+	// Credentials returns the credentials currently in effect.
+	Credentials() (Auth, error)
+}
+
+// StaticProvider always supplies the same credentials.
+type StaticProvider struct {
+	Auth Auth
+}
+
+// NewStaticProvider returns a provider supplying auth.
+func NewStaticProvider(auth Auth) *StaticProvider {
+	return &StaticProvider{Auth: auth}
+}
+
+func (p *StaticProvider) Credentials() (Auth, error) {
+	return p.Auth, nil
+}
+
+// EnvProvider supplies the credentials found in the environment.
+//
+// See EnvAuth for the variables used.
+type EnvProvider struct{}
+
+func (p EnvProvider) Credentials() (Auth, error) {
+	return EnvAuth()
+}
+
+// SharedCredentialsProvider supplies the credentials of a profile in the
+// shared credentials file used by the AWS command line tools and SDKs.
+// Unless Filename is set, those of profiles not in that file are read
+// from the shared config file, as LoadProfile does.
+//
+// The files are read on every call, so changes to them are picked up.
+type SharedCredentialsProvider struct {
+	// Filename is the path of the credentials file. If empty, the
+	// AWS_SHARED_CREDENTIALS_FILE environment variable is used, and
+	// then $HOME/.aws/credentials.
+	Filename string
+
+	// Profile is the name of the profile to read. If empty, the
+	// AWS_PROFILE environment variable is used, and then "default".
+	Profile string
+}
+
+func (p *SharedCredentialsProvider) Credentials() (auth Auth, err error) {
+	filename := p.Filename
+	if filename == "" {
+		filename, err = SharedCredentialsFilename()
+		if err != nil {
+			return
+		}
+	}
+	profile := p.Profile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	sections, err := readINIFile(filename)
+	section, ok := sections[profile]
+	if !ok && p.Filename == "" && (err == nil || os.IsNotExist(err)) {
+		// The profile may hold its credentials in the config file.
+		if configFile, config := sharedConfigSection(profile); config != nil {
+			filename, section, ok, err = configFile, config, true, nil
+		}
+	}
+	if err != nil {
+		return
+	}
+	if !ok {
+		err = fmt.Errorf("profile %q not found in %s", profile, filename)
+		return
+	}
+	auth.AccessKey = section["aws_access_key_id"]
+	auth.SecretKey = section["aws_secret_access_key"]
+	auth.token = section["aws_session_token"]
+	if auth.AccessKey == "" || auth.SecretKey == "" {
+		err = fmt.Errorf("profile %q in %s has no aws_access_key_id or aws_secret_access_key", profile, filename)
+	}
+	return
+}
+
+// SharedCredentialsFilename returns the path of the shared credentials
+// file: the value of AWS_SHARED_CREDENTIALS_FILE if set, and otherwise
+// .aws/credentials in the user's home directory.
+func SharedCredentialsFilename() (string, error) {
+	return sharedFilename("AWS_SHARED_CREDENTIALS_FILE", "credentials")
+}
+
+// sharedFilename returns the value of the environment variable env if
+// set, and otherwise the path of the file called name in the .aws
+// directory of the user's home directory.
+func sharedFilename(env, name string) (string, error) {
+	if filename := os.Getenv(env); filename != "" {
+		return filename, nil
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		home = os.Getenv("USERPROFILE") // Windows
+	}
+	if home == "" {
+		return "", fmt.Errorf("cannot locate the shared %s file: HOME not set", name)
+	}
+	return filepath.Join(home, ".aws", name), nil
+}
+
+// readINIFile parses the INI-style file at filename into a map from
+// section names to their keys and values. Lines starting with '#' or ';'
+// are comments.
+func readINIFile(filename string) (map[string]map[string]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sections := make(map[string]map[string]string)
+	var section map[string]string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "", line[0] == '#', line[0] == ';':
+			continue
+		case line[0] == '[' && line[len(line)-1] == ']':
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if sections[name] == nil {
+				sections[name] = make(map[string]string)
+			}
+			section = sections[name]
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 || section == nil {
+			return nil, fmt.Errorf("%s:%d: invalid line %q", filename, n, line)
+		}
+		section[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// InstanceRoleProvider supplies the credentials of the IAM role the
+// current EC2 instance was launched with, as reported by the instance
+// metadata service.
+type InstanceRoleProvider struct{}
+
+func (p InstanceRoleProvider) Credentials() (Auth, error) {
+	cred, err := getInstanceCredentials()
+	if err != nil {
+		return Auth{}, err
+	}
+	return cred.auth()
+}
+
+// ChainProvider supplies the credentials of the first of its providers
+// that supplies any.
+type ChainProvider []CredentialsProvider
+
+// NewChainProvider returns a provider trying each of providers in turn.
+func NewChainProvider(providers ...CredentialsProvider) ChainProvider {
+	return ChainProvider(providers)
+}
+
+// DefaultChainProvider returns a provider looking for credentials in the
+// environment, then in the shared credentials file, and finally in the
+// instance metadata.
+func DefaultChainProvider() ChainProvider {
+	return NewChainProvider(EnvProvider{}, &SharedCredentialsProvider{}, InstanceRoleProvider{})
+}
+
+func (c ChainProvider) Credentials() (Auth, error) {
+	var errs []string
+	for _, p := range c {
+		auth, err := p.Credentials()
+		if err == nil {
+			return auth, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	if len(errs) == 0 {
+		return Auth{}, errors.New("No valid AWS authentication found")
+	}
+	return Auth{}, fmt.Errorf("No valid AWS authentication found: %s", strings.Join(errs, "; "))
+}
+
+// DefaultRefreshWindow is how long before their expiration
+// RefreshingProvider renews credentials by default.
+const DefaultRefreshWindow = 5 * time.Minute
+
+// An Expirer caches credentials and can be told to discard them, so that
+// they are renewed the next time they are needed.
+type Expirer interface {
+	Expire()
+}
+
+// RefreshingProvider caches the temporary credentials supplied by another
+// provider, such as InstanceRoleProvider or sts.AssumeRoleProvider, and
+// renews them shortly before they expire.
+//
+// Renewal happens once, however many goroutines ask for credentials at
+// the time. If renewal fails, the cached credentials are used for as long
+// as they remain valid.
+type RefreshingProvider struct {
+	// Provider supplies the credentials to cache.
+	Provider CredentialsProvider
+
+	// Window is how long before their expiration credentials are
+	// renewed. If zero, DefaultRefreshWindow is used.
+	Window time.Duration
+
+	mu     sync.Mutex
+	auth   Auth
+	cached bool
+}
+
+// NewRefreshingProvider returns a provider caching the credentials
+// supplied by provider.
+func NewRefreshingProvider(provider CredentialsProvider) *RefreshingProvider {
+	return &RefreshingProvider{Provider: provider}
+}
+
+func (p *RefreshingProvider) Credentials() (Auth, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cached && !p.expiring() {
+		return p.auth, nil
+	}
+	auth, err := p.Provider.Credentials()
+	if err != nil {
+		if p.cached && (p.auth.expiration.IsZero() || time.Now().Before(p.auth.expiration)) {
+			return p.auth, nil
+		}
+		return Auth{}, err
+	}
+	p.auth, p.cached = auth, true
+	return auth, nil
+}
+
+// Expire discards the cached credentials.
+func (p *RefreshingProvider) Expire() {
+	p.mu.Lock()
+	p.cached = false
+	p.mu.Unlock()
+}
+
+// expiring reports whether the cached credentials are due for renewal.
+func (p *RefreshingProvider) expiring() bool {
+	if p.auth.expiration.IsZero() {
+		return false
+	}
+	window := p.Window
+	if window == 0 {
+		window = DefaultRefreshWindow
+	}
+	return time.Now().Add(window).After(p.auth.expiration)
+}
+
+// ExpireCredentials reports whether a request that failed with the AWS
+// error code code should be retried, because the credentials it was signed
+// with had expired and fresh ones can be obtained. If so, the expired
+// credentials are discarded from provider or, if provider is nil, from the
+// cache backing auth.
+//
+// Only providers implementing Expirer, and credentials obtained from the
+// instance metadata by GetAuth, can be renewed.
+func ExpireCredentials(code string, provider CredentialsProvider, auth Auth) bool {
+	if code != "ExpiredToken" && code != "ExpiredTokenException" {
+		return false
+	}
+	var e Expirer
+	if provider != nil {
+		e, _ = provider.(Expirer)
+	} else if auth.renew != nil {
+		e = auth.renew
+	}
+	if e == nil {
+		return false
+	}
+	e.Expire()
+	return true
+}
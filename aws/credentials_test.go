@@ -0,0 +1,276 @@
+package aws_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/goamz/goamz/aws"
+	. "gopkg.in/check.v1"
+)
+
+var sharedCredentials = `
+# Comment
+[default]
+aws_access_key_id = access
+aws_secret_access_key = secret
+
+[other]
+aws_access_key_id=other-access
+aws_secret_access_key=other-secret
+aws_session_token=other-token
+
+[incomplete]
+aws_access_key_id = access
+`
+
+func writeCredentials(c *C, content string) string {
+	filename := filepath.Join(c.MkDir(), "credentials")
+	err := ioutil.WriteFile(filename, []byte(content), 0600)
+	c.Assert(err, IsNil)
+	return filename
+}
+
+func (s *S) TestStaticProvider(c *C) {
+	p := aws.NewStaticProvider(aws.Auth{AccessKey: "access", SecretKey: "secret"})
+	auth, err := p.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth, Equals, aws.Auth{AccessKey: "access", SecretKey: "secret"})
+}
+
+func (s *S) TestEnvProvider(c *C) {
+	os.Clearenv()
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	os.Setenv("AWS_ACCESS_KEY_ID", "access")
+	auth, err := aws.EnvProvider{}.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth, Equals, aws.Auth{AccessKey: "access", SecretKey: "secret"})
+}
+
+func (s *S) TestSharedCredentialsProvider(c *C) {
+	os.Clearenv()
+	filename := writeCredentials(c, sharedCredentials)
+
+	p := &aws.SharedCredentialsProvider{Filename: filename}
+	auth, err := p.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth, Equals, aws.Auth{AccessKey: "access", SecretKey: "secret"})
+
+	p = &aws.SharedCredentialsProvider{Filename: filename, Profile: "other"}
+	auth, err = p.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth.AccessKey, Equals, "other-access")
+	c.Assert(auth.SecretKey, Equals, "other-secret")
+	c.Assert(auth.Token(), Equals, "other-token")
+}
+
+func (s *S) TestSharedCredentialsProviderEnv(c *C) {
+	os.Clearenv()
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", writeCredentials(c, sharedCredentials))
+	os.Setenv("AWS_PROFILE", "other")
+	auth, err := (&aws.SharedCredentialsProvider{}).Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth.AccessKey, Equals, "other-access")
+
+	os.Clearenv()
+	os.Setenv("HOME", filepath.Dir(filepath.Dir(writeCredentials(c, "[default]\naws_access_key_id=a\naws_secret_access_key=s\n"))))
+	_, err = (&aws.SharedCredentialsProvider{}).Credentials()
+	c.Assert(err, ErrorMatches, "open .*/.aws/credentials: no such file or directory")
+}
+
+func (s *S) TestSharedCredentialsProviderErrors(c *C) {
+	os.Clearenv()
+	filename := writeCredentials(c, sharedCredentials)
+
+	p := &aws.SharedCredentialsProvider{Filename: filename, Profile: "missing"}
+	_, err := p.Credentials()
+	c.Assert(err, ErrorMatches, `profile "missing" not found in .*`)
+
+	p = &aws.SharedCredentialsProvider{Filename: filename, Profile: "incomplete"}
+	_, err = p.Credentials()
+	c.Assert(err, ErrorMatches, `profile "incomplete" in .* has no aws_access_key_id or aws_secret_access_key`)
+
+	p = &aws.SharedCredentialsProvider{Filename: writeCredentials(c, "key = value\n")}
+	_, err = p.Credentials()
+	c.Assert(err, ErrorMatches, `.*:1: invalid line "key = value"`)
+}
+
+type failingProvider struct{ err error }
+
+func (p failingProvider) Credentials() (aws.Auth, error) {
+	return aws.Auth{}, p.err
+}
+
+func (s *S) TestChainProvider(c *C) {
+	want := aws.Auth{AccessKey: "access", SecretKey: "secret"}
+	p := aws.NewChainProvider(
+		failingProvider{errors.New("first")},
+		aws.NewStaticProvider(want),
+		failingProvider{errors.New("third")},
+	)
+	auth, err := p.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth, Equals, want)
+
+	p = aws.NewChainProvider(failingProvider{errors.New("first")}, failingProvider{errors.New("second")})
+	_, err = p.Credentials()
+	c.Assert(err, ErrorMatches, "No valid AWS authentication found: first; second")
+}
+
+// sequenceProvider supplies credentials expiring at the given times in
+// turn, with access keys "key1", "key2", ..., and then fails.
+type sequenceProvider struct {
+	expirations []time.Time
+	calls       int
+}
+
+func (p *sequenceProvider) Credentials() (aws.Auth, error) {
+	if p.calls == len(p.expirations) {
+		return aws.Auth{}, errors.New("no more credentials")
+	}
+	p.calls++
+	key := fmt.Sprintf("key%d", p.calls)
+	return *aws.NewAuth(key, "secret", "token", p.expirations[p.calls-1]), nil
+}
+
+func (s *S) TestRefreshingProviderCaches(c *C) {
+	inner := &sequenceProvider{expirations: []time.Time{time.Now().Add(time.Hour)}}
+	p := aws.NewRefreshingProvider(inner)
+	for i := 0; i < 3; i++ {
+		auth, err := p.Credentials()
+		c.Assert(err, IsNil)
+		c.Assert(auth.AccessKey, Equals, "key1")
+	}
+	c.Assert(inner.calls, Equals, 1)
+}
+
+func (s *S) TestRefreshingProviderRenewsBeforeExpiry(c *C) {
+	inner := &sequenceProvider{expirations: []time.Time{
+		time.Now().Add(time.Minute),
+		time.Now().Add(time.Hour),
+	}}
+	p := aws.NewRefreshingProvider(inner)
+	auth, err := p.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth.AccessKey, Equals, "key1")
+
+	// key1 expires within DefaultRefreshWindow, so it is renewed.
+	auth, err = p.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth.AccessKey, Equals, "key2")
+	auth, err = p.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth.AccessKey, Equals, "key2")
+	c.Assert(inner.calls, Equals, 2)
+}
+
+func (s *S) TestRefreshingProviderWindow(c *C) {
+	inner := &sequenceProvider{expirations: []time.Time{
+		time.Now().Add(time.Minute),
+		time.Now().Add(time.Hour),
+	}}
+	p := aws.NewRefreshingProvider(inner)
+	p.Window = time.Second
+	for i := 0; i < 2; i++ {
+		auth, err := p.Credentials()
+		c.Assert(err, IsNil)
+		c.Assert(auth.AccessKey, Equals, "key1")
+	}
+}
+
+func (s *S) TestRefreshingProviderKeepsValidOnFailure(c *C) {
+	inner := &sequenceProvider{expirations: []time.Time{time.Now().Add(time.Minute)}}
+	p := aws.NewRefreshingProvider(inner)
+	_, err := p.Credentials()
+	c.Assert(err, IsNil)
+
+	// Renewal fails, but key1 has not expired yet.
+	auth, err := p.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth.AccessKey, Equals, "key1")
+	c.Assert(inner.calls, Equals, 1)
+}
+
+func (s *S) TestRefreshingProviderExpired(c *C) {
+	inner := &sequenceProvider{expirations: []time.Time{time.Now().Add(-time.Minute)}}
+	p := aws.NewRefreshingProvider(inner)
+	_, err := p.Credentials()
+	c.Assert(err, IsNil)
+	_, err = p.Credentials()
+	c.Assert(err, ErrorMatches, "no more credentials")
+}
+
+func (s *S) TestRefreshingProviderExpire(c *C) {
+	inner := &sequenceProvider{expirations: []time.Time{
+		time.Now().Add(time.Hour),
+		time.Now().Add(time.Hour),
+	}}
+	p := aws.NewRefreshingProvider(inner)
+	auth, err := p.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth.AccessKey, Equals, "key1")
+	p.Expire()
+	auth, err = p.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth.AccessKey, Equals, "key2")
+}
+
+func (s *S) TestRefreshingProviderConcurrent(c *C) {
+	inner := &sequenceProvider{expirations: []time.Time{time.Now().Add(time.Hour)}}
+	p := aws.NewRefreshingProvider(inner)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Credentials()
+		}()
+	}
+	wg.Wait()
+	c.Assert(inner.calls, Equals, 1)
+}
+
+func (s *S) TestExpireCredentials(c *C) {
+	inner := &sequenceProvider{expirations: []time.Time{
+		time.Now().Add(time.Hour),
+		time.Now().Add(time.Hour),
+	}}
+	p := aws.NewRefreshingProvider(inner)
+	p.Credentials()
+
+	c.Assert(aws.ExpireCredentials("AccessDenied", p, aws.Auth{}), Equals, false)
+	auth, _ := p.Credentials()
+	c.Assert(auth.AccessKey, Equals, "key1")
+
+	c.Assert(aws.ExpireCredentials("ExpiredToken", p, aws.Auth{}), Equals, true)
+	auth, _ = p.Credentials()
+	c.Assert(auth.AccessKey, Equals, "key2")
+
+	// Neither static credentials nor a provider that cannot be told to
+	// renew its credentials are worth retrying with.
+	c.Assert(aws.ExpireCredentials("ExpiredToken", nil, aws.Auth{AccessKey: "access"}), Equals, false)
+	c.Assert(aws.ExpireCredentials("ExpiredTokenException", inner, aws.Auth{}), Equals, false)
+}
+
+func (s *S) TestAuthCurrent(c *C) {
+	auth := aws.Auth{AccessKey: "access", SecretKey: "secret"}
+	c.Assert(auth.Current(), Equals, auth)
+}
+
+func (s *S) TestAuthTokenRenews(c *C) {
+	inner := &sequenceProvider{expirations: []time.Time{
+		time.Now().Add(time.Minute),
+		time.Now().Add(time.Hour),
+	}}
+	auth := aws.RenewingAuth(aws.NewRefreshingProvider(inner))
+	c.Assert(auth.AccessKey, Equals, "key1")
+
+	// key1 expires within DefaultRefreshWindow, so Token renews it.
+	c.Assert(auth.Token(), Equals, "token")
+	c.Assert(auth.AccessKey, Equals, "key2")
+	c.Assert(inner.calls, Equals, 2)
+}
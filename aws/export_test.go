@@ -27,3 +27,11 @@ func (s *V4Signer) Signature(t time.Time, sts string) string {
 func (s *V4Signer) Authorization(header http.Header, t time.Time, signature string) string {
 	return s.authorization(header, t, signature)
 }
+
+// RenewingAuth returns the credentials supplied by p, renewed by Current
+// and Token as those obtained by GetAuth are.
+func RenewingAuth(p *RefreshingProvider) Auth {
+	auth, _ := p.Credentials()
+	auth.renew = p
+	return auth
+}
@@ -0,0 +1,176 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// A Profile holds the settings of a named profile, as found in the shared
+// credentials file and the shared config file used by the AWS command
+// line tools and SDKs.
+type Profile struct {
+	Name string
+
+	// Auth holds the credentials of the profile, if any. Credentials
+	// in the credentials file take precedence over those in the
+	// config file.
+	Auth Auth
+
+	// Region is the name of the default region of the profile.
+	Region string
+
+	// RoleARN, when set, names the role to assume to obtain the
+	// credentials of the profile, using those of SourceProfile.
+	RoleARN         string
+	SourceProfile   string
+	ExternalId      string
+	RoleSessionName string
+	DurationSeconds int
+}
+
+// LoadProfile reads the profile called name from the shared credentials
+// file and the shared config file. If name is empty, the AWS_PROFILE
+// environment variable is used, and then "default".
+//
+// See SharedCredentialsFilename and SharedConfigFilename for the files
+// read. Either file may be missing, but the profile must be found in at
+// least one of them.
+func LoadProfile(name string) (*Profile, error) {
+	if name == "" {
+		name = os.Getenv("AWS_PROFILE")
+	}
+	if name == "" {
+		name = "default"
+	}
+	credsFile, err := SharedCredentialsFilename()
+	if err != nil {
+		return nil, err
+	}
+	configFile, err := SharedConfigFilename()
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := readProfileSection(credsFile, name)
+	if err != nil {
+		return nil, err
+	}
+	config, err := readProfileSection(configFile, configProfileSection(name))
+	if err != nil {
+		return nil, err
+	}
+	if creds == nil && config == nil {
+		return nil, fmt.Errorf("profile %q not found in %s or %s", name, credsFile, configFile)
+	}
+
+	// Settings in the credentials file win.
+	section := make(map[string]string)
+	for k, v := range config {
+		section[k] = v
+	}
+	for k, v := range creds {
+		section[k] = v
+	}
+
+	p := &Profile{
+		Name:            name,
+		Region:          section["region"],
+		RoleARN:         section["role_arn"],
+		SourceProfile:   section["source_profile"],
+		ExternalId:      section["external_id"],
+		RoleSessionName: section["role_session_name"],
+	}
+	p.Auth.AccessKey = section["aws_access_key_id"]
+	p.Auth.SecretKey = section["aws_secret_access_key"]
+	p.Auth.token = section["aws_session_token"]
+	if s := section["duration_seconds"]; s != "" {
+		p.DurationSeconds, err = strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: invalid duration_seconds %q", name, s)
+		}
+	}
+	if p.RoleARN != "" && p.SourceProfile == "" {
+		return nil, fmt.Errorf("profile %q has a role_arn but no source_profile", name)
+	}
+	return p, nil
+}
+
+// readProfileSection returns the keys and values of the named section of
+// filename, or nil if either the file or the section does not exist.
+func readProfileSection(filename, name string) (map[string]string, error) {
+	sections, err := readINIFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sections[name], nil
+}
+
+// configProfileSection returns the name of the section of the shared
+// config file holding the profile called name: profiles other than the
+// default one are in [profile name] sections.
+func configProfileSection(name string) string {
+	if name == "default" {
+		return name
+	}
+	return "profile " + name
+}
+
+// sharedConfigSection returns the path of the shared config file and the
+// keys and values of the profile called name in it, or nil if it cannot
+// be read or has no such profile.
+func sharedConfigSection(name string) (string, map[string]string) {
+	filename, err := SharedConfigFilename()
+	if err != nil {
+		return "", nil
+	}
+	section, err := readProfileSection(filename, configProfileSection(name))
+	if err != nil {
+		return "", nil
+	}
+	return filename, section
+}
+
+// HasCredentials reports whether the profile holds credentials of its own.
+func (p *Profile) HasCredentials() bool {
+	return p.Auth.AccessKey != "" && p.Auth.SecretKey != ""
+}
+
+// RegionName returns the name of the region requests made on behalf of
+// the profile should go to: the one named by the AWS_REGION or
+// AWS_DEFAULT_REGION environment variables if set, and otherwise the
+// region of the profile, if any.
+func (p *Profile) RegionName() string {
+	name := os.Getenv("AWS_REGION")
+	if name == "" {
+		name = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if name == "" {
+		name = p.Region
+	}
+	return name
+}
+
+// DefaultRegion returns the region named by RegionName, failing if there
+// is none or it is unknown.
+func (p *Profile) DefaultRegion() (Region, error) {
+	name := p.RegionName()
+	if name == "" {
+		return Region{}, fmt.Errorf("profile %q has no region", p.Name)
+	}
+	region, ok := Regions[name]
+	if !ok {
+		return Region{}, fmt.Errorf("profile %q: unknown region %q", p.Name, name)
+	}
+	return region, nil
+}
+
+// SharedConfigFilename returns the path of the shared config file: the
+// value of AWS_CONFIG_FILE if set, and otherwise .aws/config in the
+// user's home directory.
+func SharedConfigFilename() (string, error) {
+	return sharedFilename("AWS_CONFIG_FILE", "config")
+}
@@ -0,0 +1,147 @@
+package aws_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/goamz/goamz/aws"
+	. "gopkg.in/check.v1"
+)
+
+var sharedConfig = `
+[default]
+region = us-west-2
+
+[profile other]
+region = eu-west-1
+aws_access_key_id = config-access
+aws_secret_access_key = config-secret
+
+[profile admin]
+role_arn = arn:aws:iam::123456789012:role/admin
+source_profile = default
+external_id = xyz
+duration_seconds = 900
+
+[profile broken]
+role_arn = arn:aws:iam::123456789012:role/admin
+`
+
+// writeSharedFiles writes the shared credentials and config files in a
+// temporary home directory and points the environment at them.
+func writeSharedFiles(c *C, credentials, config string) {
+	os.Clearenv()
+	home := c.MkDir()
+	dir := filepath.Join(home, ".aws")
+	c.Assert(os.Mkdir(dir, 0700), IsNil)
+	if credentials != "" {
+		err := ioutil.WriteFile(filepath.Join(dir, "credentials"), []byte(credentials), 0600)
+		c.Assert(err, IsNil)
+	}
+	if config != "" {
+		err := ioutil.WriteFile(filepath.Join(dir, "config"), []byte(config), 0600)
+		c.Assert(err, IsNil)
+	}
+	os.Setenv("HOME", home)
+}
+
+func (s *S) TestLoadProfile(c *C) {
+	writeSharedFiles(c, sharedCredentials, sharedConfig)
+
+	p, err := aws.LoadProfile("")
+	c.Assert(err, IsNil)
+	c.Assert(p.Name, Equals, "default")
+	c.Assert(p.Auth, Equals, aws.Auth{AccessKey: "access", SecretKey: "secret"})
+	c.Assert(p.Region, Equals, "us-west-2")
+	c.Assert(p.HasCredentials(), Equals, true)
+
+	// Credentials in the credentials file win over those in the config file.
+	os.Setenv("AWS_PROFILE", "other")
+	p, err = aws.LoadProfile("")
+	c.Assert(err, IsNil)
+	c.Assert(p.Name, Equals, "other")
+	c.Assert(p.Auth.AccessKey, Equals, "other-access")
+	c.Assert(p.Auth.Token(), Equals, "other-token")
+	c.Assert(p.Region, Equals, "eu-west-1")
+}
+
+func (s *S) TestLoadProfileRole(c *C) {
+	writeSharedFiles(c, sharedCredentials, sharedConfig)
+
+	p, err := aws.LoadProfile("admin")
+	c.Assert(err, IsNil)
+	c.Assert(p.HasCredentials(), Equals, false)
+	c.Assert(p.RoleARN, Equals, "arn:aws:iam::123456789012:role/admin")
+	c.Assert(p.SourceProfile, Equals, "default")
+	c.Assert(p.ExternalId, Equals, "xyz")
+	c.Assert(p.DurationSeconds, Equals, 900)
+}
+
+func (s *S) TestLoadProfileConfigOnly(c *C) {
+	writeSharedFiles(c, "", sharedConfig)
+
+	p, err := aws.LoadProfile("other")
+	c.Assert(err, IsNil)
+	c.Assert(p.Auth, Equals, aws.Auth{AccessKey: "config-access", SecretKey: "config-secret"})
+}
+
+func (s *S) TestLoadProfileErrors(c *C) {
+	writeSharedFiles(c, sharedCredentials, sharedConfig)
+
+	_, err := aws.LoadProfile("missing")
+	c.Assert(err, ErrorMatches, `profile "missing" not found in .*/credentials or .*/config`)
+
+	_, err = aws.LoadProfile("broken")
+	c.Assert(err, ErrorMatches, `profile "broken" has a role_arn but no source_profile`)
+
+	writeSharedFiles(c, "", "")
+	_, err = aws.LoadProfile("")
+	c.Assert(err, ErrorMatches, `profile "default" not found in .*`)
+}
+
+func (s *S) TestProfileDefaultRegion(c *C) {
+	writeSharedFiles(c, sharedCredentials, sharedConfig)
+
+	p, err := aws.LoadProfile("")
+	c.Assert(err, IsNil)
+	region, err := p.DefaultRegion()
+	c.Assert(err, IsNil)
+	c.Assert(region, DeepEquals, aws.USWest2)
+
+	os.Setenv("AWS_DEFAULT_REGION", "eu-west-1")
+	region, err = p.DefaultRegion()
+	c.Assert(err, IsNil)
+	c.Assert(region, DeepEquals, aws.EUWest)
+
+	os.Setenv("AWS_REGION", "nowhere-1")
+	c.Assert(p.RegionName(), Equals, "nowhere-1")
+	_, err = p.DefaultRegion()
+	c.Assert(err, ErrorMatches, `profile "default": unknown region "nowhere-1"`)
+
+	p, err = aws.LoadProfile("admin")
+	c.Assert(err, IsNil)
+	os.Clearenv()
+	_, err = p.DefaultRegion()
+	c.Assert(err, ErrorMatches, `profile "admin" has no region`)
+}
+
+func (s *S) TestSharedCredentialsProviderConfig(c *C) {
+	writeSharedFiles(c, sharedCredentials, sharedConfig)
+
+	// Credentials in the credentials file win over those in the config file.
+	auth, err := (&aws.SharedCredentialsProvider{Profile: "other"}).Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth.AccessKey, Equals, "other-access")
+
+	_, err = (&aws.SharedCredentialsProvider{Profile: "admin"}).Credentials()
+	c.Assert(err, ErrorMatches, `profile "admin" in .*/config has no aws_access_key_id or aws_secret_access_key`)
+
+	writeSharedFiles(c, "", sharedConfig)
+	auth, err = (&aws.SharedCredentialsProvider{Profile: "other"}).Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(auth, Equals, aws.Auth{AccessKey: "config-access", SecretKey: "config-secret"})
+
+	_, err = (&aws.SharedCredentialsProvider{Profile: "missing"}).Credentials()
+	c.Assert(err, ErrorMatches, "open .*/.aws/credentials: no such file or directory")
+}
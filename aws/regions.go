@@ -1,243 +1,244 @@
 package aws
 
 var USGovWest = Region{
-	"us-gov-west-1",
-	"https://ec2.us-gov-west-1.amazonaws.com",
-	"https://s3-fips-us-gov-west-1.amazonaws.com",
-	"",
-	true,
-	true,
-	"",
-	"",
-	"https://sns.us-gov-west-1.amazonaws.com",
-	"https://sqs.us-gov-west-1.amazonaws.com",
-	"https://iam.us-gov.amazonaws.com",
-	"https://elasticloadbalancing.us-gov-west-1.amazonaws.com",
-	"https://dynamodb.us-gov-west-1.amazonaws.com",
-	ServiceInfo{"https://monitoring.us-gov-west-1.amazonaws.com", V2Signature},
-	"https://autoscaling.us-gov-west-1.amazonaws.com",
-	ServiceInfo{"https://rds.us-gov-west-1.amazonaws.com", V2Signature},
-	"https://sts.amazonaws.com",
-	"https://cloudformation.us-gov-west-1.amazonaws.com",
-	"https://ecs.us-gov-west-1.amazonaws.com",
+	Name:                   "us-gov-west-1",
+	EC2Endpoint:            "https://ec2.us-gov-west-1.amazonaws.com",
+	S3Endpoint:             "https://s3-fips-us-gov-west-1.amazonaws.com",
+	S3LocationConstraint:   true,
+	S3LowercaseBucket:      true,
+	SNSEndpoint:            "https://sns.us-gov-west-1.amazonaws.com",
+	SQSEndpoint:            "https://sqs.us-gov-west-1.amazonaws.com",
+	IAMEndpoint:            "https://iam.us-gov.amazonaws.com",
+	ELBEndpoint:            "https://elasticloadbalancing.us-gov-west-1.amazonaws.com",
+	DynamoDBEndpoint:       "https://dynamodb.us-gov-west-1.amazonaws.com",
+	CloudWatchServicepoint: ServiceInfo{"https://monitoring.us-gov-west-1.amazonaws.com", V2Signature},
+	AutoScalingEndpoint:    "https://autoscaling.us-gov-west-1.amazonaws.com",
+	RDSEndpoint:            ServiceInfo{"https://rds.us-gov-west-1.amazonaws.com", V2Signature},
+	STSEndpoint:            "https://sts.amazonaws.com",
+	CloudFormationEndpoint: "https://cloudformation.us-gov-west-1.amazonaws.com",
+	ECSEndpoint:            "https://ecs.us-gov-west-1.amazonaws.com",
+	S3Signer:               V2Signature,
+	KMSEndpoint:            "https://kms.us-gov-west-1.amazonaws.com",
 }
 
 var USEast = Region{
-	"us-east-1",
-	"https://ec2.us-east-1.amazonaws.com",
-	"https://s3.amazonaws.com",
-	"",
-	false,
-	false,
-	"https://sdb.amazonaws.com",
-	"https://email.us-east-1.amazonaws.com",
-	"https://sns.us-east-1.amazonaws.com",
-	"https://sqs.us-east-1.amazonaws.com",
-	"https://iam.amazonaws.com",
-	"https://elasticloadbalancing.us-east-1.amazonaws.com",
-	"https://dynamodb.us-east-1.amazonaws.com",
-	ServiceInfo{"https://monitoring.us-east-1.amazonaws.com", V2Signature},
-	"https://autoscaling.us-east-1.amazonaws.com",
-	ServiceInfo{"https://rds.us-east-1.amazonaws.com", V2Signature},
-	"https://sts.amazonaws.com",
-	"https://cloudformation.us-east-1.amazonaws.com",
-	"https://ecs.us-east-1.amazonaws.com",
+	Name:                   "us-east-1",
+	EC2Endpoint:            "https://ec2.us-east-1.amazonaws.com",
+	S3Endpoint:             "https://s3.amazonaws.com",
+	SDBEndpoint:            "https://sdb.amazonaws.com",
+	SESEndpoint:            "https://email.us-east-1.amazonaws.com",
+	SNSEndpoint:            "https://sns.us-east-1.amazonaws.com",
+	SQSEndpoint:            "https://sqs.us-east-1.amazonaws.com",
+	IAMEndpoint:            "https://iam.amazonaws.com",
+	ELBEndpoint:            "https://elasticloadbalancing.us-east-1.amazonaws.com",
+	DynamoDBEndpoint:       "https://dynamodb.us-east-1.amazonaws.com",
+	CloudWatchServicepoint: ServiceInfo{"https://monitoring.us-east-1.amazonaws.com", V2Signature},
+	AutoScalingEndpoint:    "https://autoscaling.us-east-1.amazonaws.com",
+	RDSEndpoint:            ServiceInfo{"https://rds.us-east-1.amazonaws.com", V2Signature},
+	STSEndpoint:            "https://sts.amazonaws.com",
+	CloudFormationEndpoint: "https://cloudformation.us-east-1.amazonaws.com",
+	ECSEndpoint:            "https://ecs.us-east-1.amazonaws.com",
+	S3Signer:               V2Signature,
+	KMSEndpoint:            "https://kms.us-east-1.amazonaws.com",
 }
 
 var USWest = Region{
-	"us-west-1",
-	"https://ec2.us-west-1.amazonaws.com",
-	"https://s3-us-west-1.amazonaws.com",
-	"",
-	true,
-	true,
-	"https://sdb.us-west-1.amazonaws.com",
-	"",
-	"https://sns.us-west-1.amazonaws.com",
-	"https://sqs.us-west-1.amazonaws.com",
-	"https://iam.amazonaws.com",
-	"https://elasticloadbalancing.us-west-1.amazonaws.com",
-	"https://dynamodb.us-west-1.amazonaws.com",
-	ServiceInfo{"https://monitoring.us-west-1.amazonaws.com", V2Signature},
-	"https://autoscaling.us-west-1.amazonaws.com",
-	ServiceInfo{"https://rds.us-west-1.amazonaws.com", V2Signature},
-	"https://sts.amazonaws.com",
-	"https://cloudformation.us-west-1.amazonaws.com",
-	"https://ecs.us-west-1.amazonaws.com",
+	Name:                   "us-west-1",
+	EC2Endpoint:            "https://ec2.us-west-1.amazonaws.com",
+	S3Endpoint:             "https://s3-us-west-1.amazonaws.com",
+	S3LocationConstraint:   true,
+	S3LowercaseBucket:      true,
+	SDBEndpoint:            "https://sdb.us-west-1.amazonaws.com",
+	SNSEndpoint:            "https://sns.us-west-1.amazonaws.com",
+	SQSEndpoint:            "https://sqs.us-west-1.amazonaws.com",
+	IAMEndpoint:            "https://iam.amazonaws.com",
+	ELBEndpoint:            "https://elasticloadbalancing.us-west-1.amazonaws.com",
+	DynamoDBEndpoint:       "https://dynamodb.us-west-1.amazonaws.com",
+	CloudWatchServicepoint: ServiceInfo{"https://monitoring.us-west-1.amazonaws.com", V2Signature},
+	AutoScalingEndpoint:    "https://autoscaling.us-west-1.amazonaws.com",
+	RDSEndpoint:            ServiceInfo{"https://rds.us-west-1.amazonaws.com", V2Signature},
+	STSEndpoint:            "https://sts.amazonaws.com",
+	CloudFormationEndpoint: "https://cloudformation.us-west-1.amazonaws.com",
+	ECSEndpoint:            "https://ecs.us-west-1.amazonaws.com",
+	S3Signer:               V2Signature,
+	KMSEndpoint:            "https://kms.us-west-1.amazonaws.com",
 }
 
 var USWest2 = Region{
-	"us-west-2",
-	"https://ec2.us-west-2.amazonaws.com",
-	"https://s3-us-west-2.amazonaws.com",
-	"",
-	true,
-	true,
-	"https://sdb.us-west-2.amazonaws.com",
-	"https://email.us-west-2.amazonaws.com",
-	"https://sns.us-west-2.amazonaws.com",
-	"https://sqs.us-west-2.amazonaws.com",
-	"https://iam.amazonaws.com",
-	"https://elasticloadbalancing.us-west-2.amazonaws.com",
-	"https://dynamodb.us-west-2.amazonaws.com",
-	ServiceInfo{"https://monitoring.us-west-2.amazonaws.com", V2Signature},
-	"https://autoscaling.us-west-2.amazonaws.com",
-	ServiceInfo{"https://rds.us-west-2.amazonaws.com", V2Signature},
-	"https://sts.amazonaws.com",
-	"https://cloudformation.us-west-2.amazonaws.com",
-	"https://ecs.us-west-2.amazonaws.com",
+	Name:                   "us-west-2",
+	EC2Endpoint:            "https://ec2.us-west-2.amazonaws.com",
+	S3Endpoint:             "https://s3-us-west-2.amazonaws.com",
+	S3LocationConstraint:   true,
+	S3LowercaseBucket:      true,
+	SDBEndpoint:            "https://sdb.us-west-2.amazonaws.com",
+	SESEndpoint:            "https://email.us-west-2.amazonaws.com",
+	SNSEndpoint:            "https://sns.us-west-2.amazonaws.com",
+	SQSEndpoint:            "https://sqs.us-west-2.amazonaws.com",
+	IAMEndpoint:            "https://iam.amazonaws.com",
+	ELBEndpoint:            "https://elasticloadbalancing.us-west-2.amazonaws.com",
+	DynamoDBEndpoint:       "https://dynamodb.us-west-2.amazonaws.com",
+	CloudWatchServicepoint: ServiceInfo{"https://monitoring.us-west-2.amazonaws.com", V2Signature},
+	AutoScalingEndpoint:    "https://autoscaling.us-west-2.amazonaws.com",
+	RDSEndpoint:            ServiceInfo{"https://rds.us-west-2.amazonaws.com", V2Signature},
+	STSEndpoint:            "https://sts.amazonaws.com",
+	CloudFormationEndpoint: "https://cloudformation.us-west-2.amazonaws.com",
+	ECSEndpoint:            "https://ecs.us-west-2.amazonaws.com",
+	S3Signer:               V2Signature,
+	KMSEndpoint:            "https://kms.us-west-2.amazonaws.com",
 }
 
 var EUWest = Region{
-	"eu-west-1",
-	"https://ec2.eu-west-1.amazonaws.com",
-	"https://s3-eu-west-1.amazonaws.com",
-	"",
-	true,
-	true,
-	"https://sdb.eu-west-1.amazonaws.com",
-	"https://email.eu-west-1.amazonaws.com",
-	"https://sns.eu-west-1.amazonaws.com",
-	"https://sqs.eu-west-1.amazonaws.com",
-	"https://iam.amazonaws.com",
-	"https://elasticloadbalancing.eu-west-1.amazonaws.com",
-	"https://dynamodb.eu-west-1.amazonaws.com",
-	ServiceInfo{"https://monitoring.eu-west-1.amazonaws.com", V2Signature},
-	"https://autoscaling.eu-west-1.amazonaws.com",
-	ServiceInfo{"https://rds.eu-west-1.amazonaws.com", V2Signature},
-	"https://sts.amazonaws.com",
-	"https://cloudformation.eu-west-1.amazonaws.com",
-	"https://ecs.eu-west-1.amazonaws.com",
+	Name:                   "eu-west-1",
+	EC2Endpoint:            "https://ec2.eu-west-1.amazonaws.com",
+	S3Endpoint:             "https://s3-eu-west-1.amazonaws.com",
+	S3LocationConstraint:   true,
+	S3LowercaseBucket:      true,
+	SDBEndpoint:            "https://sdb.eu-west-1.amazonaws.com",
+	SESEndpoint:            "https://email.eu-west-1.amazonaws.com",
+	SNSEndpoint:            "https://sns.eu-west-1.amazonaws.com",
+	SQSEndpoint:            "https://sqs.eu-west-1.amazonaws.com",
+	IAMEndpoint:            "https://iam.amazonaws.com",
+	ELBEndpoint:            "https://elasticloadbalancing.eu-west-1.amazonaws.com",
+	DynamoDBEndpoint:       "https://dynamodb.eu-west-1.amazonaws.com",
+	CloudWatchServicepoint: ServiceInfo{"https://monitoring.eu-west-1.amazonaws.com", V2Signature},
+	AutoScalingEndpoint:    "https://autoscaling.eu-west-1.amazonaws.com",
+	RDSEndpoint:            ServiceInfo{"https://rds.eu-west-1.amazonaws.com", V2Signature},
+	STSEndpoint:            "https://sts.amazonaws.com",
+	CloudFormationEndpoint: "https://cloudformation.eu-west-1.amazonaws.com",
+	ECSEndpoint:            "https://ecs.eu-west-1.amazonaws.com",
+	S3Signer:               V2Signature,
+	KMSEndpoint:            "https://kms.eu-west-1.amazonaws.com",
 }
 
 var EUCentral = Region{
-	"eu-central-1",
-	"https://ec2.eu-central-1.amazonaws.com",
-	"https://s3-eu-central-1.amazonaws.com",
-	"",
-	true,
-	true,
-	"https://sdb.eu-central-1.amazonaws.com",
-	"https://email.eu-central-1.amazonaws.com",
-	"https://sns.eu-central-1.amazonaws.com",
-	"https://sqs.eu-central-1.amazonaws.com",
-	"https://iam.amazonaws.com",
-	"https://elasticloadbalancing.eu-central-1.amazonaws.com",
-	"https://dynamodb.eu-central-1.amazonaws.com",
-	ServiceInfo{"https://monitoring.eu-central-1.amazonaws.com", V2Signature},
-	"https://autoscaling.eu-central-1.amazonaws.com",
-	ServiceInfo{"https://rds.eu-central-1.amazonaws.com", V2Signature},
-	"https://sts.amazonaws.com",
-	"https://cloudformation.eu-central-1.amazonaws.com",
-	"https://ecs.eu-central-1.amazonaws.com",
+	Name:                   "eu-central-1",
+	EC2Endpoint:            "https://ec2.eu-central-1.amazonaws.com",
+	S3Endpoint:             "https://s3-eu-central-1.amazonaws.com",
+	S3LocationConstraint:   true,
+	S3LowercaseBucket:      true,
+	SDBEndpoint:            "https://sdb.eu-central-1.amazonaws.com",
+	SESEndpoint:            "https://email.eu-central-1.amazonaws.com",
+	SNSEndpoint:            "https://sns.eu-central-1.amazonaws.com",
+	SQSEndpoint:            "https://sqs.eu-central-1.amazonaws.com",
+	IAMEndpoint:            "https://iam.amazonaws.com",
+	ELBEndpoint:            "https://elasticloadbalancing.eu-central-1.amazonaws.com",
+	DynamoDBEndpoint:       "https://dynamodb.eu-central-1.amazonaws.com",
+	CloudWatchServicepoint: ServiceInfo{"https://monitoring.eu-central-1.amazonaws.com", V2Signature},
+	AutoScalingEndpoint:    "https://autoscaling.eu-central-1.amazonaws.com",
+	RDSEndpoint:            ServiceInfo{"https://rds.eu-central-1.amazonaws.com", V2Signature},
+	STSEndpoint:            "https://sts.amazonaws.com",
+	CloudFormationEndpoint: "https://cloudformation.eu-central-1.amazonaws.com",
+	ECSEndpoint:            "https://ecs.eu-central-1.amazonaws.com",
+	S3Signer:               V4Signature,
+	KMSEndpoint:            "https://kms.eu-central-1.amazonaws.com",
 }
 
 var APSoutheast = Region{
-	"ap-southeast-1",
-	"https://ec2.ap-southeast-1.amazonaws.com",
-	"https://s3-ap-southeast-1.amazonaws.com",
-	"",
-	true,
-	true,
-	"https://sdb.ap-southeast-1.amazonaws.com",
-	"",
-	"https://sns.ap-southeast-1.amazonaws.com",
-	"https://sqs.ap-southeast-1.amazonaws.com",
-	"https://iam.amazonaws.com",
-	"https://elasticloadbalancing.ap-southeast-1.amazonaws.com",
-	"https://dynamodb.ap-southeast-1.amazonaws.com",
-	ServiceInfo{"https://monitoring.ap-southeast-1.amazonaws.com", V2Signature},
-	"https://autoscaling.ap-southeast-1.amazonaws.com",
-	ServiceInfo{"https://rds.ap-southeast-1.amazonaws.com", V2Signature},
-	"https://sts.amazonaws.com",
-	"https://cloudformation.ap-southeast-1.amazonaws.com",
-	"https://ecs.ap-southeast-1.amazonaws.com",
+	Name:                   "ap-southeast-1",
+	EC2Endpoint:            "https://ec2.ap-southeast-1.amazonaws.com",
+	S3Endpoint:             "https://s3-ap-southeast-1.amazonaws.com",
+	S3LocationConstraint:   true,
+	S3LowercaseBucket:      true,
+	SDBEndpoint:            "https://sdb.ap-southeast-1.amazonaws.com",
+	SNSEndpoint:            "https://sns.ap-southeast-1.amazonaws.com",
+	SQSEndpoint:            "https://sqs.ap-southeast-1.amazonaws.com",
+	IAMEndpoint:            "https://iam.amazonaws.com",
+	ELBEndpoint:            "https://elasticloadbalancing.ap-southeast-1.amazonaws.com",
+	DynamoDBEndpoint:       "https://dynamodb.ap-southeast-1.amazonaws.com",
+	CloudWatchServicepoint: ServiceInfo{"https://monitoring.ap-southeast-1.amazonaws.com", V2Signature},
+	AutoScalingEndpoint:    "https://autoscaling.ap-southeast-1.amazonaws.com",
+	RDSEndpoint:            ServiceInfo{"https://rds.ap-southeast-1.amazonaws.com", V2Signature},
+	STSEndpoint:            "https://sts.amazonaws.com",
+	CloudFormationEndpoint: "https://cloudformation.ap-southeast-1.amazonaws.com",
+	ECSEndpoint:            "https://ecs.ap-southeast-1.amazonaws.com",
+	S3Signer:               V2Signature,
+	KMSEndpoint:            "https://kms.ap-southeast-1.amazonaws.com",
 }
 
 var APSoutheast2 = Region{
-	"ap-southeast-2",
-	"https://ec2.ap-southeast-2.amazonaws.com",
-	"https://s3-ap-southeast-2.amazonaws.com",
-	"",
-	true,
-	true,
-	"https://sdb.ap-southeast-2.amazonaws.com",
-	"",
-	"https://sns.ap-southeast-2.amazonaws.com",
-	"https://sqs.ap-southeast-2.amazonaws.com",
-	"https://iam.amazonaws.com",
-	"https://elasticloadbalancing.ap-southeast-2.amazonaws.com",
-	"https://dynamodb.ap-southeast-2.amazonaws.com",
-	ServiceInfo{"https://monitoring.ap-southeast-2.amazonaws.com", V2Signature},
-	"https://autoscaling.ap-southeast-2.amazonaws.com",
-	ServiceInfo{"https://rds.ap-southeast-2.amazonaws.com", V2Signature},
-	"https://sts.amazonaws.com",
-	"https://cloudformation.ap-southeast-2.amazonaws.com",
-	"https://ecs.ap-southeast-2.amazonaws.com",
+	Name:                   "ap-southeast-2",
+	EC2Endpoint:            "https://ec2.ap-southeast-2.amazonaws.com",
+	S3Endpoint:             "https://s3-ap-southeast-2.amazonaws.com",
+	S3LocationConstraint:   true,
+	S3LowercaseBucket:      true,
+	SDBEndpoint:            "https://sdb.ap-southeast-2.amazonaws.com",
+	SNSEndpoint:            "https://sns.ap-southeast-2.amazonaws.com",
+	SQSEndpoint:            "https://sqs.ap-southeast-2.amazonaws.com",
+	IAMEndpoint:            "https://iam.amazonaws.com",
+	ELBEndpoint:            "https://elasticloadbalancing.ap-southeast-2.amazonaws.com",
+	DynamoDBEndpoint:       "https://dynamodb.ap-southeast-2.amazonaws.com",
+	CloudWatchServicepoint: ServiceInfo{"https://monitoring.ap-southeast-2.amazonaws.com", V2Signature},
+	AutoScalingEndpoint:    "https://autoscaling.ap-southeast-2.amazonaws.com",
+	RDSEndpoint:            ServiceInfo{"https://rds.ap-southeast-2.amazonaws.com", V2Signature},
+	STSEndpoint:            "https://sts.amazonaws.com",
+	CloudFormationEndpoint: "https://cloudformation.ap-southeast-2.amazonaws.com",
+	ECSEndpoint:            "https://ecs.ap-southeast-2.amazonaws.com",
+	S3Signer:               V2Signature,
+	KMSEndpoint:            "https://kms.ap-southeast-2.amazonaws.com",
 }
 
 var APNortheast = Region{
-	"ap-northeast-1",
-	"https://ec2.ap-northeast-1.amazonaws.com",
-	"https://s3-ap-northeast-1.amazonaws.com",
-	"",
-	true,
-	true,
-	"https://sdb.ap-northeast-1.amazonaws.com",
-	"",
-	"https://sns.ap-northeast-1.amazonaws.com",
-	"https://sqs.ap-northeast-1.amazonaws.com",
-	"https://iam.amazonaws.com",
-	"https://elasticloadbalancing.ap-northeast-1.amazonaws.com",
-	"https://dynamodb.ap-northeast-1.amazonaws.com",
-	ServiceInfo{"https://monitoring.ap-northeast-1.amazonaws.com", V2Signature},
-	"https://autoscaling.ap-northeast-1.amazonaws.com",
-	ServiceInfo{"https://rds.ap-northeast-1.amazonaws.com", V2Signature},
-	"https://sts.amazonaws.com",
-	"https://cloudformation.ap-northeast-1.amazonaws.com",
-	"https://ecs.ap-northeast-1.amazonaws.com",
+	Name:                   "ap-northeast-1",
+	EC2Endpoint:            "https://ec2.ap-northeast-1.amazonaws.com",
+	S3Endpoint:             "https://s3-ap-northeast-1.amazonaws.com",
+	S3LocationConstraint:   true,
+	S3LowercaseBucket:      true,
+	SDBEndpoint:            "https://sdb.ap-northeast-1.amazonaws.com",
+	SNSEndpoint:            "https://sns.ap-northeast-1.amazonaws.com",
+	SQSEndpoint:            "https://sqs.ap-northeast-1.amazonaws.com",
+	IAMEndpoint:            "https://iam.amazonaws.com",
+	ELBEndpoint:            "https://elasticloadbalancing.ap-northeast-1.amazonaws.com",
+	DynamoDBEndpoint:       "https://dynamodb.ap-northeast-1.amazonaws.com",
+	CloudWatchServicepoint: ServiceInfo{"https://monitoring.ap-northeast-1.amazonaws.com", V2Signature},
+	AutoScalingEndpoint:    "https://autoscaling.ap-northeast-1.amazonaws.com",
+	RDSEndpoint:            ServiceInfo{"https://rds.ap-northeast-1.amazonaws.com", V2Signature},
+	STSEndpoint:            "https://sts.amazonaws.com",
+	CloudFormationEndpoint: "https://cloudformation.ap-northeast-1.amazonaws.com",
+	ECSEndpoint:            "https://ecs.ap-northeast-1.amazonaws.com",
+	S3Signer:               V2Signature,
+	KMSEndpoint:            "https://kms.ap-northeast-1.amazonaws.com",
 }
 
 var SAEast = Region{
-	"sa-east-1",
-	"https://ec2.sa-east-1.amazonaws.com",
-	"https://s3-sa-east-1.amazonaws.com",
-	"",
-	true,
-	true,
-	"https://sdb.sa-east-1.amazonaws.com",
-	"",
-	"https://sns.sa-east-1.amazonaws.com",
-	"https://sqs.sa-east-1.amazonaws.com",
-	"https://iam.amazonaws.com",
-	"https://elasticloadbalancing.sa-east-1.amazonaws.com",
-	"https://dynamodb.sa-east-1.amazonaws.com",
-	ServiceInfo{"https://monitoring.sa-east-1.amazonaws.com", V2Signature},
-	"https://autoscaling.sa-east-1.amazonaws.com",
-	ServiceInfo{"https://rds.sa-east-1.amazonaws.com", V2Signature},
-	"https://sts.amazonaws.com",
-	"https://cloudformation.sa-east-1.amazonaws.com",
-	"https://ecs.sa-east-1.amazonaws.com",
+	Name:                   "sa-east-1",
+	EC2Endpoint:            "https://ec2.sa-east-1.amazonaws.com",
+	S3Endpoint:             "https://s3-sa-east-1.amazonaws.com",
+	S3LocationConstraint:   true,
+	S3LowercaseBucket:      true,
+	SDBEndpoint:            "https://sdb.sa-east-1.amazonaws.com",
+	SNSEndpoint:            "https://sns.sa-east-1.amazonaws.com",
+	SQSEndpoint:            "https://sqs.sa-east-1.amazonaws.com",
+	IAMEndpoint:            "https://iam.amazonaws.com",
+	ELBEndpoint:            "https://elasticloadbalancing.sa-east-1.amazonaws.com",
+	DynamoDBEndpoint:       "https://dynamodb.sa-east-1.amazonaws.com",
+	CloudWatchServicepoint: ServiceInfo{"https://monitoring.sa-east-1.amazonaws.com", V2Signature},
+	AutoScalingEndpoint:    "https://autoscaling.sa-east-1.amazonaws.com",
+	RDSEndpoint:            ServiceInfo{"https://rds.sa-east-1.amazonaws.com", V2Signature},
+	STSEndpoint:            "https://sts.amazonaws.com",
+	CloudFormationEndpoint: "https://cloudformation.sa-east-1.amazonaws.com",
+	ECSEndpoint:            "https://ecs.sa-east-1.amazonaws.com",
+	S3Signer:               V2Signature,
+	KMSEndpoint:            "https://kms.sa-east-1.amazonaws.com",
 }
 
 var CNNorth = Region{
-	"cn-north-1",
-	"https://ec2.cn-north-1.amazonaws.com.cn",
-	"https://s3.cn-north-1.amazonaws.com.cn",
-	"",
-	true,
-	true,
-	"https://sdb.cn-north-1.amazonaws.com.cn",
-	"",
-	"https://sns.cn-north-1.amazonaws.com.cn",
-	"https://sqs.cn-north-1.amazonaws.com.cn",
-	"https://iam.cn-north-1.amazonaws.com.cn",
-	"https://elasticloadbalancing.cn-north-1.amazonaws.com.cn",
-	"https://dynamodb.cn-north-1.amazonaws.com.cn",
-	ServiceInfo{"https://monitoring.cn-north-1.amazonaws.com.cn", V4Signature},
-	"https://autoscaling.cn-north-1.amazonaws.com.cn",
-	ServiceInfo{"https://rds.cn-north-1.amazonaws.com.cn", V4Signature},
-	"https://sts.cn-north-1.amazonaws.com.cn",
-	"https://cloudformation.cn-north-1.amazonaws.com.cn",
-	"https://ecs.cn-north-1.amazonaws.com.cn",
+	Name:                   "cn-north-1",
+	EC2Endpoint:            "https://ec2.cn-north-1.amazonaws.com.cn",
+	S3Endpoint:             "https://s3.cn-north-1.amazonaws.com.cn",
+	S3LocationConstraint:   true,
+	S3LowercaseBucket:      true,
+	SDBEndpoint:            "https://sdb.cn-north-1.amazonaws.com.cn",
+	SNSEndpoint:            "https://sns.cn-north-1.amazonaws.com.cn",
+	SQSEndpoint:            "https://sqs.cn-north-1.amazonaws.com.cn",
+	IAMEndpoint:            "https://iam.cn-north-1.amazonaws.com.cn",
+	ELBEndpoint:            "https://elasticloadbalancing.cn-north-1.amazonaws.com.cn",
+	DynamoDBEndpoint:       "https://dynamodb.cn-north-1.amazonaws.com.cn",
+	CloudWatchServicepoint: ServiceInfo{"https://monitoring.cn-north-1.amazonaws.com.cn", V4Signature},
+	AutoScalingEndpoint:    "https://autoscaling.cn-north-1.amazonaws.com.cn",
+	RDSEndpoint:            ServiceInfo{"https://rds.cn-north-1.amazonaws.com.cn", V4Signature},
+	STSEndpoint:            "https://sts.cn-north-1.amazonaws.com.cn",
+	CloudFormationEndpoint: "https://cloudformation.cn-north-1.amazonaws.com.cn",
+	ECSEndpoint:            "https://ecs.cn-north-1.amazonaws.com.cn",
+	S3Signer:               V4Signature,
+	KMSEndpoint:            "https://kms.cn-north-1.amazonaws.com.cn",
 }
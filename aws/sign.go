@@ -6,11 +6,13 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -32,6 +34,10 @@ func NewV2Signer(auth Auth, service ServiceInfo) (*V2Signer, error) {
 }
 
 func (s *V2Signer) Sign(method, path string, params map[string]string) {
+	// Drop anything left over from signing params before, so the same
+	// params can be signed again when a request is retried.
+	delete(params, "Signature")
+	delete(params, "SecurityToken")
 	params["AWSAccessKeyId"] = s.auth.AccessKey
 	params["SignatureVersion"] = "2"
 	params["SignatureMethod"] = "HmacSHA256"
@@ -108,6 +114,9 @@ func (s *Route53Signer) Sign(req *http.Request) {
 	req.Header.Set("X-Amzn-Authorization", authHeader)
 	req.Header.Set("X-Amz-Date", date)
 	req.Header.Set("Content-Type", "application/xml")
+	if token := s.auth.Token(); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
 }
 
 /*
@@ -140,6 +149,11 @@ The signed request will include a new "Authorization" header indicating that the
 Any changes to the request after signing the request will invalidate the signature.
 */
 func (s *V4Signer) Sign(req *http.Request) {
+	s.sign(req)
+}
+
+// sign implements Sign, returning the time and the signature of req.
+func (s *V4Signer) sign(req *http.Request) (time.Time, string) {
 	req.Header.Set("host", req.Host)                  // host header must be included as a signed header
 	t := s.requestTime(req)                           // Get requst time
 	creq := s.canonicalRequest(req)                   // Build canonical request
@@ -147,7 +161,141 @@ func (s *V4Signer) Sign(req *http.Request) {
 	signature := s.signature(t, sts)                  // Calculate the AWS Signature Version 4
 	auth := s.authorization(req.Header, t, signature) // Create Authorization header value
 	req.Header.Set("Authorization", auth)             // Add Authorization header to request
-	return
+	return t, signature
+}
+
+// Values of the "x-amz-content-sha256" header for payloads that are not
+// hashed as a whole. See
+// http://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+const (
+	// The payload is not signed.
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	// The payload is sent in chunks, each signed in turn. See
+	// SignStreaming.
+	StreamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+)
+
+/*
+SignStreaming signs req like Sign, for a payload of length bytes sent in chunks of
+chunkSize bytes, each of them signed in turn, as supported by S3.
+(http://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html)
+
+SignStreaming sets the headers declaring the "aws-chunked" content encoding of the payload,
+including "content-length", and req.ContentLength, to the length of the encoded payload.
+It returns a function turning a reader of the payload into a reader of the encoded payload,
+to be sent as the body of req.
+*/
+func (s *V4Signer) SignStreaming(req *http.Request, length int64, chunkSize int) func(io.Reader) io.Reader {
+	encoding := "aws-chunked"
+	if e := req.Header.Get("content-encoding"); e != "" {
+		encoding += "," + e
+	}
+	size := chunkedLength(length, int64(chunkSize))
+	req.Header.Set("content-encoding", encoding)
+	req.Header.Set("content-length", strconv.FormatInt(size, 10))
+	req.Header.Set("x-amz-content-sha256", StreamingPayload)
+	req.Header.Set("x-amz-decoded-content-length", strconv.FormatInt(length, 10))
+	req.ContentLength = size
+	t, signature := s.sign(req)
+	return func(r io.Reader) io.Reader {
+		return &chunkedReader{signer: s, t: t, r: r, signature: signature, chunk: make([]byte, chunkSize)}
+	}
+}
+
+// chunkedLength returns the length of the "aws-chunked" encoding of a
+// payload of length bytes sent in chunks of chunkSize bytes.
+func chunkedLength(length, chunkSize int64) int64 {
+	chunk := func(n int64) int64 {
+		// hex(n) ";chunk-signature=" signature "\r\n" data "\r\n"
+		return int64(len(strconv.FormatInt(n, 16))) + 17 + 64 + 2 + n + 2
+	}
+	size := length / chunkSize * chunk(chunkSize)
+	if rest := length % chunkSize; rest > 0 {
+		size += chunk(rest)
+	}
+	return size + chunk(0)
+}
+
+// chunkedReader reads the "aws-chunked" encoding of the payload read
+// from r, signing every chunk with the signature of the previous one.
+type chunkedReader struct {
+	signer    *V4Signer
+	t         time.Time
+	r         io.Reader
+	signature string
+	chunk     []byte
+	buf       bytes.Buffer
+	done      bool
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if r.buf.Len() == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(r.r, r.chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		r.writeChunk(r.chunk[:n])
+		// The payload ends with an empty chunk.
+		r.done = n == 0
+	}
+	return r.buf.Read(p)
+}
+
+func (r *chunkedReader) writeChunk(data []byte) {
+	s := r.signer
+	w := new(bytes.Buffer)
+	fmt.Fprint(w, "AWS4-HMAC-SHA256-PAYLOAD\n")
+	fmt.Fprintf(w, "%s\n", r.t.Format(ISO8601BasicFormat))
+	fmt.Fprintf(w, "%s\n", s.credentialScope(r.t))
+	fmt.Fprintf(w, "%s\n", r.signature)
+	fmt.Fprintf(w, "%s\n", s.hash(""))
+	fmt.Fprintf(w, "%x", sha256.Sum256(data))
+	r.signature = s.signature(r.t, w.String())
+	fmt.Fprintf(&r.buf, "%x;chunk-signature=%s\r\n", len(data), r.signature)
+	r.buf.Write(data)
+	r.buf.WriteString("\r\n")
+}
+
+/*
+Presign signs req by adding the authentication parameters of the AWS Signature Version 4
+Signing Process to the query string of its URL, so that anyone holding the URL may make
+the request until the signature expires, expires from the request time.
+(http://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html)
+
+The headers of req, along with the "host" header, are signed and must be sent with the
+request. The payload is not signed.
+*/
+func (s *V4Signer) Presign(req *http.Request, expires time.Duration) {
+	t := s.requestTime(req)
+	req.Header.Del("x-amz-date")
+	header := make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		header[k] = v
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	header.Set("host", host)
+
+	q := req.URL.Query()
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", s.auth.AccessKey+"/"+s.credentialScope(t))
+	q.Set("X-Amz-Date", t.Format(ISO8601BasicFormat))
+	q.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	q.Set("X-Amz-SignedHeaders", s.signedHeaders(header))
+	if token := s.auth.Token(); token != "" {
+		q.Set("X-Amz-Security-Token", token)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	creq := s.buildCanonicalRequest(req.Method, req.URL, header, UnsignedPayload)
+	signature := s.signature(t, s.stringToSign(t, creq))
+	req.URL.RawQuery += "&X-Amz-Signature=" + signature
 }
 
 /*
@@ -201,17 +349,33 @@ canonicalRequest method creates the canonical request according to Task 1 of the
       HexEncode(Hash(Payload))
 */
 func (s *V4Signer) canonicalRequest(req *http.Request) string {
+	return s.buildCanonicalRequest(req.Method, req.URL, req.Header, s.payloadHash(req))
+}
+
+func (s *V4Signer) buildCanonicalRequest(method string, u *url.URL, header http.Header, payloadHash string) string {
 	c := new(bytes.Buffer)
-	fmt.Fprintf(c, "%s\n", req.Method)
-	fmt.Fprintf(c, "%s\n", s.canonicalURI(req.URL))
-	fmt.Fprintf(c, "%s\n", s.canonicalQueryString(req.URL))
-	fmt.Fprintf(c, "%s\n\n", s.canonicalHeaders(req.Header))
-	fmt.Fprintf(c, "%s\n", s.signedHeaders(req.Header))
-	fmt.Fprintf(c, "%s", s.payloadHash(req))
+	fmt.Fprintf(c, "%s\n", method)
+	fmt.Fprintf(c, "%s\n", s.canonicalURI(u))
+	fmt.Fprintf(c, "%s\n", s.canonicalQueryString(u))
+	fmt.Fprintf(c, "%s\n\n", s.canonicalHeaders(header))
+	fmt.Fprintf(c, "%s\n", s.signedHeaders(header))
+	fmt.Fprintf(c, "%s", payloadHash)
 	return c.String()
 }
 
 func (s *V4Signer) canonicalURI(u *url.URL) string {
+	if s.serviceName == "s3" {
+		// S3 object keys are used as they are, with each segment
+		// encoded on its own.
+		segments := strings.Split(u.Path, "/")
+		for i, segment := range segments {
+			segments[i] = Encode(segment)
+		}
+		if canonicalPath := strings.Join(segments, "/"); canonicalPath != "" {
+			return canonicalPath
+		}
+		return "/"
+	}
 	canonicalPath := u.RequestURI()
 	if u.RawQuery != "" {
 		canonicalPath = canonicalPath[:len(canonicalPath)-len(u.RawQuery)-1]
@@ -227,12 +391,12 @@ func (s *V4Signer) canonicalURI(u *url.URL) string {
 func (s *V4Signer) canonicalQueryString(u *url.URL) string {
 	var a []string
 	for k, vs := range u.Query() {
-		k = url.QueryEscape(k)
+		k = Encode(k)
 		for _, v := range vs {
 			if v == "" {
 				a = append(a, k+"=")
 			} else {
-				v = url.QueryEscape(v)
+				v = Encode(v)
 				a = append(a, k+"="+v)
 			}
 		}
@@ -242,16 +406,22 @@ func (s *V4Signer) canonicalQueryString(u *url.URL) string {
 }
 
 func (s *V4Signer) canonicalHeaders(h http.Header) string {
-	i, a := 0, make([]string, len(h))
+	// Headers are sorted by name, which may be a prefix of another.
+	names, values := make([]string, 0, len(h)), make(map[string]string, len(h))
 	for k, v := range h {
 		for j, w := range v {
 			v[j] = strings.Trim(w, " ")
 		}
 		sort.Strings(v)
-		a[i] = strings.ToLower(k) + ":" + strings.Join(v, ",")
-		i++
+		k = strings.ToLower(k)
+		names = append(names, k)
+		values[k] = strings.Join(v, ",")
+	}
+	sort.Strings(names)
+	a := make([]string, len(names))
+	for i, k := range names {
+		a[i] = k + ":" + values[k]
 	}
-	sort.Strings(a)
 	return strings.Join(a, "\n")
 }
 
@@ -266,6 +436,10 @@ func (s *V4Signer) signedHeaders(h http.Header) string {
 }
 
 func (s *V4Signer) payloadHash(req *http.Request) string {
+	// The payload hash may be given, or left out, by the request.
+	if h := req.Header.Get("x-amz-content-sha256"); h != "" {
+		return h
+	}
 	var b []byte
 	if req.Body == nil {
 		b = []byte("")
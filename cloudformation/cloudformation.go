@@ -7,6 +7,7 @@
 package cloudformation
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"log"
@@ -24,15 +25,45 @@ import (
 type CloudFormation struct {
 	aws.Auth
 	aws.Region
+	provider aws.CredentialsProvider
+	ctx      context.Context
 }
 
 // New creates a new CloudFormation Client.
 func New(auth aws.Auth, region aws.Region) *CloudFormation {
 
-	return &CloudFormation{auth, region}
+	return &CloudFormation{Auth: auth, Region: region}
 
 }
 
+// NewWithProvider creates a new CloudFormation whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) *CloudFormation {
+	c := New(aws.Auth{}, region)
+	c.provider = provider
+	return c
+}
+
+// credentials returns the credentials to sign the next request with.
+func (c *CloudFormation) credentials() (aws.Auth, error) {
+	if c.provider == nil {
+		return c.Auth.Current(), nil
+	}
+	return c.provider.Credentials()
+}
+
+// WithContext returns a copy of c whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (c *CloudFormation) WithContext(ctx context.Context) *CloudFormation {
+	if ctx == nil {
+		panic("nil context")
+	}
+	cf := *c
+	cf.ctx = ctx
+	return &cf
+}
+
 const debug = false
 
 // ----------------------------------------------------------------------------
@@ -66,7 +97,17 @@ type xmlErrors struct {
 	Errors    []Error `xml:"Error"`
 }
 
+// query makes the request described by params, retrying it once with
+// fresh credentials if the ones it was signed with had expired.
 func (c *CloudFormation) query(params map[string]string, resp interface{}) error {
+	err := c.queryOnce(params, resp)
+	if e, ok := err.(*Error); ok && aws.ExpireCredentials(e.Code, c.provider, c.Auth) {
+		err = c.queryOnce(params, resp)
+	}
+	return err
+}
+
+func (c *CloudFormation) queryOnce(params map[string]string, resp interface{}) error {
 	params["Version"] = "2010-05-15"
 
 	data := strings.NewReader(multimap(params).Encode())
@@ -78,17 +119,24 @@ func (c *CloudFormation) query(params map[string]string, resp interface{}) error
 
 	hreq.Header.Set("Content-Type", "application/x-www-form-urlencoded; param=value")
 
-	token := c.Auth.Token()
+	auth, err := c.credentials()
+	if err != nil {
+		return err
+	}
+	token := auth.Token()
 	if token != "" {
 		hreq.Header.Set("X-Amz-Security-Token", token)
 	}
 
-	signer := aws.NewV4Signer(c.Auth, "cloudformation", c.Region)
+	signer := aws.NewV4Signer(auth, "cloudformation", c.Region)
 	signer.Sign(hreq)
 
 	if debug {
 		log.Printf("%v -> {\n", hreq)
 	}
+	if c.ctx != nil {
+		hreq = hreq.WithContext(c.ctx)
+	}
 	r, err := http.DefaultClient.Do(hreq)
 
 	if err != nil {
@@ -16,11 +16,13 @@
 package cloudwatch
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"github.com/feyeleanor/sets"
 	"github.com/goamz/goamz/aws"
+	"net/http"
 	"strconv"
 	"time"
 )
@@ -28,6 +30,7 @@ import (
 // The CloudWatch type encapsulates all the CloudWatch operations in a region.
 type CloudWatch struct {
 	Service aws.AWSService
+	ctx     context.Context
 }
 
 type Dimension struct {
@@ -187,11 +190,41 @@ func NewCloudWatch(auth aws.Auth, region aws.ServiceInfo) (*CloudWatch, error) {
 	}, nil
 }
 
+// Create a new CloudWatch object whose requests are signed with the
+// credentials supplied by provider at the time they are made
+func NewCloudWatchWithProvider(provider aws.CredentialsProvider, region aws.ServiceInfo) (*CloudWatch, error) {
+	service, err := aws.NewServiceWithProvider(provider, region)
+	if err != nil {
+		return nil, err
+	}
+	return &CloudWatch{
+		Service: service,
+	}, nil
+}
+
+// WithContext returns a copy of c whose requests are made with ctx,
+// provided its Service implements aws.ContextAWSService. Cancelling ctx,
+// or reaching its deadline, aborts any request in flight.
+func (c *CloudWatch) WithContext(ctx context.Context) *CloudWatch {
+	if ctx == nil {
+		panic("nil context")
+	}
+	cw := *c
+	cw.ctx = ctx
+	return &cw
+}
+
 func (c *CloudWatch) query(method, path string, params map[string]string, resp interface{}) error {
 	// Add basic Cloudwatch param
 	params["Version"] = "2010-08-01"
 
-	r, err := c.Service.Query(method, path, params)
+	var r *http.Response
+	var err error
+	if cs, ok := c.Service.(aws.ContextAWSService); ok && c.ctx != nil {
+		r, err = cs.QueryWithContext(c.ctx, method, path, params)
+	} else {
+		r, err = c.Service.Query(method, path, params)
+	}
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package dynamodb
 
 import simplejson "github.com/bitly/go-simplejson"
 import (
+	"context"
 	"errors"
 	"github.com/goamz/goamz/aws"
 	"io/ioutil"
@@ -12,8 +13,36 @@ import (
 )
 
 type Server struct {
-	Auth   aws.Auth
-	Region aws.Region
+	Auth     aws.Auth
+	Region   aws.Region
+	provider aws.CredentialsProvider
+	ctx      context.Context
+}
+
+// NewWithProvider creates a new Server whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) *Server {
+	return &Server{Region: region, provider: provider}
+}
+
+// credentials returns the credentials to sign the next request with.
+func (s *Server) credentials() (aws.Auth, error) {
+	if s.provider == nil {
+		return s.Auth.Current(), nil
+	}
+	return s.provider.Credentials()
+}
+
+// WithContext returns a copy of s whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (s *Server) WithContext(ctx context.Context) *Server {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *s
+	c.ctx = ctx
+	return &c
 }
 
 /*
@@ -72,7 +101,18 @@ func buildError(r *http.Response, jsonBody []byte) error {
 	return &ddbError
 }
 
+// queryServer makes the request described by target and query, retrying
+// it once with fresh credentials if the ones it was signed with had
+// expired.
 func (s *Server) queryServer(target string, query *Query) ([]byte, error) {
+	body, err := s.queryServerOnce(target, query)
+	if e, ok := err.(*Error); ok && aws.ExpireCredentials(e.Code, s.provider, s.Auth) {
+		body, err = s.queryServerOnce(target, query)
+	}
+	return body, err
+}
+
+func (s *Server) queryServerOnce(target string, query *Query) ([]byte, error) {
 	data := strings.NewReader(query.String())
 	hreq, err := http.NewRequest("POST", s.Region.DynamoDBEndpoint+"/", data)
 	if err != nil {
@@ -83,14 +123,21 @@ func (s *Server) queryServer(target string, query *Query) ([]byte, error) {
 	hreq.Header.Set("X-Amz-Date", time.Now().UTC().Format(aws.ISO8601BasicFormat))
 	hreq.Header.Set("X-Amz-Target", target)
 
-	token := s.Auth.Token()
+	auth, err := s.credentials()
+	if err != nil {
+		return nil, err
+	}
+	token := auth.Token()
 	if token != "" {
 		hreq.Header.Set("X-Amz-Security-Token", token)
 	}
 
-	signer := aws.NewV4Signer(s.Auth, "dynamodb", s.Region)
+	signer := aws.NewV4Signer(auth, "dynamodb", s.Region)
 	signer.Sign(hreq)
 
+	if s.ctx != nil {
+		hreq = hreq.WithContext(s.ctx)
+	}
 	resp, err := http.DefaultClient.Do(hreq)
 
 	if err != nil {
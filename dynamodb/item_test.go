@@ -14,7 +14,7 @@ type ItemSuite struct {
 func (s *ItemSuite) SetUpSuite(c *C) {
 	setUpAuth(c)
 	s.DynamoDBTest.TableDescriptionT = s.TableDescriptionT
-	s.server = &dynamodb.Server{dynamodb_auth, dynamodb_region}
+	s.server = &dynamodb.Server{Auth: dynamodb_auth, Region: dynamodb_region}
 	pk, err := s.TableDescriptionT.BuildPrimaryKey()
 	if err != nil {
 		c.Skip(err.Error())
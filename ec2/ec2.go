@@ -11,6 +11,7 @@
 package ec2
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/xml"
@@ -33,12 +34,14 @@ type EC2 struct {
 	aws.Auth
 	aws.Region
 	httpClient *http.Client
+	provider   aws.CredentialsProvider
+	ctx        context.Context
 	private    byte // Reserve the right of using private data.
 }
 
 // NewWithClient creates a new EC2 with a custom http client
 func NewWithClient(auth aws.Auth, region aws.Region, client *http.Client) *EC2 {
-	return &EC2{auth, region, client, 0}
+	return &EC2{Auth: auth, Region: region, httpClient: client}
 }
 
 // New creates a new EC2.
@@ -46,6 +49,34 @@ func New(auth aws.Auth, region aws.Region) *EC2 {
 	return NewWithClient(auth, region, aws.RetryingClient)
 }
 
+// NewWithProvider creates a new EC2 whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) *EC2 {
+	c := New(aws.Auth{}, region)
+	c.provider = provider
+	return c
+}
+
+// credentials returns the credentials to sign the next request with.
+func (ec2 *EC2) credentials() (aws.Auth, error) {
+	if ec2.provider == nil {
+		return ec2.Auth.Current(), nil
+	}
+	return ec2.provider.Credentials()
+}
+
+// WithContext returns a copy of ec2 whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight along with its pending retries.
+func (ec2 *EC2) WithContext(ctx context.Context) *EC2 {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *ec2
+	c.ctx = ctx
+	return &c
+}
+
 // ----------------------------------------------------------------------------
 // Filtering helper.
 
@@ -125,7 +156,17 @@ type xmlErrors struct {
 
 var timeNow = time.Now
 
+// query makes the request described by params, retrying it once with
+// fresh credentials if the ones it was signed with had expired.
 func (ec2 *EC2) query(params map[string]string, resp interface{}) error {
+	err := ec2.queryOnce(params, resp)
+	if e, ok := err.(*Error); ok && aws.ExpireCredentials(e.Code, ec2.provider, ec2.Auth) {
+		err = ec2.queryOnce(params, resp)
+	}
+	return err
+}
+
+func (ec2 *EC2) queryOnce(params map[string]string, resp interface{}) error {
 	params["Version"] = "2014-02-01"
 	params["Timestamp"] = timeNow().In(time.UTC).Format(time.RFC3339)
 	endpoint, err := url.Parse(ec2.Region.EC2Endpoint)
@@ -135,12 +176,23 @@ func (ec2 *EC2) query(params map[string]string, resp interface{}) error {
 	if endpoint.Path == "" {
 		endpoint.Path = "/"
 	}
-	sign(ec2.Auth, "GET", endpoint.Path, params, endpoint.Host)
+	auth, err := ec2.credentials()
+	if err != nil {
+		return err
+	}
+	sign(auth, "GET", endpoint.Path, params, endpoint.Host)
 	endpoint.RawQuery = multimap(params).Encode()
 	if debug {
 		log.Printf("get { %v } -> {\n", endpoint.String())
 	}
-	r, err := ec2.httpClient.Get(endpoint.String())
+	hreq, err := http.NewRequest("GET", endpoint.String(), nil)
+	if err != nil {
+		return err
+	}
+	if ec2.ctx != nil {
+		hreq = hreq.WithContext(ec2.ctx)
+	}
+	r, err := ec2.httpClient.Do(hreq)
 	if err != nil {
 		return err
 	}
@@ -60,6 +60,50 @@ func (s *S) TestRunInstancesErrorDump(c *C) {
 	c.Assert(ec2err.RequestId, Equals, "0503f4e9-bbd6-483c-b54f-c4ae9f3b30f4")
 }
 
+type rotatingProvider struct {
+	keys []string
+}
+
+func (p *rotatingProvider) Credentials() (aws.Auth, error) {
+	key := p.keys[0]
+	p.keys = p.keys[1:]
+	return aws.Auth{AccessKey: key, SecretKey: "secret"}, nil
+}
+
+func (s *S) TestNewWithProvider(c *C) {
+	testServer.Response(200, nil, DescribeInstancesExample1)
+	testServer.Response(200, nil, DescribeInstancesExample1)
+
+	p := &rotatingProvider{keys: []string{"first", "second"}}
+	e := ec2.NewWithProvider(p, aws.Region{EC2Endpoint: testServer.URL})
+
+	_, err := e.DescribeInstances(nil, nil)
+	c.Assert(err, IsNil)
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["AWSAccessKeyId"], DeepEquals, []string{"first"})
+
+	_, err = e.DescribeInstances(nil, nil)
+	c.Assert(err, IsNil)
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["AWSAccessKeyId"], DeepEquals, []string{"second"})
+}
+
+func (s *S) TestExpiredTokenRetried(c *C) {
+	testServer.Response(400, nil, ExpiredTokenDump)
+	testServer.Response(200, nil, DescribeInstancesExample1)
+
+	p := aws.NewRefreshingProvider(&rotatingProvider{keys: []string{"first", "second"}})
+	e := ec2.NewWithProvider(p, aws.Region{EC2Endpoint: testServer.URL})
+
+	_, err := e.DescribeInstances(nil, nil)
+	c.Assert(err, IsNil)
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["AWSAccessKeyId"], DeepEquals, []string{"first"})
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["AWSAccessKeyId"], DeepEquals, []string{"second"})
+	c.Assert(req.Form["Signature"], HasLen, 1)
+}
+
 func (s *S) TestRunInstancesErrorWithoutXML(c *C) {
 	testServer.Responses(5, 500, nil, "")
 	options := ec2.RunInstancesOptions{ImageId: "image-id"}
@@ -926,3 +926,10 @@ var DescribeReservedInstancesExample = `
    </reservedInstancesSet> 
 </DescribeReservedInstancesResponse>
 `
+
+var ExpiredTokenDump = `
+<?xml version="1.0" encoding="UTF-8"?>
+<Response><Errors><Error><Code>ExpiredToken</Code>
+<Message>The security token included in the request is expired</Message>
+</Error></Errors><RequestID>0503f4e9-bbd6-483c-b54f-c4ae9f3b30f4</RequestID></Response>
+`
@@ -9,6 +9,7 @@
 package ecs
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"log"
@@ -30,11 +31,41 @@ var timeNow = time.Now
 type ECS struct {
 	aws.Auth
 	aws.Region
+	provider aws.CredentialsProvider
+	ctx      context.Context
 }
 
 // New creates a new ECS Client.
 func New(auth aws.Auth, region aws.Region) *ECS {
-	return &ECS{auth, region}
+	return &ECS{Auth: auth, Region: region}
+}
+
+// NewWithProvider creates a new ECS whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) *ECS {
+	c := New(aws.Auth{}, region)
+	c.provider = provider
+	return c
+}
+
+// credentials returns the credentials to sign the next request with.
+func (e *ECS) credentials() (aws.Auth, error) {
+	if e.provider == nil {
+		return e.Auth.Current(), nil
+	}
+	return e.provider.Credentials()
+}
+
+// WithContext returns a copy of e whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (e *ECS) WithContext(ctx context.Context) *ECS {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *e
+	c.ctx = ctx
+	return &c
 }
 
 // ----------------------------------------------------------------------------
@@ -68,7 +99,17 @@ type xmlErrors struct {
 	Errors    []Error `xml:"Error"`
 }
 
+// query makes the request described by params, retrying it once with
+// fresh credentials if the ones it was signed with had expired.
 func (e *ECS) query(params map[string]string, resp interface{}) error {
+	err := e.queryOnce(params, resp)
+	if ecsErr, ok := err.(*Error); ok && aws.ExpireCredentials(ecsErr.Code, e.provider, e.Auth) {
+		err = e.queryOnce(params, resp)
+	}
+	return err
+}
+
+func (e *ECS) queryOnce(params map[string]string, resp interface{}) error {
 	params["Version"] = "2014-11-13"
 	data := strings.NewReader(multimap(params).Encode())
 
@@ -79,17 +120,24 @@ func (e *ECS) query(params map[string]string, resp interface{}) error {
 
 	hreq.Header.Set("Content-Type", "application/x-www-form-urlencoded; param=value")
 
-	token := e.Auth.Token()
+	auth, err := e.credentials()
+	if err != nil {
+		return err
+	}
+	token := auth.Token()
 	if token != "" {
 		hreq.Header.Set("X-Amz-Security-Token", token)
 	}
 
-	signer := aws.NewV4Signer(e.Auth, "ecs", e.Region)
+	signer := aws.NewV4Signer(auth, "ecs", e.Region)
 	signer.Sign(hreq)
 
 	if debug {
 		log.Printf("%v -> {\n", hreq)
 	}
+	if e.ctx != nil {
+		hreq = hreq.WithContext(e.ctx)
+	}
 	r, err := http.DefaultClient.Do(hreq)
 
 	if err != nil {
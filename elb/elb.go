@@ -2,6 +2,7 @@
 package elb
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"github.com/goamz/goamz/aws"
@@ -14,10 +15,40 @@ import (
 type ELB struct {
 	aws.Auth
 	aws.Region
+	provider aws.CredentialsProvider
+	ctx      context.Context
 }
 
 func New(auth aws.Auth, region aws.Region) *ELB {
-	return &ELB{auth, region}
+	return &ELB{Auth: auth, Region: region}
+}
+
+// NewWithProvider creates a new ELB whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) *ELB {
+	c := New(aws.Auth{}, region)
+	c.provider = provider
+	return c
+}
+
+// credentials returns the credentials to sign the next request with.
+func (elb *ELB) credentials() (aws.Auth, error) {
+	if elb.provider == nil {
+		return elb.Auth.Current(), nil
+	}
+	return elb.provider.Credentials()
+}
+
+// WithContext returns a copy of elb whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (elb *ELB) WithContext(ctx context.Context) *ELB {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *elb
+	c.ctx = ctx
+	return &c
 }
 
 // The CreateLoadBalancer type encapsulates options for the respective request in AWS.
@@ -268,7 +299,17 @@ func (elb *ELB) ConfigureHealthCheck(lbName string, healthCheck *HealthCheck) (*
 	return resp, nil
 }
 
+// query makes the request described by params, retrying it once with
+// fresh credentials if the ones it was signed with had expired.
 func (elb *ELB) query(params map[string]string, resp interface{}) error {
+	err := elb.queryOnce(params, resp)
+	if e, ok := err.(*Error); ok && aws.ExpireCredentials(e.Code, elb.provider, elb.Auth) {
+		err = elb.queryOnce(params, resp)
+	}
+	return err
+}
+
+func (elb *ELB) queryOnce(params map[string]string, resp interface{}) error {
 	params["Version"] = "2012-06-01"
 	params["Timestamp"] = time.Now().In(time.UTC).Format(time.RFC3339)
 	endpoint, err := url.Parse(elb.Region.ELBEndpoint)
@@ -278,9 +319,20 @@ func (elb *ELB) query(params map[string]string, resp interface{}) error {
 	if endpoint.Path == "" {
 		endpoint.Path = "/"
 	}
-	sign(elb.Auth, "GET", endpoint.Path, params, endpoint.Host)
+	auth, err := elb.credentials()
+	if err != nil {
+		return err
+	}
+	sign(auth, "GET", endpoint.Path, params, endpoint.Host)
 	endpoint.RawQuery = multimap(params).Encode()
-	r, err := http.Get(endpoint.String())
+	req, err := http.NewRequest("GET", endpoint.String(), nil)
+	if err != nil {
+		return err
+	}
+	if elb.ctx != nil {
+		req = req.WithContext(elb.ctx)
+	}
+	r, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
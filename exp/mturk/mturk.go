@@ -14,6 +14,7 @@
 package mturk
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -28,7 +29,9 @@ import (
 
 type MTurk struct {
 	aws.Auth
-	URL *url.URL
+	URL      *url.URL
+	provider aws.CredentialsProvider
+	ctx      context.Context
 }
 
 func New(auth aws.Auth, sandbox bool) *MTurk {
@@ -45,6 +48,34 @@ func New(auth aws.Auth, sandbox bool) *MTurk {
 	return mt
 }
 
+// NewWithProvider creates a new MTurk whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, sandbox bool) *MTurk {
+	mt := New(aws.Auth{}, sandbox)
+	mt.provider = provider
+	return mt
+}
+
+// credentials returns the credentials to sign the next request with.
+func (mt *MTurk) credentials() (aws.Auth, error) {
+	if mt.provider == nil {
+		return mt.Auth.Current(), nil
+	}
+	return mt.provider.Credentials()
+}
+
+// WithContext returns a copy of mt whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (mt *MTurk) WithContext(ctx context.Context) *MTurk {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *mt
+	c.ctx = ctx
+	return &c
+}
+
 // ----------------------------------------------------------------------------
 // Request dispatching logic.
 
@@ -436,7 +467,11 @@ func (mt *MTurk) query(params map[string]string, operation string, resp interfac
 	service := "AWSMechanicalTurkRequester"
 	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05Z")
 
-	params["AWSAccessKeyId"] = mt.Auth.AccessKey
+	auth, err := mt.credentials()
+	if err != nil {
+		return err
+	}
+	params["AWSAccessKeyId"] = auth.AccessKey
 	params["Service"] = service
 	params["Timestamp"] = timestamp
 	params["Operation"] = operation
@@ -444,9 +479,16 @@ func (mt *MTurk) query(params map[string]string, operation string, resp interfac
 	// make a copy
 	url := *mt.URL
 
-	sign(mt.Auth, service, operation, timestamp, params)
+	sign(auth, service, operation, timestamp, params)
 	url.RawQuery = multimap(params).Encode()
-	r, err := http.Get(url.String())
+	req, err := http.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return err
+	}
+	if mt.ctx != nil {
+		req = req.WithContext(mt.ctx)
+	}
+	r, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
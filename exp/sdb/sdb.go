@@ -21,6 +21,7 @@ package sdb
 //
 
 import (
+	"context"
 	"encoding/xml"
 	"github.com/goamz/goamz/aws"
 	"log"
@@ -37,12 +38,42 @@ const debug = false
 type SDB struct {
 	aws.Auth
 	aws.Region
-	private byte // Reserve the right of using private data.
+	provider aws.CredentialsProvider
+	ctx      context.Context
+	private  byte // Reserve the right of using private data.
 }
 
 // New creates a new SDB.
 func New(auth aws.Auth, region aws.Region) *SDB {
-	return &SDB{auth, region, 0}
+	return &SDB{Auth: auth, Region: region}
+}
+
+// NewWithProvider creates a new SDB whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) *SDB {
+	c := New(aws.Auth{}, region)
+	c.provider = provider
+	return c
+}
+
+// credentials returns the credentials to sign the next request with.
+func (sdb *SDB) credentials() (aws.Auth, error) {
+	if sdb.provider == nil {
+		return sdb.Auth.Current(), nil
+	}
+	return sdb.provider.Credentials()
+}
+
+// WithContext returns a copy of sdb whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (sdb *SDB) WithContext(ctx context.Context) *SDB {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *sdb
+	c.ctx = ctx
+	return &c
 }
 
 // The Domain type represents a collection of items that are described
@@ -331,7 +362,17 @@ func (domain *Domain) query(item *Item, params url.Values, headers http.Header,
 	return domain.SDB.query(domain, item, params, headers, resp)
 }
 
+// query makes the request described by params and headers, retrying it
+// once with fresh credentials if the ones it was signed with had expired.
 func (sdb *SDB) query(domain *Domain, item *Item, params url.Values, headers http.Header, resp interface{}) error {
+	err := sdb.queryOnce(domain, item, params, headers, resp)
+	if e, ok := err.(*Error); ok && aws.ExpireCredentials(e.Code, sdb.provider, sdb.Auth) {
+		err = sdb.queryOnce(domain, item, params, headers, resp)
+	}
+	return err
+}
+
+func (sdb *SDB) queryOnce(domain *Domain, item *Item, params url.Values, headers http.Header, resp interface{}) error {
 	// all SimpleDB operations have path="/"
 	method := "GET"
 	path := "/"
@@ -364,7 +405,11 @@ func (sdb *SDB) query(domain *Domain, item *Item, params url.Values, headers htt
 		return err
 	}
 	headers["Host"] = []string{u.Host}
-	sign(sdb.Auth, method, path, params, headers)
+	auth, err := sdb.credentials()
+	if err != nil {
+		return err
+	}
+	sign(auth, method, path, params, headers)
 
 	u.Path = path
 	if len(params) > 0 {
@@ -384,7 +429,11 @@ func (sdb *SDB) query(domain *Domain, item *Item, params url.Values, headers htt
 		delete(headers, "Content-Length")
 	}
 
-	r, err := http.DefaultClient.Do(&req)
+	hreq := &req
+	if sdb.ctx != nil {
+		hreq = hreq.WithContext(sdb.ctx)
+	}
+	r, err := http.DefaultClient.Do(hreq)
 	if err != nil {
 		return err
 	}
@@ -26,7 +26,10 @@ func sign(auth aws.Auth, method, path string, params url.Values, headers http.He
 		}
 	}
 
-	// set up some defaults used for signing the request
+	// set up some defaults used for signing the request, dropping any
+	// left over from signing params before
+	delete(params, "Signature")
+	delete(params, "SecurityToken")
 	params["AWSAccessKeyId"] = []string{auth.AccessKey}
 	params["SignatureVersion"] = []string{"2"}
 	params["SignatureMethod"] = []string{"HmacSHA256"}
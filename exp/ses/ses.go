@@ -2,6 +2,7 @@
 package ses
 
 import (
+	"context"
 	"encoding/xml"
 	"github.com/goamz/goamz/aws"
 	"io/ioutil"
@@ -12,18 +13,47 @@ import (
 )
 
 type SES struct {
-	auth   aws.Auth
-	region aws.Region
-	client *http.Client
+	auth     aws.Auth
+	region   aws.Region
+	client   *http.Client
+	provider aws.CredentialsProvider
+	ctx      context.Context
 }
 
 // Initializes a pointer to an SES struct which can be used
 // to perform SES API calls.
 func NewSES(auth aws.Auth, region aws.Region) *SES {
-	ses := SES{auth, region, nil}
+	ses := SES{auth: auth, region: region}
 	return &ses
 }
 
+// NewSESWithProvider is like NewSES, but requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewSESWithProvider(provider aws.CredentialsProvider, region aws.Region) *SES {
+	ses := SES{region: region, provider: provider}
+	return &ses
+}
+
+// credentials returns the credentials to sign the next request with.
+func (ses *SES) credentials() (aws.Auth, error) {
+	if ses.provider == nil {
+		return ses.auth.Current(), nil
+	}
+	return ses.provider.Credentials()
+}
+
+// WithContext returns a copy of ses whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (ses *SES) WithContext(ctx context.Context) *SES {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *ses
+	c.ctx = ctx
+	return &c
+}
+
 // Sends an email to the specifications stored in the Email struct.
 func (ses *SES) SendEmail(email *Email) error {
 	data := make(url.Values)
@@ -113,9 +143,13 @@ func (ses *SES) doPost(action string, data url.Values) error {
 
 	req.URL = URL
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	sign(ses.auth, "POST", req.Header)
+	auth, err := ses.credentials()
+	if err != nil {
+		return err
+	}
+	sign(auth, "POST", req.Header)
 
-	data.Add("AWSAccessKeyId", ses.auth.AccessKey)
+	data.Add("AWSAccessKeyId", auth.AccessKey)
 	data.Add("Action", action)
 
 	body := data.Encode()
@@ -126,7 +160,11 @@ func (ses *SES) doPost(action string, data url.Values) error {
 		ses.client = &http.Client{}
 	}
 
-	resp, err := ses.client.Do(&req)
+	hreq := &req
+	if ses.ctx != nil {
+		hreq = hreq.WithContext(ses.ctx)
+	}
+	resp, err := ses.client.Do(hreq)
 	if err != nil {
 		return err
 	}
@@ -23,4 +23,7 @@ func sign(auth aws.Auth, method string, headers map[string][]string) {
 	authHeader := fmt.Sprintf("AWS3-HTTPS AWSAccessKeyId=%s, Algorithm=HmacSHA256, Signature=%s", auth.AccessKey, signature)
 	headers["Date"] = []string{date}
 	headers["X-Amzn-Authorization"] = []string{authHeader}
+	if auth.Token() != "" {
+		headers["X-Amz-Security-Token"] = []string{auth.Token()}
+	}
 }
@@ -49,6 +49,8 @@ func sign(auth aws.Auth, method, path string, params url.Values, headers http.He
 }*/
 
 func sign(auth aws.Auth, method, path string, params map[string]string, host string) {
+	delete(params, "Signature")
+	delete(params, "SecurityToken")
 	params["AWSAccessKeyId"] = auth.AccessKey
 	if auth.Token() != "" {
 		params["SecurityToken"] = auth.Token()
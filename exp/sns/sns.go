@@ -16,6 +16,7 @@ package sns
 // BUG(niemeyer): Package needs documentation.
 
 import (
+	"context"
 	"encoding/xml"
 	"net/http"
 	"net/url"
@@ -28,7 +29,9 @@ import (
 type SNS struct {
 	aws.Auth
 	aws.Region
-	private byte // Reserve the right of using private data.
+	provider aws.CredentialsProvider
+	ctx      context.Context
+	private  byte // Reserve the right of using private data.
 }
 
 type AttributeEntry struct {
@@ -42,7 +45,35 @@ type ResponseMetadata struct {
 }
 
 func New(auth aws.Auth, region aws.Region) *SNS {
-	return &SNS{auth, region, 0}
+	return &SNS{Auth: auth, Region: region}
+}
+
+// NewWithProvider creates a new SNS whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) *SNS {
+	c := New(aws.Auth{}, region)
+	c.provider = provider
+	return c
+}
+
+// credentials returns the credentials to sign the next request with.
+func (sns *SNS) credentials() (aws.Auth, error) {
+	if sns.provider == nil {
+		return sns.Auth.Current(), nil
+	}
+	return sns.provider.Credentials()
+}
+
+// WithContext returns a copy of sns whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (sns *SNS) WithContext(ctx context.Context) *SNS {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *sns
+	c.ctx = ctx
+	return &c
 }
 
 func makeParams(action string) map[string]string {
@@ -67,16 +98,37 @@ type xmlErrors struct {
 	Errors    []Error `xml:"Errors>Error"`
 }
 
+// query makes the request described by params, retrying it once with
+// fresh credentials if the ones it was signed with had expired.
 func (sns *SNS) query(params map[string]string, resp interface{}) error {
+	err := sns.queryOnce(params, resp)
+	if e, ok := err.(*Error); ok && aws.ExpireCredentials(e.Code, sns.provider, sns.Auth) {
+		err = sns.queryOnce(params, resp)
+	}
+	return err
+}
+
+func (sns *SNS) queryOnce(params map[string]string, resp interface{}) error {
 	params["Timestamp"] = time.Now().UTC().Format(time.RFC3339)
 	u, err := url.Parse(sns.Region.SNSEndpoint)
 	if err != nil {
 		return err
 	}
 
-	sign(sns.Auth, "GET", "/", params, u.Host)
+	auth, err := sns.credentials()
+	if err != nil {
+		return err
+	}
+	sign(auth, "GET", "/", params, u.Host)
 	u.RawQuery = multimap(params).Encode()
-	r, err := http.Get(u.String())
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if sns.ctx != nil {
+		req = req.WithContext(sns.ctx)
+	}
+	r, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -3,6 +3,7 @@
 package iam
 
 import (
+	"context"
 	"encoding/xml"
 	"net/http"
 	"net/url"
@@ -18,6 +19,8 @@ type IAM struct {
 	aws.Auth
 	aws.Region
 	httpClient *http.Client
+	provider   aws.CredentialsProvider
+	ctx        context.Context
 }
 
 // New creates a new IAM instance.
@@ -26,19 +29,68 @@ func New(auth aws.Auth, region aws.Region) *IAM {
 }
 
 func NewWithClient(auth aws.Auth, region aws.Region, httpClient *http.Client) *IAM {
-	return &IAM{auth, region, httpClient}
+	return &IAM{Auth: auth, Region: region, httpClient: httpClient}
 }
 
+// NewWithProvider creates a new IAM whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) *IAM {
+	c := New(aws.Auth{}, region)
+	c.provider = provider
+	return c
+}
+
+// credentials returns the credentials to sign the next request with.
+func (iam *IAM) credentials() (aws.Auth, error) {
+	if iam.provider == nil {
+		return iam.Auth.Current(), nil
+	}
+	return iam.provider.Credentials()
+}
+
+// WithContext returns a copy of iam whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (iam *IAM) WithContext(ctx context.Context) *IAM {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *iam
+	c.ctx = ctx
+	return &c
+}
+
+// query makes the request described by params, retrying it once with
+// fresh credentials if the ones it was signed with had expired.
 func (iam *IAM) query(params map[string]string, resp interface{}) error {
+	err := iam.queryOnce(params, resp)
+	if e, ok := err.(*Error); ok && aws.ExpireCredentials(e.Code, iam.provider, iam.Auth) {
+		err = iam.queryOnce(params, resp)
+	}
+	return err
+}
+
+func (iam *IAM) queryOnce(params map[string]string, resp interface{}) error {
 	params["Version"] = "2010-05-08"
 	params["Timestamp"] = time.Now().In(time.UTC).Format(time.RFC3339)
 	endpoint, err := url.Parse(iam.IAMEndpoint)
 	if err != nil {
 		return err
 	}
-	sign(iam.Auth, "GET", "/", params, endpoint.Host)
+	auth, err := iam.credentials()
+	if err != nil {
+		return err
+	}
+	sign(auth, "GET", "/", params, endpoint.Host)
 	endpoint.RawQuery = multimap(params).Encode()
-	r, err := iam.httpClient.Get(endpoint.String())
+	req, err := http.NewRequest("GET", endpoint.String(), nil)
+	if err != nil {
+		return err
+	}
+	if iam.ctx != nil {
+		req = req.WithContext(iam.ctx)
+	}
+	r, err := iam.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -49,14 +101,28 @@ func (iam *IAM) query(params map[string]string, resp interface{}) error {
 	return xml.NewDecoder(r.Body).Decode(resp)
 }
 
+// postQuery makes the request described by params, retrying it once with
+// fresh credentials if the ones it was signed with had expired.
 func (iam *IAM) postQuery(params map[string]string, resp interface{}) error {
+	err := iam.postQueryOnce(params, resp)
+	if e, ok := err.(*Error); ok && aws.ExpireCredentials(e.Code, iam.provider, iam.Auth) {
+		err = iam.postQueryOnce(params, resp)
+	}
+	return err
+}
+
+func (iam *IAM) postQueryOnce(params map[string]string, resp interface{}) error {
 	endpoint, err := url.Parse(iam.IAMEndpoint)
 	if err != nil {
 		return err
 	}
 	params["Version"] = "2010-05-08"
 	params["Timestamp"] = time.Now().In(time.UTC).Format(time.RFC3339)
-	sign(iam.Auth, "POST", "/", params, endpoint.Host)
+	auth, err := iam.credentials()
+	if err != nil {
+		return err
+	}
+	sign(auth, "POST", "/", params, endpoint.Host)
 	encoded := multimap(params).Encode()
 	body := strings.NewReader(encoded)
 	req, err := http.NewRequest("POST", endpoint.String(), body)
@@ -66,6 +132,9 @@ func (iam *IAM) postQuery(params map[string]string, resp interface{}) error {
 	req.Header.Set("Host", endpoint.Host)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Content-Length", strconv.Itoa(len(encoded)))
+	if iam.ctx != nil {
+		req = req.WithContext(iam.ctx)
+	}
 	r, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
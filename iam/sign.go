@@ -15,6 +15,8 @@ import (
 var b64 = base64.StdEncoding
 
 func sign(auth aws.Auth, method, path string, params map[string]string, host string) {
+	delete(params, "Signature")
+	delete(params, "SecurityToken")
 	params["AWSAccessKeyId"] = auth.AccessKey
 	params["SignatureVersion"] = "2"
 	params["SignatureMethod"] = "HmacSHA256"
@@ -0,0 +1,231 @@
+//
+// kms: This package provides types and functions to interact with the AWS KMS API
+//
+// Depends on https://github.com/goamz/goamz
+//
+
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/goamz/goamz/aws"
+)
+
+// The KMS type encapsulates operations with the Key Management Service
+// within a specific region.
+type KMS struct {
+	aws.Auth
+	aws.Region
+	provider aws.CredentialsProvider
+	ctx      context.Context
+}
+
+// New creates a new KMS client.
+func New(auth aws.Auth, region aws.Region) *KMS {
+	return &KMS{Auth: auth, Region: region}
+}
+
+// NewWithProvider creates a new KMS whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) *KMS {
+	k := New(aws.Auth{}, region)
+	k.provider = provider
+	return k
+}
+
+// credentials returns the credentials to sign the next request with.
+func (k *KMS) credentials() (aws.Auth, error) {
+	if k.provider == nil {
+		return k.Auth.Current(), nil
+	}
+	return k.provider.Credentials()
+}
+
+// WithContext returns a copy of k whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (k *KMS) WithContext(ctx context.Context) *KMS {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *k
+	c.ctx = ctx
+	return &c
+}
+
+// ----------------------------------------------------------------------------
+// Request dispatching logic.
+
+// Error encapsulates an error returned by the AWS KMS API.
+//
+// See http://docs.aws.amazon.com/kms/latest/APIReference/CommonErrors.html
+// for more details.
+type Error struct {
+	// HTTP status code (400, 500, ...)
+	StatusCode int
+	// KMS error code ("NotFoundException", ...)
+	Code string
+	// The human-oriented error message
+	Message string
+}
+
+func (err *Error) Error() string {
+	if err.Code == "" {
+		return err.Message
+	}
+	return fmt.Sprintf("%s (%s)", err.Message, err.Code)
+}
+
+// query calls the KMS action with the JSON encoding of params, decoding
+// the response into resp. It retries once with fresh credentials if the
+// ones it was signed with had expired.
+func (k *KMS) query(action string, params, resp interface{}) error {
+	err := k.queryOnce(action, params, resp)
+	if e, ok := err.(*Error); ok && aws.ExpireCredentials(e.Code, k.provider, k.Auth) {
+		err = k.queryOnce(action, params, resp)
+	}
+	return err
+}
+
+func (k *KMS) queryOnce(action string, params, resp interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	hreq, err := http.NewRequest("POST", k.Region.KMSEndpoint+"/", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	hreq.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	hreq.Header.Set("X-Amz-Target", "TrentService."+action)
+
+	auth, err := k.credentials()
+	if err != nil {
+		return err
+	}
+	if token := auth.Token(); token != "" {
+		hreq.Header.Set("X-Amz-Security-Token", token)
+	}
+	signer := aws.NewV4Signer(auth, "kms", k.Region)
+	signer.Sign(hreq)
+
+	if k.ctx != nil {
+		hreq = hreq.WithContext(k.ctx)
+	}
+	r, err := http.DefaultClient.Do(hreq)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if r.StatusCode != 200 {
+		return buildError(r, body)
+	}
+	return json.Unmarshal(body, resp)
+}
+
+func buildError(r *http.Response, body []byte) error {
+	var e struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+	json.Unmarshal(body, &e)
+	err := &Error{
+		StatusCode: r.StatusCode,
+		Code:       e.Type,
+		Message:    e.Message,
+	}
+	// The type may be qualified, as in
+	// "com.amazonaws.kms#NotFoundException".
+	if i := strings.LastIndex(err.Code, "#"); i >= 0 {
+		err.Code = err.Code[i+1:]
+	}
+	if err.Message == "" {
+		err.Message = r.Status
+	}
+	return err
+}
+
+// ----------------------------------------------------------------------------
+// Data keys.
+
+// The key specs of data keys GenerateDataKey can return.
+const (
+	AES128 = "AES_128"
+	AES256 = "AES_256"
+)
+
+// DataKey is a data key generated by KMS, both in plaintext and encrypted
+// under the master key KeyId.
+type DataKey struct {
+	KeyId          string
+	Plaintext      []byte
+	CiphertextBlob []byte
+}
+
+// GenerateDataKey returns a new data key of the given spec, AES128 or
+// AES256, encrypted under the master key keyId. The same encryption
+// context must be given to Decrypt the key.
+//
+// See http://docs.aws.amazon.com/kms/latest/APIReference/API_GenerateDataKey.html
+// for details.
+func (k *KMS) GenerateDataKey(keyId, keySpec string, context map[string]string) (*DataKey, error) {
+	params := struct {
+		KeyId             string
+		KeySpec           string
+		EncryptionContext map[string]string `json:",omitempty"`
+	}{keyId, keySpec, context}
+	resp := &DataKey{}
+	if err := k.query("GenerateDataKey", params, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Encrypt encrypts plaintext, of up to 4KB, under the master key keyId.
+//
+// See http://docs.aws.amazon.com/kms/latest/APIReference/API_Encrypt.html
+// for details.
+func (k *KMS) Encrypt(keyId string, plaintext []byte, context map[string]string) ([]byte, error) {
+	params := struct {
+		KeyId             string
+		Plaintext         []byte
+		EncryptionContext map[string]string `json:",omitempty"`
+	}{keyId, plaintext, context}
+	var resp struct {
+		CiphertextBlob []byte
+	}
+	if err := k.query("Encrypt", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.CiphertextBlob, nil
+}
+
+// Decrypt decrypts ciphertext, as returned by Encrypt or GenerateDataKey
+// with the encryption context given.
+//
+// See http://docs.aws.amazon.com/kms/latest/APIReference/API_Decrypt.html
+// for details.
+func (k *KMS) Decrypt(ciphertext []byte, context map[string]string) ([]byte, error) {
+	params := struct {
+		CiphertextBlob    []byte
+		EncryptionContext map[string]string `json:",omitempty"`
+	}{ciphertext, context}
+	var resp struct {
+		Plaintext []byte
+	}
+	if err := k.query("Decrypt", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
@@ -0,0 +1,94 @@
+package kms_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/goamz/goamz/aws"
+	"github.com/goamz/goamz/kms"
+	"github.com/goamz/goamz/testutil"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&S{})
+
+type S struct {
+	kms *kms.KMS
+}
+
+var testServer = testutil.NewHTTPServer()
+
+func (s *S) SetUpSuite(c *C) {
+	testServer.Start()
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	s.kms = kms.New(auth, aws.Region{Name: "us-east-1", KMSEndpoint: testServer.URL})
+}
+
+func (s *S) TearDownTest(c *C) {
+	testServer.Flush()
+}
+
+func (s *S) TestGenerateDataKey(c *C) {
+	testServer.Response(200, nil, GenerateDataKeyResponse)
+
+	key, err := s.kms.GenerateDataKey("alias/test", kms.AES256, map[string]string{"bucket": "b"})
+	c.Assert(err, IsNil)
+	c.Assert(key.KeyId, Equals, "arn:aws:kms:us-east-1:123456789012:key/key-id")
+	c.Assert(string(key.Plaintext), Equals, "plaintext key")
+	c.Assert(string(key.CiphertextBlob), Equals, "encrypted key")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "POST")
+	c.Assert(req.Header.Get("X-Amz-Target"), Equals, "TrentService.GenerateDataKey")
+	c.Assert(req.Header.Get("Content-Type"), Equals, "application/x-amz-json-1.1")
+	c.Assert(req.Header.Get("Authorization"), Matches, "AWS4-HMAC-SHA256 Credential=abc/[0-9]+/us-east-1/kms/aws4_request.*")
+	body, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `{"KeyId":"alias/test","KeySpec":"AES_256","EncryptionContext":{"bucket":"b"}}`)
+}
+
+func (s *S) TestEncrypt(c *C) {
+	testServer.Response(200, nil, EncryptResponse)
+
+	blob, err := s.kms.Encrypt("alias/test", []byte("secret"), nil)
+	c.Assert(err, IsNil)
+	c.Assert(string(blob), Equals, "encrypted key")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("X-Amz-Target"), Equals, "TrentService.Encrypt")
+	var params map[string]interface{}
+	err = json.NewDecoder(req.Body).Decode(&params)
+	c.Assert(err, IsNil)
+	c.Assert(params, DeepEquals, map[string]interface{}{"KeyId": "alias/test", "Plaintext": "c2VjcmV0"})
+}
+
+func (s *S) TestDecrypt(c *C) {
+	testServer.Response(200, nil, DecryptResponse)
+
+	plaintext, err := s.kms.Decrypt([]byte("encrypted key"), map[string]string{"bucket": "b"})
+	c.Assert(err, IsNil)
+	c.Assert(string(plaintext), Equals, "plaintext key")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("X-Amz-Target"), Equals, "TrentService.Decrypt")
+	body, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `{"CiphertextBlob":"ZW5jcnlwdGVkIGtleQ==","EncryptionContext":{"bucket":"b"}}`)
+}
+
+func (s *S) TestError(c *C) {
+	testServer.Response(400, nil, ErrorResponse)
+
+	_, err := s.kms.Decrypt([]byte("bad"), nil)
+	c.Assert(err, FitsTypeOf, &kms.Error{})
+	e := err.(*kms.Error)
+	c.Assert(e.StatusCode, Equals, 400)
+	c.Assert(e.Code, Equals, "InvalidCiphertextException")
+	c.Assert(e.Error(), Equals, "The ciphertext is invalid (InvalidCiphertextException)")
+}
@@ -0,0 +1,30 @@
+package kms_test
+
+var GenerateDataKeyResponse = `
+{
+  "CiphertextBlob": "ZW5jcnlwdGVkIGtleQ==",
+  "KeyId": "arn:aws:kms:us-east-1:123456789012:key/key-id",
+  "Plaintext": "cGxhaW50ZXh0IGtleQ=="
+}
+`
+
+var EncryptResponse = `
+{
+  "CiphertextBlob": "ZW5jcnlwdGVkIGtleQ==",
+  "KeyId": "arn:aws:kms:us-east-1:123456789012:key/key-id"
+}
+`
+
+var DecryptResponse = `
+{
+  "KeyId": "arn:aws:kms:us-east-1:123456789012:key/key-id",
+  "Plaintext": "cGxhaW50ZXh0IGtleQ=="
+}
+`
+
+var ErrorResponse = `
+{
+  "__type": "InvalidCiphertextException",
+  "message": "The ciphertext is invalid"
+}
+`
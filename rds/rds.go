@@ -1,9 +1,11 @@
 package rds
 
 import (
+	"context"
 	"encoding/xml"
 	"github.com/goamz/goamz/aws"
 	"log"
+	"net/http"
 	"net/http/httputil"
 	"strconv"
 )
@@ -18,6 +20,7 @@ const (
 // The RDS type encapsulates operations within a specific EC2 region.
 type RDS struct {
 	Service aws.AWSService
+	ctx     context.Context
 }
 
 // New creates a new RDS Client.
@@ -31,6 +34,30 @@ func New(auth aws.Auth, region aws.Region) (*RDS, error) {
 	}, nil
 }
 
+// NewWithProvider creates a new RDS Client whose requests are signed with
+// the credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) (*RDS, error) {
+	service, err := aws.NewServiceWithProvider(provider, region.RDSEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &RDS{
+		Service: service,
+	}, nil
+}
+
+// WithContext returns a copy of rds whose requests are made with ctx,
+// provided its Service implements aws.ContextAWSService. Cancelling ctx,
+// or reaching its deadline, aborts any request in flight.
+func (rds *RDS) WithContext(ctx context.Context) *RDS {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *rds
+	c.ctx = ctx
+	return &c
+}
+
 // ----------------------------------------------------------------------------
 // Request dispatching logic.
 
@@ -39,7 +66,13 @@ func (rds *RDS) query(method, path string, params map[string]string, resp interf
 	// Add basic RDS param
 	params["Version"] = ApiVersion
 
-	r, err := rds.Service.Query(method, path, params)
+	var r *http.Response
+	var err error
+	if cs, ok := rds.Service.(aws.ContextAWSService); ok && rds.ctx != nil {
+		r, err = cs.QueryWithContext(rds.ctx, method, path, params)
+	} else {
+		r, err = rds.Service.Query(method, path, params)
+	}
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package route53
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"github.com/goamz/goamz/aws"
@@ -14,6 +15,8 @@ type Route53 struct {
 	Endpoint string
 	Signer   *aws.Route53Signer
 	Service  *aws.Service
+	provider aws.CredentialsProvider
+	ctx      context.Context
 }
 
 const route53_host = "https://route53.amazonaws.com"
@@ -29,6 +32,42 @@ func NewRoute53(auth aws.Auth) (*Route53, error) {
 	}, nil
 }
 
+// NewRoute53WithProvider is like NewRoute53, but requests are signed with
+// the credentials supplied by provider at the time they are made.
+func NewRoute53WithProvider(provider aws.CredentialsProvider) (*Route53, error) {
+	return &Route53{
+		provider: provider,
+		Endpoint: route53_host + "/2013-04-01/hostedzone",
+	}, nil
+}
+
+// signer returns the signer to sign the next request with.
+func (r *Route53) signer() (*aws.Route53Signer, error) {
+	if r.provider == nil {
+		if auth := r.Auth.Current(); auth != r.Auth {
+			return aws.NewRoute53Signer(auth), nil
+		}
+		return r.Signer, nil
+	}
+	auth, err := r.provider.Credentials()
+	if err != nil {
+		return nil, err
+	}
+	return aws.NewRoute53Signer(auth), nil
+}
+
+// WithContext returns a copy of r whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (r *Route53) WithContext(ctx context.Context) *Route53 {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *r
+	c.ctx = ctx
+	return &c
+}
+
 // General Structs used in all types of requests
 type HostedZone struct {
 	XMLName                xml.Name `xml:"HostedZone"`
@@ -162,13 +201,36 @@ type DeleteHostedZoneResponse struct {
 // query sends the specified HTTP request to the path and signs the request
 // with the required authentication and headers based on the Auth.
 //
-// Automatically decodes the response into the the result interface
-func (r *Route53) query(method string, path string, body io.Reader, result interface{}) error {
-	var err error
+// Automatically decodes the response into the the result interface. A
+// request failing because the credentials it was signed with had expired
+// is retried once with fresh credentials.
+func (r *Route53) query(method string, path string, body []byte, result interface{}) error {
+	err := r.queryOnce(method, path, body, result)
+	if e, ok := err.(*aws.Error); ok && aws.ExpireCredentials(e.Code, r.provider, r.Auth) {
+		err = r.queryOnce(method, path, body, result)
+	}
+	return err
+}
+
+func (r *Route53) queryOnce(method string, path string, body []byte, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
 
 	// Create the POST request and sign the headers
-	req, err := http.NewRequest(method, path, body)
-	r.Signer.Sign(req)
+	req, err := http.NewRequest(method, path, reader)
+	if err != nil {
+		return err
+	}
+	if r.ctx != nil {
+		req = req.WithContext(r.ctx)
+	}
+	signer, err := r.signer()
+	if err != nil {
+		return err
+	}
+	signer.Sign(req)
 
 	// Send the request and capture the response
 	client := &http.Client{}
@@ -199,7 +261,7 @@ func (r *Route53) CreateHostedZone(hostedZoneReq *CreateHostedZoneRequest) (*Cre
 	}
 
 	result := new(CreateHostedZoneResponse)
-	err = r.query("POST", r.Endpoint, bytes.NewBuffer(xmlBytes), result)
+	err = r.query("POST", r.Endpoint, xmlBytes, result)
 
 	return result, err
 }
@@ -214,7 +276,7 @@ func (r *Route53) ChangeResourceRecordSet(req *ChangeResourceRecordSetsRequest,
 
 	result := new(ChangeResourceRecordSetsResponse)
 	path := fmt.Sprintf("%s/%s/rrset", r.Endpoint, zoneId)
-	err = r.query("POST", path, bytes.NewBuffer(xmlBytes), result)
+	err = r.query("POST", path, xmlBytes, result)
 
 	return result, err
 }
@@ -0,0 +1,113 @@
+package s3
+
+import (
+	"encoding/xml"
+)
+
+// The permissions that can be granted on buckets and objects.
+const (
+	PermissionFullControl = "FULL_CONTROL"
+	PermissionRead        = "READ"
+	PermissionWrite       = "WRITE"
+	PermissionReadACP     = "READ_ACP"
+	PermissionWriteACP    = "WRITE_ACP"
+)
+
+// The types of grantees.
+const (
+	GranteeCanonicalUser = "CanonicalUser"
+	GranteeEmail         = "AmazonCustomerByEmail"
+	GranteeGroup         = "Group"
+)
+
+// The URIs of the predefined groups permissions can be granted to.
+const (
+	AllUsersGroup           = "http://acs.amazonaws.com/groups/global/AllUsers"
+	AuthenticatedUsersGroup = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+	LogDeliveryGroup        = "http://acs.amazonaws.com/groups/s3/LogDelivery"
+)
+
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// AccessControlPolicy holds the owner of a bucket or object and the
+// permissions granted on it. Unlike the canned ACLs, it can grant
+// permissions to any user or group.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/dev/acl-overview.html
+// for an overview.
+type AccessControlPolicy struct {
+	XMLName xml.Name `xml:"AccessControlPolicy"`
+	Owner   Owner
+	Grants  []Grant `xml:"AccessControlList>Grant"`
+}
+
+// Grant gives a permission to a grantee.
+type Grant struct {
+	Grantee    Grantee
+	Permission string // PermissionRead, PermissionWrite, ...
+}
+
+// Grantee is a user or a group permissions are granted to. Users are
+// identified by their canonical ID, or by their email address when
+// granting permissions, and groups by their URI.
+type Grantee struct {
+	Type         string `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"` // GranteeCanonicalUser, GranteeEmail or GranteeGroup
+	ID           string `xml:",omitempty"`
+	DisplayName  string `xml:",omitempty"`
+	EmailAddress string `xml:",omitempty"`
+	URI          string `xml:",omitempty"`
+}
+
+// MarshalXML encodes g with its type given as the xsi:type attribute S3
+// expects, which encoding/xml cannot produce from a struct tag.
+func (g Grantee) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = []xml.Attr{
+		{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace},
+		{Name: xml.Name{Local: "xsi:type"}, Value: g.Type},
+	}
+	type grantee struct {
+		ID           string `xml:",omitempty"`
+		DisplayName  string `xml:",omitempty"`
+		EmailAddress string `xml:",omitempty"`
+		URI          string `xml:",omitempty"`
+	}
+	return e.EncodeElement(grantee{g.ID, g.DisplayName, g.EmailAddress, g.URI}, start)
+}
+
+// GetBucketACL returns the access control policy of b.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketGETacl.html
+// for details.
+func (b *Bucket) GetBucketACL() (*AccessControlPolicy, error) {
+	return b.GetACL("/")
+}
+
+// PutBucketACL replaces the access control policy of b. The policy must
+// name the owner of b.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUTacl.html
+// for details.
+func (b *Bucket) PutBucketACL(p *AccessControlPolicy) error {
+	return b.PutACL("/", p)
+}
+
+// GetACL returns the access control policy of the object at path.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectGETacl.html
+// for details.
+func (b *Bucket) GetACL(path string) (*AccessControlPolicy, error) {
+	p := &AccessControlPolicy{}
+	if err := b.getSubresource(path, "acl", p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PutACL replaces the access control policy of the object at path. The
+// policy must name the owner of the object.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPUTacl.html
+// for details.
+func (b *Bucket) PutACL(path string, p *AccessControlPolicy) error {
+	return b.putSubresourceXML(path, "acl", p)
+}
@@ -0,0 +1,116 @@
+package s3_test
+
+import (
+	"io/ioutil"
+
+	"github.com/goamz/goamz/s3"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestGetACL(c *C) {
+	testServer.Response(200, nil, GetACLDump)
+
+	b := s.s3.Bucket("bucket")
+	p, err := b.GetACL("name")
+	c.Assert(err, IsNil)
+	owner := s3.Owner{
+		ID:          "75aa57f09aa0c8caeab4f8c24e99d10f8e7faeebf76c078efc7c6caea54ba06a",
+		DisplayName: "CustomersName@amazon.com",
+	}
+	c.Assert(p.Owner, Equals, owner)
+	c.Assert(p.Grants, DeepEquals, []s3.Grant{{
+		Grantee:    s3.Grantee{Type: s3.GranteeCanonicalUser, ID: owner.ID, DisplayName: owner.DisplayName},
+		Permission: s3.PermissionFullControl,
+	}, {
+		Grantee:    s3.Grantee{Type: s3.GranteeGroup, URI: s3.AllUsersGroup},
+		Permission: s3.PermissionRead,
+	}})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.URL.Path, Equals, "/bucket/name")
+	c.Assert(req.Form["acl"], DeepEquals, []string{""})
+}
+
+func (s *S) TestGetBucketACL(c *C) {
+	testServer.Response(200, nil, GetACLDump)
+
+	b := s.s3.Bucket("bucket")
+	p, err := b.GetBucketACL()
+	c.Assert(err, IsNil)
+	c.Assert(p.Grants, HasLen, 2)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.URL.Path, Equals, "/bucket/")
+	c.Assert(req.Form["acl"], DeepEquals, []string{""})
+}
+
+func (s *S) TestPutACL(c *C) {
+	testServer.Response(200, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.PutACL("name", &s3.AccessControlPolicy{
+		Owner: s3.Owner{ID: "owner"},
+		Grants: []s3.Grant{{
+			Grantee:    s3.Grantee{Type: s3.GranteeEmail, EmailAddress: "user@example.com"},
+			Permission: s3.PermissionReadACP,
+		}},
+	})
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "PUT")
+	c.Assert(req.URL.Path, Equals, "/bucket/name")
+	c.Assert(req.Form["acl"], DeepEquals, []string{""})
+	c.Assert(req.Header.Get("Content-MD5"), Not(Equals), "")
+	body, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<AccessControlPolicy><Owner><ID>owner</ID><DisplayName></DisplayName></Owner><AccessControlList><Grant>`+
+		`<Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="AmazonCustomerByEmail">`+
+		`<EmailAddress>user@example.com</EmailAddress></Grantee><Permission>READ_ACP</Permission>`+
+		`</Grant></AccessControlList></AccessControlPolicy>`)
+}
+
+func (s *S) TestPutBucketPolicy(c *C) {
+	testServer.Response(204, nil, "")
+
+	policy := `{"Version":"2012-10-17","Statement":[]}`
+	b := s.s3.Bucket("bucket")
+	err := b.PutBucketPolicy([]byte(policy))
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "PUT")
+	c.Assert(req.URL.Path, Equals, "/bucket/")
+	c.Assert(req.Form["policy"], DeepEquals, []string{""})
+	body, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, policy)
+}
+
+func (s *S) TestGetBucketPolicy(c *C) {
+	policy := `{"Version":"2012-10-17","Statement":[]}`
+	testServer.Response(200, nil, policy)
+
+	b := s.s3.Bucket("bucket")
+	data, err := b.GetBucketPolicy()
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, policy)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.Form["policy"], DeepEquals, []string{""})
+}
+
+func (s *S) TestDeleteBucketPolicy(c *C) {
+	testServer.Response(204, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.DeleteBucketPolicy()
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "DELETE")
+	c.Assert(req.Form["policy"], DeepEquals, []string{""})
+}
@@ -0,0 +1,118 @@
+package s3
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxParts is the number of parts a multipart upload may have at most.
+const maxParts = 10000
+
+// CopyLarge copies the object source, given as "bucket/key" and optionally
+// followed by "?versionId=" and a version, to path in u.Bucket. Unlike
+// PutCopy, which S3 only allows for objects up to 5GB, it copies objects
+// of any size using a multipart upload whose parts S3 copies from ranges
+// of source, Concurrency of them at once. Objects no larger than PartSize
+// are copied with PutCopy instead.
+//
+// Parts are PartSize bytes long, or longer if that would make more than
+// the 10000 parts S3 allows. They are retried and reported to Progress as
+// those sent by Upload. If copying any part fails, the multipart upload
+// is aborted and the error returned.
+//
+// As with PutCopy, the content type and metadata of source are kept unless
+// options.MetadataDirective is "REPLACE", in which case those in options
+// are set instead. Only the metadata Options can hold is kept. If source
+// is encrypted with options.CopySourceSSECustomerKey, the key is sent with
+// the HEAD request and with the request copying each part.
+func (u *Uploader) CopyLarge(path string, perm ACL, options CopyOptions, source string) error {
+	resp, err := u.headSource(source, options.CopySourceSSECustomerKey)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	size := resp.ContentLength
+	if size < 0 {
+		return fmt.Errorf("size of %q unknown", source)
+	}
+	partSize := u.partSize()
+	if size <= partSize {
+		_, err := u.Bucket.PutCopy(path, perm, options, source)
+		return err
+	}
+	if least := (size + maxParts - 1) / maxParts; partSize < least {
+		partSize = least
+	}
+
+	contType, opts := options.ContentType, options.Options
+	if options.MetadataDirective != "REPLACE" {
+		contType, opts = copiedMetadata(resp.Header, options.Options)
+	}
+	m, err := u.Bucket.InitMultiOptions(path, contType, perm, opts)
+	if err != nil {
+		return err
+	}
+	m.CopySourceSSECustomerKey = options.CopySourceSSECustomerKey
+	s := u.newPartSender(m)
+	for n, offset := 1, int64(0); offset < size; n, offset = n+1, offset+partSize {
+		length := partSize
+		if offset+length > size {
+			length = size - offset
+		}
+		if !s.sendCopy(n, source, offset, length) {
+			break
+		}
+	}
+	parts, err := s.wait()
+	if err == nil {
+		err = m.Complete(parts)
+	}
+	if err != nil {
+		m.Abort()
+		return err
+	}
+	return nil
+}
+
+// headSource HEADs the object source of a copy, encrypted with the
+// customer provided key if it is not nil.
+func (u *Uploader) headSource(source string, key []byte) (*http.Response, error) {
+	src, versionId := source, ""
+	if i := strings.Index(src, "?"); i >= 0 {
+		q, err := url.ParseQuery(src[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("bad copy source %q: %v", source, err)
+		}
+		src, versionId = src[:i], q.Get("versionId")
+	}
+	i := strings.Index(src, "/")
+	if i < 0 {
+		return nil, fmt.Errorf("bad copy source %q: not of the form bucket/key", source)
+	}
+	var headers map[string][]string
+	if key != nil {
+		headers = SSECustomerKeyHeaders(key)
+	}
+	b := u.Bucket.S3.Bucket(src[:i])
+	if versionId != "" {
+		return b.HeadVersion(src[i+1:], versionId, headers)
+	}
+	return b.Head(src[i+1:], headers)
+}
+
+// copiedMetadata returns the content type and the options to set on a
+// copy of an object with the given headers, as S3 keeps them when copying
+// it with PutCopy. Other than metadata, options are kept.
+func copiedMetadata(h http.Header, options Options) (string, Options) {
+	options.ContentEncoding = h.Get("Content-Encoding")
+	options.CacheControl = h.Get("Cache-Control")
+	options.Meta = make(map[string][]string)
+	for name, v := range h {
+		if strings.HasPrefix(name, "X-Amz-Meta-") {
+			options.Meta[strings.ToLower(name[len("X-Amz-Meta-"):])] = v
+		}
+	}
+	return h.Get("Content-Type"), options
+}
@@ -0,0 +1,164 @@
+package s3_test
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/goamz/goamz/s3"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestCopyLarge(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, map[string]string{
+		"Content-Length":   "12",
+		"Content-Type":     "application/x-tar",
+		"Cache-Control":    "no-cache",
+		"x-amz-meta-owner": "archives",
+	}, "")
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(200, nil, `<CopyPartResult><ETag>"etag1"</ETag></CopyPartResult>`)
+	testServer.Response(200, nil, `<CopyPartResult><ETag>"etag2"</ETag></CopyPartResult>`)
+	testServer.Response(200, nil, `<CopyPartResult><ETag>"etag3"</ETag></CopyPartResult>`)
+	testServer.Response(200, nil, listPartsDump(
+		s3.Part{N: 1, ETag: `"etag1"`, Size: 5},
+		s3.Part{N: 2, ETag: `"etag2"`, Size: 5},
+		s3.Part{N: 3, ETag: `"etag3"`, Size: 2},
+	))
+	testServer.Response(200, nil, "")
+
+	var sent []int64
+	u := s.newUploader()
+	u.Progress = func(part s3.Part, n int64) {
+		sent = append(sent, n)
+	}
+	err := u.CopyLarge("multi", s3.Private, s3.CopyOptions{}, "archives/big.tar")
+	c.Assert(err, IsNil)
+	c.Assert(sent, DeepEquals, []int64{5, 10, 12})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "HEAD")
+	c.Assert(req.URL.Path, Equals, "/archives/big.tar")
+
+	req = testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "POST")
+	c.Assert(req.URL.Path, Equals, "/sample/multi")
+	c.Assert(req.Header.Get("Content-Type"), Equals, "application/x-tar")
+	c.Assert(req.Header.Get("Cache-Control"), Equals, "no-cache")
+	c.Assert(req.Header.Get("x-amz-meta-owner"), Equals, "archives")
+
+	for _, r := range []string{"bytes=0-4", "bytes=5-9", "bytes=10-11"} {
+		req = testServer.WaitRequest()
+		c.Assert(req.Method, Equals, "PUT")
+		c.Assert(req.Header.Get("x-amz-copy-source"), Equals, "archives/big.tar")
+		c.Assert(req.Header.Get("x-amz-copy-source-range"), Equals, r)
+	}
+
+	testServer.WaitRequest() // ListParts
+	req = testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "POST")
+	c.Assert(req.Form["uploadId"], Not(IsNil))
+}
+
+func (s *S) TestCopyLargeReplaceMetadata(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, map[string]string{
+		"Content-Length":   "6",
+		"Content-Type":     "application/x-tar",
+		"x-amz-meta-owner": "archives",
+	}, "")
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(200, nil, `<CopyPartResult><ETag>"etag1"</ETag></CopyPartResult>`)
+	testServer.Response(200, nil, `<CopyPartResult><ETag>"etag2"</ETag></CopyPartResult>`)
+	testServer.Response(200, nil, listPartsDump(
+		s3.Part{N: 1, ETag: `"etag1"`, Size: 5},
+		s3.Part{N: 2, ETag: `"etag2"`, Size: 1},
+	))
+	testServer.Response(200, nil, "")
+
+	options := s3.CopyOptions{
+		Options:           s3.Options{Meta: map[string][]string{"owner": {"backups"}}},
+		MetadataDirective: "REPLACE",
+		ContentType:       "application/gzip",
+	}
+	err := s.newUploader().CopyLarge("multi", s3.Private, options, "archives/big.tar?versionId=v1")
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "HEAD")
+	c.Assert(req.Form["versionId"], DeepEquals, []string{"v1"})
+	req = testServer.WaitRequest()
+	c.Assert(req.Header.Get("Content-Type"), Equals, "application/gzip")
+	c.Assert(req.Header.Get("x-amz-meta-owner"), Equals, "backups")
+	c.Assert(req.Header.Get("x-amz-metadata-directive"), Equals, "")
+	req = testServer.WaitRequest()
+	c.Assert(req.Header.Get("x-amz-copy-source"), Equals, "archives/big.tar?versionId=v1")
+	testServer.WaitRequests(3)
+}
+
+func (s *S) TestCopyLargeSSECustomerKey(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, map[string]string{"Content-Length": "6"}, "")
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(200, nil, `<CopyPartResult><ETag>"etag1"</ETag></CopyPartResult>`)
+	testServer.Response(200, nil, `<CopyPartResult><ETag>"etag2"</ETag></CopyPartResult>`)
+	testServer.Response(200, nil, listPartsDump(
+		s3.Part{N: 1, ETag: `"etag1"`, Size: 5},
+		s3.Part{N: 2, ETag: `"etag2"`, Size: 1},
+	))
+	testServer.Response(200, nil, "")
+
+	key := []byte("01234567890123456789012345678901")
+	options := s3.CopyOptions{CopySourceSSECustomerKey: key}
+	err := s.newUploader().CopyLarge("multi", s3.Private, options, "archives/big.tar")
+	c.Assert(err, IsNil)
+
+	keyHeaders := s3.SSECustomerKeyHeaders(key)
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "HEAD")
+	for name, v := range keyHeaders {
+		c.Assert(req.Header[http.CanonicalHeaderKey(name)], DeepEquals, v)
+	}
+	testServer.WaitRequest()
+	for i := 0; i < 2; i++ {
+		req = testServer.WaitRequest()
+		c.Assert(req.Header.Get("x-amz-copy-source-range"), Not(Equals), "")
+		for name, v := range keyHeaders {
+			c.Assert(req.Header[http.CanonicalHeaderKey(name)], IsNil)
+			copyName := strings.Replace(name, "x-amz-", "x-amz-copy-source-", 1)
+			c.Assert(req.Header[http.CanonicalHeaderKey(copyName)], DeepEquals, v)
+		}
+	}
+	testServer.WaitRequests(2)
+}
+
+func (s *S) TestCopyLargeSmallObject(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, map[string]string{"Content-Length": "5"}, "")
+	testServer.Response(200, nil, `<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`)
+
+	err := s.newUploader().CopyLarge("copy", s3.Private, s3.CopyOptions{}, "archives/small")
+	c.Assert(err, IsNil)
+
+	testServer.WaitRequest()
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "PUT")
+	c.Assert(req.URL.Path, Equals, "/sample/copy")
+	c.Assert(req.Header.Get("x-amz-copy-source"), Equals, "archives/small")
+	c.Assert(req.Header.Get("x-amz-copy-source-range"), Equals, "")
+}
+
+func (s *S) TestCopyLargeAborts(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, map[string]string{"Content-Length": "10"}, "")
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(403, nil, AccessDeniedErrorDump)
+	testServer.Response(204, nil, "")
+
+	err := s.newUploader().CopyLarge("multi", s3.Private, s3.CopyOptions{}, "archives/big.tar")
+	c.Assert(err, ErrorMatches, "Access Denied")
+
+	reqs := testServer.WaitRequests(4)
+	c.Assert(reqs[3].Method, Equals, "DELETE")
+	c.Assert(reqs[3].Form["uploadId"], Not(IsNil))
+}
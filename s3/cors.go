@@ -0,0 +1,59 @@
+package s3
+
+import (
+	"encoding/xml"
+)
+
+// CORSConfiguration holds the rules S3 follows to answer the cross-origin
+// requests web browsers make to a bucket, such as the form uploads set up
+// with PostFormArgs from pages served elsewhere.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/dev/cors.html
+// for an overview.
+type CORSConfiguration struct {
+	XMLName xml.Name   `xml:"CORSConfiguration"`
+	Rules   []CORSRule `xml:"CORSRule"`
+}
+
+// CORSRule allows requests from AllowedOrigins, which may hold one "*"
+// wildcard each, using AllowedMethods ("GET", "PUT", "POST", "DELETE" or
+// "HEAD") and sending AllowedHeaders. ExposeHeaders lists the response
+// headers browsers let scripts read, and MaxAgeSeconds how long browsers
+// may cache the response to a preflight request.
+type CORSRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedHeaders []string `xml:"AllowedHeader"`
+	ExposeHeaders  []string `xml:"ExposeHeader"`
+	MaxAgeSeconds  int      `xml:",omitempty"`
+}
+
+// PutBucketCORS replaces the CORS configuration of b.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUTcors.html
+// for details.
+func (b *Bucket) PutBucketCORS(c *CORSConfiguration) error {
+	return b.putBucketSubresourceXML("cors", c)
+}
+
+// GetBucketCORS returns the CORS configuration of b. If b has none, the
+// error returned has the code NoSuchCORSConfiguration.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketGETcors.html
+// for details.
+func (b *Bucket) GetBucketCORS() (*CORSConfiguration, error) {
+	c := &CORSConfiguration{}
+	if err := b.getBucketSubresource("cors", c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DeleteBucketCORS removes the CORS configuration of b.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketDELETEcors.html
+// for details.
+func (b *Bucket) DeleteBucketCORS() error {
+	return b.delBucketSubresource("cors")
+}
@@ -0,0 +1,121 @@
+package s3_test
+
+import (
+	"io/ioutil"
+
+	"github.com/goamz/goamz/s3"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestPutBucketCORS(c *C) {
+	testServer.Response(200, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.PutBucketCORS(&s3.CORSConfiguration{
+		Rules: []s3.CORSRule{{
+			AllowedOrigins: []string{"http://www.example.com"},
+			AllowedMethods: []string{"PUT", "POST"},
+			AllowedHeaders: []string{"*"},
+			ExposeHeaders:  []string{"x-amz-request-id"},
+			MaxAgeSeconds:  3000,
+		}},
+	})
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "PUT")
+	c.Assert(req.URL.Path, Equals, "/bucket/")
+	c.Assert(req.Form["cors"], DeepEquals, []string{""})
+	c.Assert(req.Header.Get("Content-MD5"), Not(Equals), "")
+	body, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<CORSConfiguration><CORSRule><AllowedOrigin>http://www.example.com</AllowedOrigin>`+
+		`<AllowedMethod>PUT</AllowedMethod><AllowedMethod>POST</AllowedMethod>`+
+		`<AllowedHeader>*</AllowedHeader><ExposeHeader>x-amz-request-id</ExposeHeader>`+
+		`<MaxAgeSeconds>3000</MaxAgeSeconds></CORSRule></CORSConfiguration>`)
+}
+
+func (s *S) TestGetBucketCORS(c *C) {
+	testServer.Response(200, nil, GetCORSDump)
+
+	b := s.s3.Bucket("bucket")
+	config, err := b.GetBucketCORS()
+	c.Assert(err, IsNil)
+	c.Assert(config.Rules, DeepEquals, []s3.CORSRule{{
+		ID:             "upload",
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "HEAD"},
+		MaxAgeSeconds:  600,
+	}})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.URL.Path, Equals, "/bucket/")
+	c.Assert(req.Form["cors"], DeepEquals, []string{""})
+}
+
+func (s *S) TestDeleteBucketCORS(c *C) {
+	testServer.Response(204, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.DeleteBucketCORS()
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "DELETE")
+	c.Assert(req.URL.Path, Equals, "/bucket/")
+	c.Assert(req.Form["cors"], DeepEquals, []string{""})
+}
+
+func (s *S) TestPutBucketNotification(c *C) {
+	testServer.Response(200, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.PutBucketNotification(&s3.NotificationConfiguration{
+		Queues: []s3.QueueConfiguration{{
+			Id:     "images",
+			Queue:  "arn:aws:sqs:us-east-1:123456789012:queue",
+			Events: []string{s3.EventObjectCreated},
+			Filter: s3.KeyFilter("images/", ".jpg"),
+		}},
+	})
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "PUT")
+	c.Assert(req.URL.Path, Equals, "/bucket/")
+	c.Assert(req.Form["notification"], DeepEquals, []string{""})
+	body, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<NotificationConfiguration><QueueConfiguration><Id>images</Id>`+
+		`<Queue>arn:aws:sqs:us-east-1:123456789012:queue</Queue><Event>s3:ObjectCreated:*</Event>`+
+		`<Filter><S3Key><FilterRule><Name>prefix</Name><Value>images/</Value></FilterRule>`+
+		`<FilterRule><Name>suffix</Name><Value>.jpg</Value></FilterRule></S3Key></Filter>`+
+		`</QueueConfiguration></NotificationConfiguration>`)
+}
+
+func (s *S) TestGetBucketNotification(c *C) {
+	testServer.Response(200, nil, GetNotificationDump)
+
+	b := s.s3.Bucket("bucket")
+	config, err := b.GetBucketNotification()
+	c.Assert(err, IsNil)
+	c.Assert(config.Queues, HasLen, 0)
+	c.Assert(config.Topics, DeepEquals, []s3.TopicConfiguration{{
+		Id:     "removals",
+		Topic:  "arn:aws:sns:us-east-1:123456789012:topic",
+		Events: []string{s3.EventObjectRemovedDelete, s3.EventObjectRemovedDeleteMarkerCreated},
+	}})
+	c.Assert(config.Lambdas, DeepEquals, []s3.LambdaFunctionConfiguration{{
+		Id:       "thumbnails",
+		Function: "arn:aws:lambda:us-east-1:123456789012:function:thumbnail",
+		Events:   []string{s3.EventObjectCreatedPut},
+		Filter:   &s3.NotificationFilter{Rules: []s3.FilterRule{{Name: "prefix", Value: "images/"}}},
+	}})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.Form["notification"], DeepEquals, []string{""})
+}
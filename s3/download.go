@@ -0,0 +1,248 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The defaults used by downloaders created by NewDownloader.
+const (
+	DefaultDownloadPartSize    = 5 * 1024 * 1024
+	DefaultDownloadConcurrency = 5
+)
+
+// A Downloader retrieves objects from S3 by requesting several ranges of
+// them at once.
+//
+// Each range is read into a buffer of PartSize bytes before being written
+// out, and ranges are hashed in order, so at most 2*Concurrency*PartSize
+// bytes are held in memory at any time.
+type Downloader struct {
+	Bucket *Bucket
+
+	// PartSize is the size of the ranges requested.
+	PartSize int64
+
+	// Concurrency is the number of ranges requested at once.
+	Concurrency int
+
+	// PartRetries is the number of times a range that could not be
+	// retrieved is requested again, on top of the retries done for
+	// every S3 request, before the download fails.
+	PartRetries int
+
+	// PartRetryDelay is how long to wait before requesting a range
+	// again. It doubles after each retry of the same range.
+	PartRetryDelay time.Duration
+
+	// SSECustomerKey is the 256-bit key the object is encrypted with, if
+	// it was stored with a customer provided key.
+	SSECustomerKey []byte
+
+	// Progress, if set, is called as the object is retrieved, with the
+	// number of bytes retrieved so far and the size of the object.
+	Progress func(received, size int64)
+}
+
+// NewDownloader returns a downloader retrieving objects from b using the
+// default settings.
+func NewDownloader(b *Bucket) *Downloader {
+	return &Downloader{
+		Bucket:         b,
+		PartSize:       DefaultDownloadPartSize,
+		Concurrency:    DefaultDownloadConcurrency,
+		PartRetries:    DefaultPartRetries,
+		PartRetryDelay: DefaultPartRetryDelay,
+	}
+}
+
+// Download writes the object at path to w and returns its size.
+//
+// All the ranges are requested with an If-Match header holding the ETag
+// the object had when the download started, so the download fails if the
+// object is replaced meanwhile. Unless the object was stored by a
+// multipart upload or is encrypted with a KMS or customer provided key,
+// its ETag is the MD5 of its content, and the content retrieved is
+// checked against it.
+func (d *Downloader) Download(path string, w io.WriterAt) (int64, error) {
+	var headers map[string][]string
+	if d.SSECustomerKey != nil {
+		headers = SSECustomerKeyHeaders(d.SSECustomerKey)
+	}
+	resp, err := d.Bucket.Head(path, headers)
+	if err != nil {
+		return 0, err
+	}
+	size := resp.ContentLength
+	etag := resp.Header.Get("ETag")
+	if size < 0 {
+		return 0, fmt.Errorf("size of %q unknown", path)
+	}
+	// The ETags of objects encrypted with KMS or customer provided keys
+	// are not the MD5 of their content.
+	enc := ResponseEncryption(resp.Header)
+	var sum hash.Hash
+	if etag != "" && !strings.Contains(etag, "-") && enc.Algorithm != SSEAlgorithmKMS && enc.CustomerAlgorithm == "" {
+		sum = md5.New()
+	}
+
+	partSize := d.PartSize
+	if partSize <= 0 {
+		partSize = DefaultDownloadPartSize
+	}
+	concurrency := d.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	parts := int((size + partSize - 1) / partSize)
+
+	type chunk struct {
+		n    int
+		data []byte
+	}
+	// Buffers are allocated on first use and handed back once their
+	// range has been written and hashed. A range is only picked once a
+	// buffer is available, so the earliest range not yet hashed always
+	// has one.
+	free := make(chan []byte, 2*concurrency)
+	for i := 0; i < cap(free); i++ {
+		free <- nil
+	}
+	done := make(chan chunk, cap(free))
+	var (
+		mu     sync.Mutex
+		next   int
+		failed = make(chan struct{})
+		ferr   error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ferr == nil {
+			ferr = err
+			close(failed)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < parts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var buf []byte
+				select {
+				case buf = <-free:
+				case <-failed:
+					return
+				}
+				mu.Lock()
+				n := next
+				next++
+				mu.Unlock()
+				if n >= parts {
+					return
+				}
+				if buf == nil {
+					buf = make([]byte, partSize)
+				}
+				offset := int64(n) * partSize
+				data := buf[:partSize]
+				if offset+partSize > size {
+					data = buf[:size-offset]
+				}
+				err := d.getRange(path, etag, offset, data, failed)
+				if err == nil {
+					_, err = w.WriteAt(data, offset)
+				}
+				if err != nil {
+					fail(err)
+					return
+				}
+				done <- chunk{n, data}
+			}
+		}()
+	}
+
+	// Hash the ranges in order as they come in.
+	var received int64
+	pending := make(map[int][]byte)
+Receive:
+	for n := 0; n < parts; {
+		select {
+		case c := <-done:
+			pending[c.n] = c.data
+		case <-failed:
+			break Receive
+		}
+		for data, ok := pending[n]; ok; data, ok = pending[n] {
+			delete(pending, n)
+			if sum != nil {
+				sum.Write(data)
+			}
+			received += int64(len(data))
+			if d.Progress != nil {
+				d.Progress(received, size)
+			}
+			free <- data
+			n++
+		}
+	}
+	wg.Wait()
+	if ferr != nil {
+		return 0, ferr
+	}
+	if sum != nil && etag != `"`+hex.EncodeToString(sum.Sum(nil))+`"` {
+		return 0, fmt.Errorf("content retrieved for %q does not match its ETag %s", path, etag)
+	}
+	return size, nil
+}
+
+// getRange reads len(buf) bytes of the object at path from offset into
+// buf, retrying as configured. It gives up early once failed is closed.
+func (d *Downloader) getRange(path, etag string, offset int64, buf []byte, failed <-chan struct{}) error {
+	delay := d.PartRetryDelay
+	for retry := 0; ; retry++ {
+		err := d.readRange(path, etag, offset, buf)
+		if err == nil || retry >= d.PartRetries || !retryTransfer(d.Bucket.S3, err) {
+			return err
+		}
+		select {
+		case <-time.After(delay):
+		case <-failed:
+			return err
+		}
+		delay *= 2
+	}
+}
+
+func (d *Downloader) readRange(path, etag string, offset int64, buf []byte) error {
+	headers := http.Header{
+		"Range": {fmt.Sprintf("bytes=%d-%d", offset, offset+int64(len(buf))-1)},
+	}
+	if etag != "" {
+		headers.Set("If-Match", etag)
+	}
+	if d.SSECustomerKey != nil {
+		addCustomerKeyHeaders(headers, "x-amz-", d.SSECustomerKey)
+	}
+	resp, err := d.Bucket.GetResponseWithHeaders(path, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// A server ignoring the range sends the whole object, which will
+	// only do for a single range starting at 0.
+	if resp.StatusCode != http.StatusPartialContent && (offset != 0 || resp.ContentLength != int64(len(buf))) {
+		return fmt.Errorf("range %s of %q not honored", headers.Get("Range"), path)
+	}
+	_, err = io.ReadFull(resp.Body, buf)
+	return err
+}
@@ -0,0 +1,175 @@
+package s3_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goamz/goamz/s3"
+	. "gopkg.in/check.v1"
+)
+
+// memWriterAt is an io.WriterAt writing to memory.
+type memWriterAt struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (w *memWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if end := int(off) + len(p); end > len(w.data) {
+		w.data = append(w.data, make([]byte, end-len(w.data))...)
+	}
+	return copy(w.data[off:], p), nil
+}
+
+func (s *S) newDownloader() *s3.Downloader {
+	d := s3.NewDownloader(s.s3.Bucket("bucket"))
+	d.PartSize = 5
+	d.Concurrency = 1
+	d.PartRetryDelay = time.Millisecond
+	return d
+}
+
+func headDump(etag string, size int) map[string]string {
+	return map[string]string{"ETag": etag, "Content-Length": fmt.Sprint(size)}
+}
+
+func (s *S) TestDownloaderDownload(c *C) {
+	s.DisableRetries()
+	etag := `"2717e175a1106a5b19695647a6efafde"` // MD5 of part1part2last
+	testServer.Response(200, headDump(etag, 14), "")
+	testServer.Response(206, nil, "part1")
+	testServer.Response(206, nil, "part2")
+	testServer.Response(206, nil, "last")
+
+	var received []int64
+	d := s.newDownloader()
+	d.Progress = func(n, size int64) {
+		c.Check(size, Equals, int64(14))
+		received = append(received, n)
+	}
+	var w memWriterAt
+	size, err := d.Download("name", &w)
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, int64(14))
+	c.Assert(string(w.data), Equals, "part1part2last")
+	c.Assert(received, DeepEquals, []int64{5, 10, 14})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "HEAD")
+	c.Assert(req.URL.Path, Equals, "/bucket/name")
+	for _, r := range []string{"bytes=0-4", "bytes=5-9", "bytes=10-13"} {
+		req = testServer.WaitRequest()
+		c.Assert(req.Method, Equals, "GET")
+		c.Assert(req.Header.Get("Range"), Equals, r)
+		c.Assert(req.Header.Get("If-Match"), Equals, etag)
+	}
+}
+
+func (s *S) TestDownloaderConcurrent(c *C) {
+	s.DisableRetries()
+	const n = 8
+	testServer.Response(200, headDump(`"350a7c71c9d86caf596a814a789139da"`, 5*n), "")
+	testServer.Responses(n, 206, nil, "12345")
+
+	d := s.newDownloader()
+	d.Concurrency = 3
+	var w memWriterAt
+	size, err := d.Download("name", &w)
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, int64(5*n))
+	c.Assert(string(w.data), Equals, strings.Repeat("12345", n))
+
+	reqs := testServer.WaitRequests(n + 1)
+	ranges := make(map[string]bool)
+	for _, req := range reqs[1:] {
+		ranges[req.Header.Get("Range")] = true
+	}
+	c.Assert(ranges, HasLen, n)
+}
+
+func (s *S) TestDownloaderRetriesRange(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, headDump(`"f4c9385f1902f7334b00b9b4ecd164de"`, 4), "") // MD5 of part
+	testServer.Response(500, nil, InternalErrorDump)
+	testServer.Response(206, nil, "part")
+
+	var w memWriterAt
+	size, err := s.newDownloader().Download("name", &w)
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, int64(4))
+	c.Assert(string(w.data), Equals, "part")
+
+	reqs := testServer.WaitRequests(3)
+	c.Assert(reqs[1].Header.Get("Range"), Equals, "bytes=0-3")
+	c.Assert(reqs[2].Header.Get("Range"), Equals, "bytes=0-3")
+}
+
+func (s *S) TestDownloaderETagMismatch(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, headDump(`"a2e0f5b4bf4e9ecec6e54a8d2e4a9a10"`, 4), "")
+	testServer.Response(206, nil, "part")
+
+	var w memWriterAt
+	_, err := s.newDownloader().Download("name", &w)
+	c.Assert(err, ErrorMatches, `content retrieved for "name" does not match its ETag "a2e0f5b4bf4e9ecec6e54a8d2e4a9a10"`)
+	testServer.WaitRequests(2)
+}
+
+func (s *S) TestDownloaderMultipartObject(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, headDump(`"d41d8cd98f00b204e9800998ecf8427e-2"`, 4), "")
+	testServer.Response(206, nil, "part")
+
+	var w memWriterAt
+	size, err := s.newDownloader().Download("name", &w)
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, int64(4))
+}
+
+func (s *S) TestDownloaderEncryptedObject(c *C) {
+	s.DisableRetries()
+	encryption := map[string]string{
+		"x-amz-server-side-encryption":                    "aws:kms",
+		"x-amz-server-side-encryption-customer-algorithm": "AES256",
+	}
+	for header, value := range encryption {
+		head := headDump(`"a2e0f5b4bf4e9ecec6e54a8d2e4a9a10"`, 4)
+		head[header] = value
+		testServer.Response(200, head, "")
+		testServer.Response(206, nil, "part")
+
+		var w memWriterAt
+		size, err := s.newDownloader().Download("name", &w)
+		c.Assert(err, IsNil, Commentf("%s: %s", header, value))
+		c.Assert(size, Equals, int64(4))
+		c.Assert(string(w.data), Equals, "part")
+		testServer.WaitRequests(2)
+	}
+}
+
+func (s *S) TestDownloaderEmptyObject(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, headDump(`"d41d8cd98f00b204e9800998ecf8427e"`, 0), "")
+
+	var w memWriterAt
+	size, err := s.newDownloader().Download("name", &w)
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, int64(0))
+	testServer.WaitRequest()
+}
+
+func (s *S) TestDownloaderObjectReplaced(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, headDump(`"2717e175a1106a5b19695647a6efafde"`, 14), "")
+	testServer.Response(412, nil, PreconditionFailedErrorDump)
+
+	d := s.newDownloader()
+	var w memWriterAt
+	_, err := d.Download("name", &w)
+	c.Assert(err, ErrorMatches, "At least one of the pre-conditions you specified did not hold")
+	testServer.WaitRequests(2)
+}
@@ -0,0 +1,104 @@
+package s3
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/goamz/goamz/aws"
+)
+
+// withScheme returns endpoint with the https scheme if it has none, or
+// with the http scheme if SSL is disabled.
+func (s3 *S3) withScheme(endpoint string) string {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	if s3.DisableSSL && strings.HasPrefix(endpoint, "https://") {
+		endpoint = "http://" + endpoint[len("https://"):]
+	}
+	return endpoint
+}
+
+// endpoint returns the URL of the service requests are sent to when
+// buckets are addressed in the path of requests.
+func (s3 *S3) endpoint() string {
+	if s3.Endpoint != "" {
+		return s3.withScheme(s3.Endpoint)
+	}
+	return s3.withScheme(s3.Region.S3Endpoint)
+}
+
+// bucketEndpoint returns the URL requests on bucket are sent to, and
+// whether the bucket is addressed in the path of requests rather than in
+// the URL.
+func (s3 *S3) bucketEndpoint(bucket string) (baseurl string, pathStyle bool, err error) {
+	if s3.PathStyle {
+		return s3.endpoint(), true, nil
+	}
+	if s3.Endpoint == "" {
+		if s3.Region.S3BucketEndpoint == "" {
+			return s3.endpoint(), true, nil
+		}
+		// Just in case, prevent injection.
+		if strings.IndexAny(bucket, "/:@") >= 0 {
+			return "", false, fmt.Errorf("bad S3 bucket: %q", bucket)
+		}
+		return s3.withScheme(strings.Replace(s3.Region.S3BucketEndpoint, "${bucket}", bucket, -1)), false, nil
+	}
+	u, err := url.Parse(s3.endpoint())
+	if err != nil {
+		return "", false, fmt.Errorf("bad S3 endpoint URL %q: %v", s3.Endpoint, err)
+	}
+	if !virtualHostBucket(bucket, u.Scheme == "https") {
+		return u.String(), true, nil
+	}
+	u.Host = bucket + "." + u.Host
+	return u.String(), false, nil
+}
+
+// bucketURL returns the URL of bucket, ending with a slash.
+func (s3 *S3) bucketURL(bucket string) (string, error) {
+	baseurl, pathStyle, err := s3.bucketEndpoint(bucket)
+	if err != nil {
+		return "", err
+	}
+	baseurl = strings.TrimSuffix(baseurl, "/")
+	if pathStyle {
+		return baseurl + "/" + bucket + "/", nil
+	}
+	return baseurl + "/", nil
+}
+
+// virtualHostBucket reports whether bucket can be addressed as a
+// subdomain of the endpoint: its name must be a valid DNS label, or
+// several without SSL, whose certificates only match a single label.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/dev/VirtualHosting.html
+// for details.
+func virtualHostBucket(bucket string, ssl bool) bool {
+	if len(bucket) < 3 || len(bucket) > 63 || net.ParseIP(bucket) != nil {
+		return false
+	}
+	for _, label := range strings.Split(bucket, ".") {
+		if label == "" || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return !ssl || !strings.Contains(bucket, ".")
+}
+
+// signingRegion returns the region requests are signed for.
+func (s3 *S3) signingRegion() aws.Region {
+	region := s3.Region
+	if s3.SigningRegion != "" {
+		region.Name = s3.SigningRegion
+	}
+	return region
+}
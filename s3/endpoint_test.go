@@ -0,0 +1,155 @@
+package s3_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/goamz/goamz/aws"
+	"github.com/goamz/goamz/s3"
+	. "gopkg.in/check.v1"
+)
+
+var endpointAuth = aws.Auth{AccessKey: "abc", SecretKey: "123"}
+
+// testServerClient returns a client sending requests to any host to the
+// test server.
+func testServerClient() *http.Client {
+	u, _ := url.Parse(testServer.URL)
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, u.Host)
+			},
+		},
+	}
+}
+
+func (s *S) TestEndpointURLs(c *C) {
+	for _, t := range []struct {
+		endpoint   string
+		pathStyle  bool
+		disableSSL bool
+		bucket     string
+		url        string
+	}{
+		{"https://minio.example.com", false, false, "bucket", "https://bucket.minio.example.com/name"},
+		{"https://minio.example.com:9000", true, false, "bucket", "https://minio.example.com:9000/bucket/name"},
+		{"minio.example.com", false, false, "bucket", "https://bucket.minio.example.com/name"},
+		{"minio.example.com", false, true, "bucket", "http://bucket.minio.example.com/name"},
+		{"https://minio.example.com", false, true, "bucket", "http://bucket.minio.example.com/name"},
+		// Names that are not valid host names, or hold dots with SSL.
+		{"https://minio.example.com", false, false, "Bucket_1", "https://minio.example.com/Bucket_1/name"},
+		{"https://minio.example.com", false, false, "my.bucket", "https://minio.example.com/my.bucket/name"},
+		{"http://minio.example.com", false, false, "my.bucket", "http://my.bucket.minio.example.com/name"},
+		{"https://minio.example.com", false, false, "ab", "https://minio.example.com/ab/name"},
+		{"https://minio.example.com", false, false, "10.0.0.1", "https://minio.example.com/10.0.0.1/name"},
+	} {
+		s3 := s3.New(endpointAuth, aws.Region{Name: "faux-region-1"})
+		s3.Endpoint = t.endpoint
+		s3.PathStyle = t.pathStyle
+		s3.DisableSSL = t.disableSSL
+		c.Check(s3.Bucket(t.bucket).URL("name"), Equals, t.url, Commentf("%+v", t))
+	}
+}
+
+func (s *S) TestRegionPathStyle(c *C) {
+	region := aws.Region{
+		Name:             "faux-region-1",
+		S3Endpoint:       "https://s3.example.com",
+		S3BucketEndpoint: "https://${bucket}.s3.example.com",
+	}
+	s3 := s3.New(endpointAuth, region)
+	c.Assert(s3.Bucket("bucket").URL("name"), Equals, "https://bucket.s3.example.com/name")
+	s3.PathStyle = true
+	c.Assert(s3.Bucket("bucket").URL("name"), Equals, "https://s3.example.com/bucket/name")
+	s3.DisableSSL = true
+	c.Assert(s3.Bucket("bucket").URL("name"), Equals, "http://s3.example.com/bucket/name")
+}
+
+func (s *S) TestEndpointRequests(c *C) {
+	testServer.Response(200, nil, "content")
+	testServer.Response(200, nil, "content")
+
+	s3 := s3.New(endpointAuth, aws.Region{Name: "faux-region-1"})
+	s3.Endpoint = "http://storage.example.com"
+	s3.HTTPClient = testServerClient()
+	data, err := s3.Bucket("bucket").Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "content")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Host, Equals, "bucket.storage.example.com")
+	c.Assert(req.URL.Path, Equals, "/name")
+	c.Assert(req.Header.Get("Authorization"), Matches, "AWS abc:.*")
+
+	s3.PathStyle = true
+	_, err = s3.Bucket("bucket").Get("name")
+	c.Assert(err, IsNil)
+	req = testServer.WaitRequest()
+	c.Assert(req.Host, Equals, "storage.example.com")
+	c.Assert(req.URL.Path, Equals, "/bucket/name")
+}
+
+func (s *S) TestSigningRegion(c *C) {
+	testServer.Response(200, nil, "content")
+
+	s3 := s3.New(endpointAuth, aws.Region{Name: "faux-region-1", S3Signer: aws.V4Signature})
+	s3.Endpoint = testServer.URL
+	s3.PathStyle = true
+	s3.SigningRegion = "us-east-1"
+	_, err := s3.Bucket("bucket").Get("name")
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.URL.Path, Equals, "/bucket/name")
+	c.Assert(req.Header.Get("Authorization"), Matches, "AWS4-HMAC-SHA256 Credential=abc/[0-9]{8}/us-east-1/s3/aws4_request, .*")
+
+	signed := s3.Bucket("bucket").SignedURL("name", time.Now().Add(time.Hour))
+	c.Assert(signed, Matches, testServer.URL+"/bucket/name\\?.*X-Amz-Credential=abc%2F[0-9]{8}%2Fus-east-1%2Fs3%2Faws4_request.*")
+}
+
+func (s *S) TestEndpointSignedURLs(c *C) {
+	s3 := s3.New(endpointAuth, aws.Region{Name: "faux-region-1"})
+	s3.Endpoint = "https://storage.example.com"
+	b := s3.Bucket("bucket")
+	expires := time.Now().Add(time.Hour)
+
+	c.Assert(b.SignedURL("name", expires), Matches, "https://bucket.storage.example.com/name\\?.*Signature=.*")
+	c.Assert(b.UploadSignedURL("name", "PUT", "text/plain", expires), Matches, "https://bucket.storage.example.com/name\\?.*Signature=.*")
+	action, _ := b.PostFormArgs("name", expires, "")
+	c.Assert(action, Equals, "https://bucket.storage.example.com/")
+
+	s3.PathStyle = true
+	c.Assert(b.SignedURL("name", expires), Matches, "https://storage.example.com/bucket/name\\?.*")
+	c.Assert(b.UploadSignedURL("name", "PUT", "text/plain", expires), Matches, "https://storage.example.com/bucket/name\\?.*")
+	action, _ = b.PostFormArgs("name", expires, "")
+	c.Assert(action, Equals, "https://storage.example.com/bucket/")
+}
+
+func (s *S) TestHTTPClient(c *C) {
+	testServer.Response(200, nil, "content")
+
+	var used bool
+	client := testServerClient()
+	transport := client.Transport
+	client.Transport = roundTripper(func(req *http.Request) (*http.Response, error) {
+		used = true
+		return transport.RoundTrip(req)
+	})
+	s3 := s3.New(endpointAuth, aws.Region{Name: "faux-region-1", S3Endpoint: testServer.URL})
+	s3.HTTPClient = client
+	_, err := s3.Bucket("bucket").Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(used, Equals, true)
+	c.Assert(strings.HasPrefix(testServer.WaitRequest().URL.Path, "/bucket/"), Equals, true)
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -15,3 +15,9 @@ func SetListPartsMax(n int) {
 func SetListMultiMax(n int) {
 	listMultiMax = n
 }
+
+func SetStreamingChunkSize(n int) (restore func()) {
+	old := streamingChunkSize
+	streamingChunkSize = n
+	return func() { streamingChunkSize = old }
+}
@@ -0,0 +1,277 @@
+package s3
+
+import (
+	"sort"
+	"strconv"
+)
+
+// pager walks the entries of a paginated listing, fetching pages as they
+// are needed and merging the items and common prefixes of each page in
+// lexical order, as S3 orders them.
+type pager struct {
+	// fetch retrieves the next page, returning the names of its
+	// items, its common prefixes, and whether more pages follow.
+	fetch func() (names, prefixes []string, more bool, err error)
+
+	names    []string
+	prefixes []string
+	i, j     int
+	more     bool
+
+	item   int    // Index of the current item in names, or -1.
+	common string // Current common prefix, if item is -1.
+	err    error
+}
+
+func newPager(fetch func() ([]string, []string, bool, error)) pager {
+	return pager{fetch: fetch, more: true, item: -1}
+}
+
+func (p *pager) next() bool {
+	for p.i >= len(p.names) && p.j >= len(p.prefixes) {
+		if !p.more || p.err != nil {
+			p.item, p.common = -1, ""
+			return false
+		}
+		p.names, p.prefixes, p.more, p.err = p.fetch()
+		p.i, p.j = 0, 0
+	}
+	if p.j >= len(p.prefixes) || p.i < len(p.names) && p.names[p.i] < p.prefixes[p.j] {
+		p.item, p.common = p.i, ""
+		p.i++
+	} else {
+		p.item, p.common = -1, p.prefixes[p.j]
+		p.j++
+	}
+	return true
+}
+
+// nextMarker returns the marker to continue a listing after a page whose
+// last item and common prefix are the last elements of names and prefixes.
+func nextMarker(names, prefixes []string) string {
+	var marker string
+	if len(names) > 0 {
+		marker = names[len(names)-1]
+	}
+	if len(prefixes) > 0 && prefixes[len(prefixes)-1] > marker {
+		marker = prefixes[len(prefixes)-1]
+	}
+	return marker
+}
+
+// ListIter iterates over the keys of a bucket, as listed by List.
+//
+// Pages of keys are requested as the iteration proceeds, so stopping
+// early, by not calling Next again, saves the requests for the pages
+// remaining and needs no cleanup:
+//
+//	iter := b.ListIter("photos/", "/")
+//	for iter.Next() {
+//		if prefix := iter.CommonPrefix(); prefix != "" {
+//			fmt.Println("dir", prefix)
+//		} else {
+//			fmt.Println("key", iter.Key().Key)
+//		}
+//	}
+//	if err := iter.Err(); err != nil {
+//		...
+//	}
+type ListIter struct {
+	pager
+	keys []Key
+}
+
+// ListIter returns an iterator over the keys in b beginning with prefix,
+// with the keys sharing a common prefix up to the next delim grouped
+// together, as with List.
+func (b *Bucket) ListIter(prefix, delim string) *ListIter {
+	iter := &ListIter{}
+	marker := ""
+	iter.pager = newPager(func() ([]string, []string, bool, error) {
+		resp, err := b.List(prefix, delim, marker, 0)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		iter.keys = resp.Contents
+		names := make([]string, len(resp.Contents))
+		for i, key := range resp.Contents {
+			names[i] = key.Key
+		}
+		// S3 only sends NextMarker when a delimiter is given.
+		next := resp.NextMarker
+		if next == "" {
+			next = nextMarker(names, resp.CommonPrefixes)
+		}
+		more := resp.IsTruncated && next != marker
+		marker = next
+		return names, resp.CommonPrefixes, more, nil
+	})
+	return iter
+}
+
+// Next advances to the next key or common prefix, and reports whether
+// there is one. It returns false at the end of the listing or when an
+// error occurs; see Err.
+func (iter *ListIter) Next() bool {
+	return iter.next()
+}
+
+// Key returns the current key. It is empty if the iterator is at a
+// common prefix.
+func (iter *ListIter) Key() Key {
+	if iter.item < 0 {
+		return Key{}
+	}
+	return iter.keys[iter.item]
+}
+
+// CommonPrefix returns the current common prefix, or "" if the iterator
+// is at a key.
+func (iter *ListIter) CommonPrefix() string {
+	return iter.common
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (iter *ListIter) Err() error {
+	return iter.err
+}
+
+// VersionIter iterates over the object versions of a bucket, as listed by
+// Versions, with the delete markers listed interleaved as S3 orders them:
+// by key, then from the newest version to the oldest. See ListIter for how
+// it is used.
+type VersionIter struct {
+	pager
+	versions []Version
+}
+
+// VersionsIter returns an iterator over the versions of the keys in b
+// beginning with prefix, with the keys sharing a common prefix up to the
+// next delim grouped together, as with Versions.
+func (b *Bucket) VersionsIter(prefix, delim string) *VersionIter {
+	iter := &VersionIter{}
+	keyMarker, versionIdMarker := "", ""
+	iter.pager = newPager(func() ([]string, []string, bool, error) {
+		resp, err := b.Versions(prefix, delim, keyMarker, versionIdMarker, 0)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		versions := append(resp.Versions, resp.DeleteMarkers...)
+		for i := len(resp.Versions); i < len(versions); i++ {
+			versions[i].IsDeleteMarker = true
+		}
+		sort.Stable(versionOrder(versions))
+		iter.versions = versions
+		names := make([]string, len(versions))
+		for i, v := range versions {
+			names[i] = v.Key
+		}
+		more := resp.IsTruncated &&
+			(resp.NextKeyMarker != keyMarker || resp.NextVersionIdMarker != versionIdMarker)
+		keyMarker, versionIdMarker = resp.NextKeyMarker, resp.NextVersionIdMarker
+		return names, resp.CommonPrefixes, more, nil
+	})
+	return iter
+}
+
+// Next advances to the next version or common prefix, and reports whether
+// there is one. It returns false at the end of the listing or when an
+// error occurs; see Err.
+func (iter *VersionIter) Next() bool {
+	return iter.next()
+}
+
+// Version returns the current version or delete marker. It is empty if
+// the iterator is at a common prefix.
+func (iter *VersionIter) Version() Version {
+	if iter.item < 0 {
+		return Version{}
+	}
+	return iter.versions[iter.item]
+}
+
+// CommonPrefix returns the current common prefix, or "" if the iterator
+// is at a version.
+func (iter *VersionIter) CommonPrefix() string {
+	return iter.common
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (iter *VersionIter) Err() error {
+	return iter.err
+}
+
+// versionOrder sorts versions by key, then from the newest to the oldest.
+type versionOrder []Version
+
+func (v versionOrder) Len() int      { return len(v) }
+func (v versionOrder) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+func (v versionOrder) Less(i, j int) bool {
+	if v[i].Key != v[j].Key {
+		return v[i].Key < v[j].Key
+	}
+	return v[i].LastModified > v[j].LastModified
+}
+
+// MultiIter iterates over the unfinished multipart uploads of a bucket, as
+// listed by ListMulti. See ListIter for how it is used.
+type MultiIter struct {
+	pager
+	multis []Multi
+}
+
+// ListMultiIter returns an iterator over the unfinished multipart uploads
+// in b of keys beginning with prefix, with the keys sharing a common
+// prefix up to the next delim grouped together, as with ListMulti.
+func (b *Bucket) ListMultiIter(prefix, delim string) *MultiIter {
+	iter := &MultiIter{}
+	params := map[string][]string{
+		"uploads":     {""},
+		"max-uploads": {strconv.FormatInt(int64(listMultiMax), 10)},
+		"prefix":      {prefix},
+		"delimiter":   {delim},
+	}
+	iter.pager = newPager(func() ([]string, []string, bool, error) {
+		resp, err := b.listMultiPage(params)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		iter.multis = resp.Upload
+		names := make([]string, len(resp.Upload))
+		for i := range resp.Upload {
+			resp.Upload[i].Bucket = b
+			names[i] = resp.Upload[i].Key
+		}
+		params["key-marker"] = []string{resp.NextKeyMarker}
+		params["upload-id-marker"] = []string{resp.NextUploadIdMarker}
+		return names, resp.CommonPrefixes, resp.IsTruncated, nil
+	})
+	return iter
+}
+
+// Next advances to the next multipart upload or common prefix, and
+// reports whether there is one. It returns false at the end of the
+// listing or when an error occurs; see Err.
+func (iter *MultiIter) Next() bool {
+	return iter.next()
+}
+
+// Multi returns the current multipart upload, or nil if the iterator is
+// at a common prefix.
+func (iter *MultiIter) Multi() *Multi {
+	if iter.item < 0 {
+		return nil
+	}
+	return &iter.multis[iter.item]
+}
+
+// CommonPrefix returns the current common prefix, or "" if the iterator
+// is at a multipart upload.
+func (iter *MultiIter) CommonPrefix() string {
+	return iter.common
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (iter *MultiIter) Err() error {
+	return iter.err
+}
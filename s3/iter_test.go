@@ -0,0 +1,173 @@
+package s3_test
+
+import (
+	"github.com/goamz/goamz/s3"
+	. "gopkg.in/check.v1"
+)
+
+var listIterDump1 = `
+<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Name>bucket</Name>
+  <Delimiter>/</Delimiter>
+  <NextMarker>c</NextMarker>
+  <IsTruncated>true</IsTruncated>
+  <Contents><Key>a</Key><Size>1</Size></Contents>
+  <Contents><Key>c</Key><Size>3</Size></Contents>
+  <CommonPrefixes><Prefix>b/</Prefix></CommonPrefixes>
+</ListBucketResult>
+`
+
+var listIterDump2 = `
+<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Name>bucket</Name>
+  <Delimiter>/</Delimiter>
+  <IsTruncated>false</IsTruncated>
+  <Contents><Key>d</Key><Size>4</Size></Contents>
+  <CommonPrefixes><Prefix>e/</Prefix></CommonPrefixes>
+</ListBucketResult>
+`
+
+func (s *S) TestListIter(c *C) {
+	testServer.Response(200, nil, listIterDump1)
+	testServer.Response(200, nil, listIterDump2)
+
+	iter := s.s3.Bucket("bucket").ListIter("", "/")
+	var got []string
+	for iter.Next() {
+		if prefix := iter.CommonPrefix(); prefix != "" {
+			c.Assert(iter.Key(), DeepEquals, s3.Key{})
+			got = append(got, "prefix "+prefix)
+		} else {
+			got = append(got, "key "+iter.Key().Key)
+		}
+	}
+	c.Assert(iter.Err(), IsNil)
+	c.Assert(got, DeepEquals, []string{"key a", "prefix b/", "key c", "key d", "prefix e/"})
+	c.Assert(iter.Next(), Equals, false)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["delimiter"], DeepEquals, []string{"/"})
+	c.Assert(req.Form["marker"], DeepEquals, []string{""})
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["marker"], DeepEquals, []string{"c"})
+}
+
+func (s *S) TestListIterNoNextMarker(c *C) {
+	testServer.Response(200, nil, `<ListBucketResult><IsTruncated>true</IsTruncated><Contents><Key>a</Key></Contents><Contents><Key>b</Key></Contents></ListBucketResult>`)
+	testServer.Response(200, nil, GetListResultDump1)
+
+	iter := s.s3.Bucket("bucket").ListIter("", "")
+	var keys []string
+	for iter.Next() {
+		keys = append(keys, iter.Key().Key)
+	}
+	c.Assert(iter.Err(), IsNil)
+	c.Assert(keys, DeepEquals, []string{"a", "b", "Nelson", "Neo"})
+
+	testServer.WaitRequest()
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["marker"], DeepEquals, []string{"b"})
+}
+
+func (s *S) TestListIterError(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, nil, listIterDump1)
+	testServer.Response(403, nil, AccessDeniedErrorDump)
+
+	iter := s.s3.Bucket("bucket").ListIter("", "/")
+	n := 0
+	for iter.Next() {
+		n++
+	}
+	c.Assert(n, Equals, 3)
+	c.Assert(iter.Err(), ErrorMatches, "Access Denied")
+	c.Assert(iter.Next(), Equals, false)
+	testServer.WaitRequests(2)
+}
+
+var versionsIterDump1 = `
+<?xml version="1.0" encoding="UTF-8"?>
+<ListVersionsResult xmlns="http://s3.amazonaws.com/doc/2006-03-01">
+  <Name>bucket</Name>
+  <NextKeyMarker>a</NextKeyMarker>
+  <NextVersionIdMarker>v1</NextVersionIdMarker>
+  <IsTruncated>true</IsTruncated>
+  <Version><Key>a</Key><VersionId>v2</VersionId><IsLatest>true</IsLatest></Version>
+  <Version><Key>a</Key><VersionId>v1</VersionId><IsLatest>false</IsLatest></Version>
+</ListVersionsResult>
+`
+
+var versionsIterDump2 = `
+<?xml version="1.0" encoding="UTF-8"?>
+<ListVersionsResult xmlns="http://s3.amazonaws.com/doc/2006-03-01">
+  <Name>bucket</Name>
+  <IsTruncated>false</IsTruncated>
+  <Version><Key>a</Key><VersionId>v0</VersionId><IsLatest>false</IsLatest></Version>
+  <Version><Key>b</Key><VersionId>v3</VersionId><IsLatest>true</IsLatest></Version>
+</ListVersionsResult>
+`
+
+func (s *S) TestVersionsIter(c *C) {
+	testServer.Response(200, nil, versionsIterDump1)
+	testServer.Response(200, nil, versionsIterDump2)
+
+	iter := s.s3.Bucket("bucket").VersionsIter("", "")
+	var got []string
+	for iter.Next() {
+		v := iter.Version()
+		got = append(got, v.Key+" "+v.VersionId)
+	}
+	c.Assert(iter.Err(), IsNil)
+	c.Assert(got, DeepEquals, []string{"a v2", "a v1", "a v0", "b v3"})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["versions"], DeepEquals, []string{""})
+	c.Assert(req.Form["key-marker"], IsNil)
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["key-marker"], DeepEquals, []string{"a"})
+	c.Assert(req.Form["version-id-marker"], DeepEquals, []string{"v1"})
+}
+
+func (s *S) TestVersionsIterDeleteMarkers(c *C) {
+	testServer.Response(200, nil, `
+<ListVersionsResult xmlns="http://s3.amazonaws.com/doc/2006-03-01">
+  <IsTruncated>false</IsTruncated>
+  <DeleteMarker><Key>a</Key><VersionId>v3</VersionId><IsLatest>true</IsLatest><LastModified>2009-10-12T17:50:32.000Z</LastModified></DeleteMarker>
+  <Version><Key>a</Key><VersionId>v2</VersionId><IsLatest>false</IsLatest><LastModified>2009-10-12T17:50:31.000Z</LastModified></Version>
+  <DeleteMarker><Key>a</Key><VersionId>v1</VersionId><IsLatest>false</IsLatest><LastModified>2009-10-12T17:50:30.000Z</LastModified></DeleteMarker>
+  <Version><Key>b</Key><VersionId>v4</VersionId><IsLatest>true</IsLatest><LastModified>2009-10-12T17:50:29.000Z</LastModified></Version>
+</ListVersionsResult>
+`)
+
+	iter := s.s3.Bucket("bucket").VersionsIter("", "")
+	var got []string
+	for iter.Next() {
+		v := iter.Version()
+		if v.IsDeleteMarker {
+			got = append(got, v.Key+" marker "+v.VersionId)
+		} else {
+			got = append(got, v.Key+" "+v.VersionId)
+		}
+	}
+	c.Assert(iter.Err(), IsNil)
+	c.Assert(got, DeepEquals, []string{"a marker v3", "a v2", "a marker v1", "b v4"})
+}
+
+func (s *S) TestListMultiIter(c *C) {
+	testServer.Response(200, nil, ListMultiResultDump)
+
+	iter := s.s3.Bucket("sample").ListMultiIter("", "/")
+	var got []string
+	for iter.Next() {
+		if m := iter.Multi(); m != nil {
+			c.Assert(m.Bucket.Name, Equals, "sample")
+			got = append(got, m.Key+" "+m.UploadId)
+		} else {
+			got = append(got, iter.CommonPrefix())
+		}
+	}
+	c.Assert(iter.Err(), IsNil)
+	c.Assert(got, DeepEquals, []string{"a/", "b/", "multi1 iUVug89pPvSswrikD", "multi2 DkirwsSvPp98guVUi"})
+}
@@ -0,0 +1,97 @@
+package s3
+
+import (
+	"encoding/xml"
+)
+
+// The statuses of lifecycle rules.
+const (
+	LifecycleRuleEnabled  = "Enabled"
+	LifecycleRuleDisabled = "Disabled"
+)
+
+// LifecycleConfiguration holds the rules S3 follows to expire the objects
+// of a bucket or move them to other storage classes.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/dev/object-lifecycle-mgmt.html
+// for an overview.
+type LifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"LifecycleConfiguration"`
+	Rules   []LifecycleRule `xml:"Rule"`
+}
+
+// LifecycleRule applies actions to the objects whose keys begin with
+// Prefix. An empty Prefix applies the rule to all the objects of the
+// bucket.
+type LifecycleRule struct {
+	ID     string `xml:"ID,omitempty"`
+	Prefix string
+	Status string // LifecycleRuleEnabled or LifecycleRuleDisabled
+
+	Expiration                     *LifecycleExpiration            `xml:",omitempty"`
+	Transitions                    []LifecycleTransition           `xml:"Transition"`
+	NoncurrentVersionExpiration    *NoncurrentVersionExpiration    `xml:",omitempty"`
+	AbortIncompleteMultipartUpload *AbortIncompleteMultipartUpload `xml:",omitempty"`
+}
+
+// LifecycleExpiration expires objects a number of days after their
+// creation, or on a date, given as midnight UTC in ISO 8601 format,
+// e.g. "2016-01-01T00:00:00.000Z".
+//
+// In versioned buckets, expiring the current version of an object leaves
+// a delete marker in its place. ExpiredObjectDeleteMarker instead removes
+// delete markers left with no noncurrent versions.
+type LifecycleExpiration struct {
+	Days                      int    `xml:",omitempty"`
+	Date                      string `xml:",omitempty"`
+	ExpiredObjectDeleteMarker bool   `xml:",omitempty"`
+}
+
+// LifecycleTransition moves objects to StorageClass a number of days after
+// their creation, or on a date, as for LifecycleExpiration.
+type LifecycleTransition struct {
+	Days         int    `xml:",omitempty"`
+	Date         string `xml:",omitempty"`
+	StorageClass string // StorageClassStandardIA or StorageClassGlacier
+}
+
+// NoncurrentVersionExpiration removes the versions of objects a number of
+// days after they are replaced by a newer version.
+type NoncurrentVersionExpiration struct {
+	NoncurrentDays int
+}
+
+// AbortIncompleteMultipartUpload aborts the multipart uploads not
+// completed a number of days after they were initiated.
+type AbortIncompleteMultipartUpload struct {
+	DaysAfterInitiation int
+}
+
+// PutLifecycleConfiguration replaces the lifecycle configuration of b.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUTlifecycle.html
+// for details.
+func (b *Bucket) PutLifecycleConfiguration(c *LifecycleConfiguration) error {
+	return b.putBucketSubresourceXML("lifecycle", c)
+}
+
+// GetLifecycleConfiguration returns the lifecycle configuration of b. If b
+// has none, the error returned has the code NoSuchLifecycleConfiguration.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketGETlifecycle.html
+// for details.
+func (b *Bucket) GetLifecycleConfiguration() (*LifecycleConfiguration, error) {
+	c := &LifecycleConfiguration{}
+	if err := b.getBucketSubresource("lifecycle", c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DeleteLifecycleConfiguration removes the lifecycle configuration of b.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketDELETElifecycle.html
+// for details.
+func (b *Bucket) DeleteLifecycleConfiguration() error {
+	return b.delBucketSubresource("lifecycle")
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
 	"sort"
 	"strconv"
@@ -23,6 +24,15 @@ type Multi struct {
 	Bucket   *Bucket
 	Key      string
 	UploadId string
+
+	// SSECustomerKey is the key the upload was initiated with, if it is
+	// encrypted with a customer provided key. Parts are sent with it.
+	// Multis returned by ListMulti do not know it and must have it set.
+	SSECustomerKey []byte `xml:"-"`
+
+	// CopySourceSSECustomerKey is the key the sources of the parts copied
+	// with PutPartCopy were encrypted with, if any.
+	CopySourceSSECustomerKey []byte `xml:"-"`
 }
 
 // That's the default. Here just for testing.
@@ -49,13 +59,24 @@ type listMultiResp struct {
 //
 // See http://goo.gl/ePioY for details.
 func (b *Bucket) ListMulti(prefix, delim string) (multis []*Multi, prefixes []string, err error) {
-	params := map[string][]string{
-		"uploads":     {""},
-		"max-uploads": {strconv.FormatInt(int64(listMultiMax), 10)},
-		"prefix":      {prefix},
-		"delimiter":   {delim},
+	iter := b.ListMultiIter(prefix, delim)
+	for iter.Next() {
+		if m := iter.Multi(); m != nil {
+			multis = append(multis, m)
+		} else {
+			prefixes = append(prefixes, iter.CommonPrefix())
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, nil, err
 	}
-	for attempt := b.S3.AttemptStrategy.Start(); attempt.Next(); {
+	return multis, prefixes, nil
+}
+
+// listMultiPage retrieves the page of unfinished multipart uploads in b
+// selected by params.
+func (b *Bucket) listMultiPage(params map[string][]string) (*listMultiResp, error) {
+	for attempt := b.S3.attempts(); attempt.Next(); {
 		req := &request{
 			method: "GET",
 			bucket: b.Name,
@@ -67,20 +88,9 @@ func (b *Bucket) ListMulti(prefix, delim string) (multis []*Multi, prefixes []st
 			continue
 		}
 		if err != nil {
-			return nil, nil, err
-		}
-		for i := range resp.Upload {
-			multi := &resp.Upload[i]
-			multi.Bucket = b
-			multis = append(multis, multi)
-		}
-		prefixes = append(prefixes, resp.CommonPrefixes...)
-		if !resp.IsTruncated {
-			return multis, prefixes, nil
+			return nil, err
 		}
-		params["key-marker"] = []string{resp.NextKeyMarker}
-		params["upload-id-marker"] = []string{resp.NextUploadIdMarker}
-		attempt = b.S3.AttemptStrategy.Start() // Last request worked.
+		return &resp, nil
 	}
 	panic("unreachable")
 }
@@ -106,11 +116,18 @@ func (b *Bucket) Multi(key, contType string, perm ACL) (*Multi, error) {
 //
 // See http://goo.gl/XP8kL for details.
 func (b *Bucket) InitMulti(key string, contType string, perm ACL) (*Multi, error) {
+	return b.InitMultiOptions(key, contType, perm, Options{})
+}
+
+// InitMultiOptions is like InitMulti, but also sets the options given,
+// such as metadata, on the object the multipart upload creates.
+func (b *Bucket) InitMultiOptions(key string, contType string, perm ACL, options Options) (*Multi, error) {
 	headers := map[string][]string{
 		"Content-Type":   {contType},
 		"Content-Length": {"0"},
 		"x-amz-acl":      {string(perm)},
 	}
+	options.addHeaders(headers)
 	params := map[string][]string{
 		"uploads": {""},
 	}
@@ -125,7 +142,7 @@ func (b *Bucket) InitMulti(key string, contType string, perm ACL) (*Multi, error
 	var resp struct {
 		UploadId string `xml:"UploadId"`
 	}
-	for attempt := b.S3.AttemptStrategy.Start(); attempt.Next(); {
+	for attempt := b.S3.attempts(); attempt.Next(); {
 		err = b.S3.query(req, &resp)
 		if !shouldRetry(err) {
 			break
@@ -134,7 +151,7 @@ func (b *Bucket) InitMulti(key string, contType string, perm ACL) (*Multi, error
 	if err != nil {
 		return nil, err
 	}
-	return &Multi{Bucket: b, Key: key, UploadId: resp.UploadId}, nil
+	return &Multi{Bucket: b, Key: key, UploadId: resp.UploadId, SSECustomerKey: options.SSECustomerKey}, nil
 }
 
 // PutPart sends part n of the multipart upload, reading all the content from r.
@@ -154,11 +171,14 @@ func (m *Multi) putPart(n int, r io.ReadSeeker, partSize int64, md5b64 string) (
 		"Content-Length": {strconv.FormatInt(partSize, 10)},
 		"Content-MD5":    {md5b64},
 	}
+	if m.SSECustomerKey != nil {
+		addCustomerKeyHeaders(headers, "x-amz-", m.SSECustomerKey)
+	}
 	params := map[string][]string{
 		"uploadId":   {m.UploadId},
 		"partNumber": {strconv.FormatInt(int64(n), 10)},
 	}
-	for attempt := m.Bucket.S3.AttemptStrategy.Start(); attempt.Next(); {
+	for attempt := m.Bucket.S3.attempts(); attempt.Next(); {
 		_, err := r.Seek(0, 0)
 		if err != nil {
 			return Part{}, err
@@ -191,6 +211,63 @@ func (m *Multi) putPart(n int, r io.ReadSeeker, partSize int64, md5b64 string) (
 	panic("unreachable")
 }
 
+// PutPartCopy makes part n of the multipart upload a copy of size bytes of
+// the object source, given as "bucket/key", starting at offset. The data
+// is copied by S3 without going through the client. Parts copied are
+// subject to the same size limits as those sent with PutPart.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadUploadPartCopy.html
+// for details.
+func (m *Multi) PutPartCopy(n int, source string, offset, size int64) (Part, error) {
+	headers := map[string][]string{
+		"x-amz-copy-source":       {source},
+		"x-amz-copy-source-range": {fmt.Sprintf("bytes=%d-%d", offset, offset+size-1)},
+	}
+	if m.SSECustomerKey != nil {
+		addCustomerKeyHeaders(headers, "x-amz-", m.SSECustomerKey)
+	}
+	if m.CopySourceSSECustomerKey != nil {
+		addCustomerKeyHeaders(headers, "x-amz-copy-source-", m.CopySourceSSECustomerKey)
+	}
+	params := map[string][]string{
+		"uploadId":   {m.UploadId},
+		"partNumber": {strconv.FormatInt(int64(n), 10)},
+	}
+	req := &request{
+		method:  "PUT",
+		bucket:  m.Bucket.Name,
+		path:    m.Key,
+		headers: headers,
+		params:  params,
+	}
+	var err error
+	var etag string
+	for attempt := m.Bucket.S3.attempts(); attempt.Next(); {
+		var resp struct {
+			ETag    string
+			Code    string
+			Message string
+		}
+		err = m.Bucket.S3.query(req, &resp)
+		if err == nil && resp.Code != "" {
+			// S3 may only find the copy failed after sending
+			// the 200 status, and sends an error body with it.
+			err = &Error{StatusCode: 200, Code: resp.Code, Message: resp.Message}
+		}
+		etag = resp.ETag
+		if !shouldRetry(err) {
+			break
+		}
+	}
+	if err != nil {
+		return Part{}, err
+	}
+	if etag == "" {
+		return Part{}, errors.New("part copy succeeded with no ETag")
+	}
+	return Part{n, etag, size}, nil
+}
+
 func seekerInfo(r io.ReadSeeker) (size int64, md5hex string, md5b64 string, err error) {
 	_, err = r.Seek(0, 0)
 	if err != nil {
@@ -238,7 +315,7 @@ func (m *Multi) ListParts() ([]Part, error) {
 		"max-parts": {strconv.FormatInt(int64(listPartsMax), 10)},
 	}
 	var parts partSlice
-	for attempt := m.Bucket.S3.AttemptStrategy.Start(); attempt.Next(); {
+	for attempt := m.Bucket.S3.attempts(); attempt.Next(); {
 		req := &request{
 			method: "GET",
 			bucket: m.Bucket.Name,
@@ -259,7 +336,7 @@ func (m *Multi) ListParts() ([]Part, error) {
 			return parts, nil
 		}
 		params["part-number-marker"] = []string{resp.NextPartNumberMarker}
-		attempt = m.Bucket.S3.AttemptStrategy.Start() // Last request worked.
+		attempt = m.Bucket.S3.attempts() // Last request worked.
 	}
 	panic("unreachable")
 }
@@ -356,7 +433,7 @@ func (m *Multi) Complete(parts []Part) error {
 	if err != nil {
 		return err
 	}
-	for attempt := m.Bucket.S3.AttemptStrategy.Start(); attempt.Next(); {
+	for attempt := m.Bucket.S3.attempts(); attempt.Next(); {
 		req := &request{
 			method:  "POST",
 			bucket:  m.Bucket.Name,
@@ -392,7 +469,7 @@ func (m *Multi) Abort() error {
 	params := map[string][]string{
 		"uploadId": {m.UploadId},
 	}
-	for attempt := m.Bucket.S3.AttemptStrategy.Start(); attempt.Next(); {
+	for attempt := m.Bucket.S3.attempts(); attempt.Next(); {
 		req := &request{
 			method: "DELETE",
 			bucket: m.Bucket.Name,
@@ -138,6 +138,51 @@ func (s *S) TestPutPart(c *C) {
 	c.Assert(req.Header["Content-Md5"], DeepEquals, []string{"JvkO/RDWFPEAJS/1bYja2A=="})
 }
 
+func (s *S) TestPutPartCopy(c *C) {
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(200, nil, `<CopyPartResult><LastModified>2011-04-11T20:34:56.000Z</LastModified><ETag>"9b2cf535f27731c974343645a3985328"</ETag></CopyPartResult>`)
+
+	b := s.s3.Bucket("sample")
+	multi, err := b.InitMulti("multi", "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+
+	part, err := multi.PutPartCopy(2, "source/key", 5242880, 1000)
+	c.Assert(err, IsNil)
+	c.Assert(part, DeepEquals, s3.Part{N: 2, ETag: `"9b2cf535f27731c974343645a3985328"`, Size: 1000})
+
+	testServer.WaitRequest()
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "PUT")
+	c.Assert(req.URL.Path, Equals, "/sample/multi")
+	c.Assert(req.Form.Get("uploadId"), Matches, "JNbR_[A-Za-z0-9.]+QQ--")
+	c.Assert(req.Form["partNumber"], DeepEquals, []string{"2"})
+	c.Assert(req.Header.Get("x-amz-copy-source"), Equals, "source/key")
+	c.Assert(req.Header.Get("x-amz-copy-source-range"), Equals, "bytes=5242880-5243879")
+}
+
+func (s *S) TestPutPartCopyErrorAfterStatus(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, nil, InternalErrorDump)
+
+	multi := &s3.Multi{Bucket: s.s3.Bucket("sample"), Key: "multi", UploadId: "id"}
+	_, err := multi.PutPartCopy(1, "source/key", 0, 10)
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "InternalError")
+	testServer.WaitRequest()
+}
+
+func (s *S) TestPutPartCopyRetryErrorAfterStatus(c *C) {
+	testServer.Response(200, nil, InternalErrorDump)
+	testServer.Response(200, nil, `<CopyPartResult><LastModified>2011-04-11T20:34:56.000Z</LastModified><ETag>"9b2cf535f27731c974343645a3985328"</ETag></CopyPartResult>`)
+
+	multi := &s3.Multi{Bucket: s.s3.Bucket("sample"), Key: "multi", UploadId: "id"}
+	part, err := multi.PutPartCopy(1, "source/key", 0, 10)
+	c.Assert(err, IsNil)
+	c.Assert(part, DeepEquals, s3.Part{N: 1, ETag: `"9b2cf535f27731c974343645a3985328"`, Size: 10})
+	testServer.WaitRequest()
+	testServer.WaitRequest()
+}
+
 func readAll(r io.Reader) string {
 	data, err := ioutil.ReadAll(r)
 	if err != nil {
@@ -0,0 +1,113 @@
+package s3
+
+import (
+	"encoding/xml"
+)
+
+// The events S3 can send notifications for. The events ending in "*"
+// stand for all the events of their kind.
+const (
+	EventObjectCreated                        = "s3:ObjectCreated:*"
+	EventObjectCreatedPut                     = "s3:ObjectCreated:Put"
+	EventObjectCreatedPost                    = "s3:ObjectCreated:Post"
+	EventObjectCreatedCopy                    = "s3:ObjectCreated:Copy"
+	EventObjectCreatedCompleteMultipartUpload = "s3:ObjectCreated:CompleteMultipartUpload"
+	EventObjectRemoved                        = "s3:ObjectRemoved:*"
+	EventObjectRemovedDelete                  = "s3:ObjectRemoved:Delete"
+	EventObjectRemovedDeleteMarkerCreated     = "s3:ObjectRemoved:DeleteMarkerCreated"
+	EventReducedRedundancyLostObject          = "s3:ReducedRedundancyLostObject"
+)
+
+// NotificationConfiguration holds where S3 sends notifications of the
+// events happening to the objects of a bucket: to SQS queues, SNS topics
+// or Lambda functions.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/dev/NotificationHowTo.html
+// for an overview, and for the permissions the destinations must grant.
+type NotificationConfiguration struct {
+	XMLName xml.Name                      `xml:"NotificationConfiguration"`
+	Queues  []QueueConfiguration          `xml:"QueueConfiguration"`
+	Topics  []TopicConfiguration          `xml:"TopicConfiguration"`
+	Lambdas []LambdaFunctionConfiguration `xml:"CloudFunctionConfiguration"`
+}
+
+// QueueConfiguration sends notifications of Events to the SQS queue whose
+// ARN is Queue.
+type QueueConfiguration struct {
+	Id     string `xml:",omitempty"`
+	Queue  string
+	Events []string            `xml:"Event"`
+	Filter *NotificationFilter `xml:",omitempty"`
+}
+
+// TopicConfiguration publishes notifications of Events to the SNS topic
+// whose ARN is Topic.
+type TopicConfiguration struct {
+	Id     string `xml:",omitempty"`
+	Topic  string
+	Events []string            `xml:"Event"`
+	Filter *NotificationFilter `xml:",omitempty"`
+}
+
+// LambdaFunctionConfiguration invokes the Lambda function whose ARN is
+// Function with notifications of Events.
+type LambdaFunctionConfiguration struct {
+	Id       string              `xml:",omitempty"`
+	Function string              `xml:"CloudFunction"`
+	Events   []string            `xml:"Event"`
+	Filter   *NotificationFilter `xml:",omitempty"`
+}
+
+// NotificationFilter limits notifications to the objects whose keys match
+// all of its rules.
+type NotificationFilter struct {
+	Rules []FilterRule `xml:"S3Key>FilterRule"`
+}
+
+// FilterRule matches the keys beginning with Value if Name is "prefix",
+// or ending with it if Name is "suffix".
+type FilterRule struct {
+	Name  string
+	Value string
+}
+
+// KeyFilter returns a filter matching the keys beginning with prefix and
+// ending with suffix. Either may be empty to match any key.
+func KeyFilter(prefix, suffix string) *NotificationFilter {
+	f := &NotificationFilter{}
+	if prefix != "" {
+		f.Rules = append(f.Rules, FilterRule{"prefix", prefix})
+	}
+	if suffix != "" {
+		f.Rules = append(f.Rules, FilterRule{"suffix", suffix})
+	}
+	return f
+}
+
+// PutBucketNotification replaces the notification configuration of b.
+// S3 checks that it may send notifications to all the destinations given.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUTnotification.html
+// for details.
+func (b *Bucket) PutBucketNotification(c *NotificationConfiguration) error {
+	return b.putBucketSubresourceXML("notification", c)
+}
+
+// GetBucketNotification returns the notification configuration of b,
+// which is empty if no notifications are sent.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketGETnotification.html
+// for details.
+func (b *Bucket) GetBucketNotification() (*NotificationConfiguration, error) {
+	c := &NotificationConfiguration{}
+	if err := b.getBucketSubresource("notification", c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DeleteBucketNotification stops all the notifications sent for b, by
+// storing an empty notification configuration.
+func (b *Bucket) DeleteBucketNotification() error {
+	return b.PutBucketNotification(&NotificationConfiguration{})
+}
@@ -0,0 +1,41 @@
+package s3
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/url"
+)
+
+// PutBucketPolicy replaces the policy of b with the JSON policy document
+// given.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/dev/access-policy-language-overview.html
+// for how policies are written.
+func (b *Bucket) PutBucketPolicy(policy []byte) error {
+	headers := map[string][]string{
+		"Content-Type": {"application/json"},
+	}
+	return b.putSubresource("/", "policy", bytes.NewReader(policy), int64(len(policy)), headers)
+}
+
+// GetBucketPolicy returns the JSON policy document of b. If b has no
+// policy, the error returned has the code NoSuchBucketPolicy.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketGETpolicy.html
+// for details.
+func (b *Bucket) GetBucketPolicy() ([]byte, error) {
+	resp, err := b.getResponse("/", url.Values{"policy": {""}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// DeleteBucketPolicy removes the policy of b.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketDELETEpolicy.html
+// for details.
+func (b *Bucket) DeleteBucketPolicy() error {
+	return b.delBucketSubresource("policy")
+}
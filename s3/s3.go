@@ -12,6 +12,7 @@ package s3
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha1"
@@ -66,6 +67,30 @@ type S3 struct {
 	// A Timeout of zero means no timeout.
 	RequestTimeout time.Duration
 
+	// Endpoint, if set, is the URL of the service requests are sent to
+	// instead of the endpoints of Region, such as that of MinIO, Ceph or
+	// a local emulator. https is used if it has no scheme. Buckets are
+	// addressed as subdomains of its host, unless PathStyle is set or
+	// their name is not a valid host name.
+	Endpoint string
+
+	// PathStyle is whether buckets are always addressed in the path of
+	// requests, as in https://endpoint/bucket/key, rather than in their
+	// host, as in https://bucket.endpoint/key.
+	PathStyle bool
+
+	// SigningRegion, if set, is the region requests signed with AWS
+	// Signature Version 4 are signed for, instead of Region.Name.
+	SigningRegion string
+
+	// DisableSSL is whether requests are sent over plain HTTP even to
+	// https endpoints.
+	DisableSSL bool
+
+	// HTTPClient, if set, is the client requests are sent with. The
+	// timeouts above only apply to the default client.
+	HTTPClient *http.Client
+
 	// AttemptStrategy is the attempt strategy used for requests.
 	aws.AttemptStrategy
 
@@ -74,6 +99,12 @@ type S3 struct {
 
 	// client used for requests
 	client *http.Client
+
+	// provider supplies the credentials requests are signed with, if set.
+	provider aws.CredentialsProvider
+
+	// ctx is the context requests are made with, if set.
+	ctx context.Context
 }
 
 // The Bucket type encapsulates operations with an S3 bucket.
@@ -97,22 +128,55 @@ type Options struct {
 	CacheControl     string
 	RedirectLocation string
 	ContentMD5       string
+
+	// StorageClass is the storage class of the object, StorageClassStandard
+	// if empty. Tags are the tags of the object.
+	StorageClass string
+	Tags         []Tag
+
+	// SSEKMS has the object encrypted with a KMS key: SSEKMSKeyId, which
+	// implies SSEKMS, or the default S3 key of the account if empty.
+	// SSEKMSContext is the encryption context of the data key, which KMS
+	// logs with every use of it. Requests using KMS must be signed with
+	// Signature Version 4.
+	SSEKMS        bool
+	SSEKMSKeyId   string
+	SSEKMSContext map[string]string
+
+	// SSECustomerKey has the object encrypted with this 256-bit key, which
+	// S3 does not store. It must be given again to read the object, see
+	// SSECustomerKeyHeaders. S3 only accepts it over HTTPS.
+	SSECustomerKey []byte
 	// What else?
 	// Content-Disposition string
-	//// The following become headers so they are []strings rather than strings... I think
-	// x-amz-storage-class []string
 }
 
 type CopyOptions struct {
 	Options
 	MetadataDirective string
 	ContentType       string
+
+	// TaggingDirective is "REPLACE" to set the tags in Options on the
+	// copy instead of those of the source.
+	TaggingDirective string
+
+	// CopySourceSSECustomerKey is the key the source was encrypted with,
+	// if it was encrypted with a customer provided key.
+	CopySourceSSECustomerKey []byte
 }
 
 // CopyObjectResult is the output from a Copy request
 type CopyObjectResult struct {
 	ETag         string
 	LastModified string
+
+	// VersionId is the version of the copy, and SourceVersionId the
+	// version copied, when the buckets involved are versioned.
+	VersionId       string `xml:"-"`
+	SourceVersionId string `xml:"-"`
+
+	// Encryption is how S3 encrypted the copy.
+	Encryption Encryption `xml:"-"`
 }
 
 // DefaultAttemptStrategy is the default AttemptStrategy used by S3 objects created by New.
@@ -127,6 +191,43 @@ func New(auth aws.Auth, region aws.Region) *S3 {
 	return &S3{Auth: auth, Region: region, AttemptStrategy: DefaultAttemptStrategy}
 }
 
+// NewWithProvider creates a new S3 whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) *S3 {
+	s3 := New(aws.Auth{}, region)
+	s3.provider = provider
+	return s3
+}
+
+// credentials returns the credentials to sign the next request with.
+func (s3 *S3) credentials() (aws.Auth, error) {
+	if s3.provider == nil {
+		return s3.Auth.Current(), nil
+	}
+	return s3.provider.Credentials()
+}
+
+// WithContext returns a copy of s3 whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight and stops further retries.
+func (s3 *S3) WithContext(ctx context.Context) *S3 {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *s3
+	c.ctx = ctx
+	return &c
+}
+
+// attempts starts a new sequence of request attempts, abandoned as
+// soon as the context s3 is bound to is done.
+func (s3 *S3) attempts() *aws.Attempt {
+	if s3.ctx == nil {
+		return s3.AttemptStrategy.Start()
+	}
+	return s3.AttemptStrategy.StartWithContext(s3.ctx)
+}
+
 // Bucket returns a Bucket with the given name.
 func (s3 *S3) Bucket(name string) *Bucket {
 	if s3.Region.S3BucketEndpoint != "" || s3.Region.S3LowercaseBucket {
@@ -135,6 +236,12 @@ func (s3 *S3) Bucket(name string) *Bucket {
 	return &Bucket{s3, name}
 }
 
+// WithContext returns a copy of b whose requests are made with ctx.
+// See S3.WithContext for details.
+func (b *Bucket) WithContext(ctx context.Context) *Bucket {
+	return &Bucket{b.S3.WithContext(ctx), b.Name}
+}
+
 var createBucketConfiguration = `<CreateBucketConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
   <LocationConstraint>%s</LocationConstraint>
 </CreateBucketConfiguration>`
@@ -189,7 +296,7 @@ func (b *Bucket) DelBucket() (err error) {
 		bucket: b.Name,
 		path:   "/",
 	}
-	for attempt := b.S3.AttemptStrategy.Start(); attempt.Next(); {
+	for attempt := b.S3.attempts(); attempt.Next(); {
 		err = b.S3.query(req, nil)
 		if !shouldRetry(err) {
 			break
@@ -241,16 +348,21 @@ func (b *Bucket) GetResponse(path string) (resp *http.Response, err error) {
 // It is the caller's responsibility to call Close on rc when
 // finished reading
 func (b *Bucket) GetResponseWithHeaders(path string, headers map[string][]string) (resp *http.Response, err error) {
+	return b.getResponse(path, nil, headers)
+}
+
+func (b *Bucket) getResponse(path string, params url.Values, headers map[string][]string) (resp *http.Response, err error) {
 	req := &request{
 		bucket:  b.Name,
 		path:    path,
+		params:  params,
 		headers: headers,
 	}
 	err = b.S3.prepare(req)
 	if err != nil {
 		return nil, err
 	}
-	for attempt := b.S3.AttemptStrategy.Start(); attempt.Next(); {
+	for attempt := b.S3.attempts(); attempt.Next(); {
 		resp, err := b.S3.run(req, nil)
 		if shouldRetry(err) && attempt.HasNext() {
 			continue
@@ -274,7 +386,7 @@ func (b *Bucket) Exists(path string) (exists bool, err error) {
 	if err != nil {
 		return
 	}
-	for attempt := b.S3.AttemptStrategy.Start(); attempt.Next(); {
+	for attempt := b.S3.attempts(); attempt.Next(); {
 		resp, err := b.S3.run(req, nil)
 
 		if shouldRetry(err) && attempt.HasNext() {
@@ -300,10 +412,15 @@ func (b *Bucket) Exists(path string) (exists bool, err error) {
 // Head HEADs an object in the S3 bucket, returns the response with
 // no body see http://bit.ly/17K1ylI
 func (b *Bucket) Head(path string, headers map[string][]string) (*http.Response, error) {
+	return b.head(path, nil, headers)
+}
+
+func (b *Bucket) head(path string, params url.Values, headers map[string][]string) (*http.Response, error) {
 	req := &request{
 		method:  "HEAD",
 		bucket:  b.Name,
 		path:    path,
+		params:  params,
 		headers: headers,
 	}
 	err := b.S3.prepare(req)
@@ -311,7 +428,7 @@ func (b *Bucket) Head(path string, headers map[string][]string) (*http.Response,
 		return nil, err
 	}
 
-	for attempt := b.S3.AttemptStrategy.Start(); attempt.Next(); {
+	for attempt := b.S3.attempts(); attempt.Next(); {
 		resp, err := b.S3.run(req, nil)
 		if shouldRetry(err) && attempt.HasNext() {
 			continue
@@ -346,10 +463,17 @@ func (b *Bucket) PutCopy(path string, perm ACL, options CopyOptions, source stri
 		headers: headers,
 	}
 	resp := &CopyObjectResult{}
-	err := b.S3.query(req, resp)
+	err := b.S3.prepare(req)
 	if err != nil {
 		return resp, err
 	}
+	hresp, err := b.S3.run(req, resp)
+	if err != nil {
+		return resp, err
+	}
+	resp.VersionId = hresp.Header.Get("x-amz-version-id")
+	resp.SourceVersionId = hresp.Header.Get("x-amz-copy-source-version-id")
+	resp.Encryption = ResponseEncryption(hresp.Header)
 	return resp, nil
 }
 
@@ -410,8 +534,13 @@ func (b *Bucket) PutReaderHeader(path string, r io.Reader, length int64, customH
 
 // addHeaders adds o's specified fields to headers
 func (o Options) addHeaders(headers map[string][]string) {
-	if o.SSE {
-		headers["x-amz-server-side-encryption"] = []string{"AES256"}
+	if o.SSEKMS || o.SSEKMSKeyId != "" {
+		addKMSHeaders(headers, o.SSEKMSKeyId, o.SSEKMSContext)
+	} else if o.SSE {
+		headers["x-amz-server-side-encryption"] = []string{SSEAlgorithmAES256}
+	}
+	if o.SSECustomerKey != nil {
+		addCustomerKeyHeaders(headers, "x-amz-", o.SSECustomerKey)
 	}
 	if len(o.ContentEncoding) != 0 {
 		headers["Content-Encoding"] = []string{o.ContentEncoding}
@@ -425,6 +554,12 @@ func (o Options) addHeaders(headers map[string][]string) {
 	if len(o.RedirectLocation) != 0 {
 		headers["x-amz-website-redirect-location"] = []string{o.RedirectLocation}
 	}
+	if len(o.StorageClass) != 0 {
+		headers["x-amz-storage-class"] = []string{o.StorageClass}
+	}
+	if len(o.Tags) != 0 {
+		headers["x-amz-tagging"] = []string{encodeTags(o.Tags)}
+	}
 	for k, v := range o.Meta {
 		headers["x-amz-meta-"+k] = v
 	}
@@ -439,6 +574,12 @@ func (o CopyOptions) addHeaders(headers map[string][]string) {
 	if len(o.ContentType) != 0 {
 		headers["Content-Type"] = []string{o.ContentType}
 	}
+	if len(o.TaggingDirective) != 0 {
+		headers["x-amz-tagging-directive"] = []string{o.TaggingDirective}
+	}
+	if o.CopySourceSSECustomerKey != nil {
+		addCustomerKeyHeaders(headers, "x-amz-copy-source-", o.CopySourceSSECustomerKey)
+	}
 }
 
 func makeXmlBuffer(doc []byte) *bytes.Buffer {
@@ -473,30 +614,111 @@ func (b *Bucket) PutBucketWebsite(configuration WebsiteConfiguration) error {
 	return b.PutBucketSubresource("website", buf, int64(buf.Len()))
 }
 
+// PutBucketSubresource stores the length bytes read from r as the named
+// subresource of b.
 func (b *Bucket) PutBucketSubresource(subresource string, r io.Reader, length int64) error {
-	headers := map[string][]string{
+	return b.putSubresource("/", subresource, r, length, nil)
+}
+
+// putSubresource stores the length bytes read from r as the named
+// subresource of the object at path, or of b itself if path is "/",
+// sending the headers given along.
+func (b *Bucket) putSubresource(path, subresource string, r io.Reader, length int64, headers map[string][]string) error {
+	h := map[string][]string{
 		"Content-Length": {strconv.FormatInt(length, 10)},
 	}
+	for k, v := range headers {
+		h[k] = v
+	}
 	req := &request{
-		path:    "/",
+		path:    path,
 		method:  "PUT",
 		bucket:  b.Name,
-		headers: headers,
+		headers: h,
 		payload: r,
 		params:  url.Values{subresource: {""}},
 	}
-
 	return b.S3.query(req, nil)
 }
 
+// putBucketSubresourceXML stores v, marshalled as XML, as the named
+// subresource of b. The Content-MD5 header some subresources require is
+// always sent.
+func (b *Bucket) putBucketSubresourceXML(subresource string, v interface{}) error {
+	return b.putSubresourceXML("/", subresource, v)
+}
+
+// putSubresourceXML stores v, marshalled as XML, as the named subresource
+// of the object at path, or of b itself if path is "/".
+func (b *Bucket) putSubresourceXML(path, subresource string, v interface{}) error {
+	doc, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf := makeXmlBuffer(doc)
+	sum := md5.Sum(buf.Bytes())
+	headers := map[string][]string{
+		"Content-MD5": {base64.StdEncoding.EncodeToString(sum[:])},
+	}
+	return b.putSubresource(path, subresource, buf, int64(buf.Len()), headers)
+}
+
+// getBucketSubresource unmarshals the named subresource of b into resp.
+func (b *Bucket) getBucketSubresource(subresource string, resp interface{}) error {
+	return b.getSubresource("/", subresource, resp)
+}
+
+// getSubresource unmarshals the named subresource of the object at path,
+// or of b itself if path is "/", into resp.
+func (b *Bucket) getSubresource(path, subresource string, resp interface{}) (err error) {
+	req := &request{
+		path:   path,
+		bucket: b.Name,
+		params: url.Values{subresource: {""}},
+	}
+	for attempt := b.S3.attempts(); attempt.Next(); {
+		err = b.S3.query(req, resp)
+		if !shouldRetry(err) {
+			break
+		}
+	}
+	return err
+}
+
+// delBucketSubresource removes the named subresource of b.
+func (b *Bucket) delBucketSubresource(subresource string) error {
+	return b.delSubresource("/", subresource)
+}
+
+func (b *Bucket) delSubresource(path, subresource string) (err error) {
+	req := &request{
+		path:   path,
+		method: "DELETE",
+		bucket: b.Name,
+		params: url.Values{subresource: {""}},
+	}
+	for attempt := b.S3.attempts(); attempt.Next(); {
+		err = b.S3.query(req, nil)
+		if !shouldRetry(err) {
+			break
+		}
+	}
+	return err
+}
+
 // Del removes an object from the S3 bucket.
 //
 // See http://goo.gl/APeTt for details.
 func (b *Bucket) Del(path string) error {
+	return b.del(path, nil)
+}
+
+func (b *Bucket) del(path string, params url.Values) error {
 	req := &request{
 		method: "DELETE",
 		bucket: b.Name,
 		path:   path,
+		params: params,
 	}
 	return b.S3.query(req, nil)
 }
@@ -511,10 +733,55 @@ type Object struct {
 	VersionId string `xml:"VersionId,omitempty"`
 }
 
+// DeleteResult holds the outcome of a DelMulti request. Unless the
+// request was Quiet, every object deleted is listed in Deleted.
+type DeleteResult struct {
+	Deleted []DeletedObject `xml:"Deleted"`
+	Errors  []DeleteError   `xml:"Error"`
+}
+
+// DeletedObject is an object removed by DelMulti. In a versioned bucket,
+// deleting a key without giving a version creates a delete marker, whose
+// version is DeleteMarkerVersionId; deleting a delete marker by its
+// version sets DeleteMarker too.
+type DeletedObject struct {
+	Key                   string
+	VersionId             string `xml:",omitempty"`
+	DeleteMarker          bool   `xml:",omitempty"`
+	DeleteMarkerVersionId string `xml:",omitempty"`
+}
+
+// DeleteError is an object DelMulti failed to remove.
+type DeleteError struct {
+	Key       string
+	VersionId string `xml:",omitempty"`
+	Code      string
+	Message   string
+}
+
 // DelMulti removes up to 1000 objects from the S3 bucket.
 //
 // See http://goo.gl/jx6cWK for details.
 func (b *Bucket) DelMulti(objects Delete) error {
+	return b.delMulti(objects, nil)
+}
+
+// DelMultiResult removes up to 1000 objects from the S3 bucket, as
+// DelMulti does, and reports what happened to each of them. The objects
+// that could not be removed are listed in the result but are not
+// reported as an error.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/multiobjectdeleteapi.html
+// for details.
+func (b *Bucket) DelMultiResult(objects Delete) (*DeleteResult, error) {
+	result := &DeleteResult{}
+	if err := b.delMulti(objects, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (b *Bucket) delMulti(objects Delete, resp interface{}) error {
 	doc, err := xml.Marshal(objects)
 	if err != nil {
 		return err
@@ -541,7 +808,7 @@ func (b *Bucket) DelMulti(objects Delete) error {
 		payload: buf,
 	}
 
-	return b.S3.query(req, nil)
+	return b.S3.query(req, resp)
 }
 
 // The ListResp type holds the results of a List bucket operation.
@@ -644,7 +911,7 @@ func (b *Bucket) List(prefix, delim, marker string, max int) (result *ListResp,
 		params: params,
 	}
 	result = &ListResp{}
-	for attempt := b.S3.AttemptStrategy.Start(); attempt.Next(); {
+	for attempt := b.S3.attempts(); attempt.Next(); {
 		err = b.S3.query(req, result)
 		if !shouldRetry(err) {
 			break
@@ -658,15 +925,21 @@ func (b *Bucket) List(prefix, delim, marker string, max int) (result *ListResp,
 
 // The VersionsResp type holds the results of a list bucket Versions operation.
 type VersionsResp struct {
-	Name            string
-	Prefix          string
-	KeyMarker       string
-	VersionIdMarker string
-	MaxKeys         int
-	Delimiter       string
-	IsTruncated     bool
-	Versions        []Version
-	CommonPrefixes  []string `xml:">Prefix"`
+	Name                string
+	Prefix              string
+	KeyMarker           string
+	VersionIdMarker     string
+	NextKeyMarker       string
+	NextVersionIdMarker string
+	MaxKeys             int
+	Delimiter           string
+	IsTruncated         bool
+	Versions            []Version `xml:"Version"`
+	CommonPrefixes      []string  `xml:">Prefix"`
+
+	// DeleteMarkers holds the delete markers listed, which have no
+	// ETag, Size or StorageClass.
+	DeleteMarkers []Version `xml:"DeleteMarker"`
 }
 
 // The Version type represents an object version stored in an S3 bucket.
@@ -681,6 +954,10 @@ type Version struct {
 	Size         int64
 	Owner        Owner
 	StorageClass string
+
+	// IsDeleteMarker is set for the delete markers VersionsIter
+	// returns along with the versions.
+	IsDeleteMarker bool `xml:"-"`
 }
 
 func (b *Bucket) Versions(prefix, delim, keyMarker string, versionIdMarker string, max int) (result *VersionsResp, err error) {
@@ -705,7 +982,7 @@ func (b *Bucket) Versions(prefix, delim, keyMarker string, versionIdMarker strin
 		params: params,
 	}
 	result = &VersionsResp{}
-	for attempt := b.S3.AttemptStrategy.Start(); attempt.Next(); {
+	for attempt := b.S3.attempts(); attempt.Next(); {
 		err = b.S3.query(req, result)
 		if !shouldRetry(err) {
 			break
@@ -777,8 +1054,8 @@ func (b *Bucket) SignedURL(path string, expires time.Time) string {
 	if err != nil {
 		panic(err)
 	}
-	if b.S3.Auth.Token() != "" {
-		return u.String() + "&x-amz-security-token=" + url.QueryEscape(req.headers["X-Amz-Security-Token"][0])
+	if token, ok := req.headers["X-Amz-Security-Token"]; ok {
+		return u.String() + "&x-amz-security-token=" + url.QueryEscape(token[0])
 	} else {
 		return u.String()
 	}
@@ -793,9 +1070,16 @@ func (b *Bucket) UploadSignedURL(path, method, content_type string, expires time
 	if method != "POST" {
 		method = "PUT"
 	}
+	if b.S3.Region.S3Signer == aws.V4Signature {
+		return b.uploadSignedURLV4(path, method, content_type, expires)
+	}
 	stringToSign := method + "\n\n" + content_type + "\n" + strconv.FormatInt(expire_date, 10) + "\n/" + b.Name + "/" + path
 	fmt.Println("String to sign:\n", stringToSign)
-	a := b.S3.Auth
+	a, err := b.S3.credentials()
+	if err != nil {
+		log.Println("ERROR signing url for S3 upload", err)
+		return ""
+	}
 	secretKey := a.SecretKey
 	accessId := a.AccessKey
 	mac := hmac.New(sha1.New, []byte(secretKey))
@@ -804,9 +1088,14 @@ func (b *Bucket) UploadSignedURL(path, method, content_type string, expires time
 	signature := base64.StdEncoding.EncodeToString([]byte(macsum))
 	signature = strings.TrimSpace(signature)
 
-	signedurl, err := url.Parse("https://" + b.Name + ".s3.amazonaws.com/")
+	base, err := b.S3.bucketURL(b.Name)
+	if err != nil {
+		log.Println("ERROR signing url for S3 upload", err)
+		return ""
+	}
+	signedurl, err := url.Parse(base)
 	if err != nil {
-		log.Println("ERROR sining url for S3 upload", err)
+		log.Println("ERROR signing url for S3 upload", err)
 		return ""
 	}
 	signedurl.Path += path
@@ -822,12 +1111,42 @@ func (b *Bucket) UploadSignedURL(path, method, content_type string, expires time
 	return signedurl.String()
 }
 
+// uploadSignedURLV4 is UploadSignedURL for regions using AWS Signature
+// Version 4.
+func (b *Bucket) uploadSignedURLV4(path, method, contentType string, expires time.Time) string {
+	req := &request{
+		method:  method,
+		bucket:  b.Name,
+		path:    path,
+		headers: map[string][]string{},
+		params:  url.Values{"Expires": {strconv.FormatInt(expires.Unix(), 10)}},
+	}
+	if contentType != "" {
+		req.headers["Content-Type"] = []string{contentType}
+	}
+	err := b.S3.prepare(req)
+	if err != nil {
+		log.Println("ERROR signing url for S3 upload", err)
+		return ""
+	}
+	u, err := req.url()
+	if err != nil {
+		log.Println("ERROR signing url for S3 upload", err)
+		return ""
+	}
+	return u.String()
+}
+
 // PostFormArgs returns the action and input fields needed to allow anonymous
 // uploads to a bucket within the expiration limit
 func (b *Bucket) PostFormArgs(path string, expires time.Time, redirect string) (action string, fields map[string]string) {
+	auth, err := b.S3.credentials()
+	if err != nil {
+		panic(err)
+	}
 	conditions := make([]string, 0)
 	fields = map[string]string{
-		"AWSAccessKeyId": b.Auth.AccessKey,
+		"AWSAccessKeyId": auth.AccessKey,
 		"key":            path,
 	}
 
@@ -844,11 +1163,14 @@ func (b *Bucket) PostFormArgs(path string, expires time.Time, redirect string) (
 	policy64 := base64.StdEncoding.EncodeToString([]byte(policy))
 	fields["policy"] = policy64
 
-	signer := hmac.New(sha1.New, []byte(b.Auth.SecretKey))
+	signer := hmac.New(sha1.New, []byte(auth.SecretKey))
 	signer.Write([]byte(policy64))
 	fields["signature"] = base64.StdEncoding.EncodeToString(signer.Sum(nil))
 
-	action = fmt.Sprintf("%s/%s/", b.S3.Region.S3Endpoint, b.Name)
+	action, err = b.S3.bucketURL(b.Name)
+	if err != nil {
+		panic(err)
+	}
 	return
 }
 
@@ -862,6 +1184,9 @@ type request struct {
 	baseurl  string
 	payload  io.Reader
 	prepared bool
+
+	// encode, if set, turns the payload into the body to send.
+	encode func(io.Reader) io.Reader
 }
 
 func (req *request) url() (*url.URL, error) {
@@ -913,18 +1238,16 @@ func (s3 *S3) prepare(req *request) error {
 			req.path = "/" + req.path
 		}
 		signpath = req.path
-		if req.bucket != "" {
-			req.baseurl = s3.Region.S3BucketEndpoint
-			if req.baseurl == "" {
-				// Use the path method to address the bucket.
-				req.baseurl = s3.Region.S3Endpoint
+		if req.bucket == "" {
+			req.baseurl = s3.endpoint()
+		} else {
+			baseurl, pathStyle, err := s3.bucketEndpoint(req.bucket)
+			if err != nil {
+				return err
+			}
+			req.baseurl = baseurl
+			if pathStyle {
 				req.path = "/" + req.bucket + req.path
-			} else {
-				// Just in case, prevent injection.
-				if strings.IndexAny(req.bucket, "/:@") >= 0 {
-					return fmt.Errorf("bad S3 bucket: %q", req.bucket)
-				}
-				req.baseurl = strings.Replace(req.baseurl, "${bucket}", req.bucket, -1)
 			}
 			signpath = "/" + req.bucket + signpath
 		}
@@ -938,18 +1261,54 @@ func (s3 *S3) prepare(req *request) error {
 	}
 	reqSignpathSpaceFix := (&url.URL{Path: signpath}).String()
 	req.headers["Host"] = []string{u.Host}
+	auth, err := s3.credentials()
+	if err != nil {
+		return err
+	}
+	if s3.Region.S3Signer == aws.V4Signature {
+		u, err := req.url()
+		if err != nil {
+			return err
+		}
+		return signV4(auth, s3.signingRegion(), req, u)
+	}
 	req.headers["Date"] = []string{time.Now().In(time.UTC).Format(time.RFC1123)}
-	if s3.Auth.Token() != "" {
-		req.headers["X-Amz-Security-Token"] = []string{s3.Auth.Token()}
+	if auth.Token() != "" {
+		req.headers["X-Amz-Security-Token"] = []string{auth.Token()}
 	}
-	sign(s3.Auth, req.method, reqSignpathSpaceFix, req.params, req.headers)
+	sign(auth, req.method, reqSignpathSpaceFix, req.params, req.headers)
 	return nil
 }
 
 // run sends req and returns the http response from the server.
 // If resp is not nil, the XML data contained in the response
-// body will be unmarshalled on it.
+// body will be unmarshalled on it. If req fails because the credentials
+// it was signed with had expired, it is signed again and retried once
+// with fresh credentials, provided its payload can be sent again.
 func (s3 *S3) run(req *request, resp interface{}) (*http.Response, error) {
+	var offset int64 = -1
+	if seeker, ok := req.payload.(io.Seeker); ok {
+		if pos, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			offset = pos
+		}
+	}
+	hresp, err := s3.runOnce(req, resp)
+	e, ok := err.(*Error)
+	if !ok || req.payload != nil && offset < 0 || !aws.ExpireCredentials(e.Code, s3.provider, s3.Auth) {
+		return hresp, err
+	}
+	if offset >= 0 {
+		if _, err := req.payload.(io.Seeker).Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	if err := s3.prepare(req); err != nil {
+		return nil, err
+	}
+	return s3.runOnce(req, resp)
+}
+
+func (s3 *S3) runOnce(req *request, resp interface{}) (*http.Response, error) {
 	if debug {
 		log.Printf("Running S3 request: %#v", req)
 	}
@@ -965,22 +1324,33 @@ func (s3 *S3) run(req *request, resp interface{}) (*http.Response, error) {
 		ProtoMajor: 1,
 		ProtoMinor: 1,
 		Close:      true,
-		Header:     req.headers,
+		Header:     make(http.Header, len(req.headers)),
 	}
 
-	if v, ok := req.headers["Content-Length"]; ok {
-		hreq.ContentLength, _ = strconv.ParseInt(v[0], 10, 64)
-		delete(req.headers, "Content-Length")
+	// The length is sent from hreq.ContentLength. Leave req.headers
+	// alone so req can be sent again.
+	for k, v := range req.headers {
+		if k == "Content-Length" {
+			hreq.ContentLength, _ = strconv.ParseInt(v[0], 10, 64)
+			continue
+		}
+		hreq.Header[k] = v
 	}
 	if req.payload != nil {
-		hreq.Body = ioutil.NopCloser(req.payload)
+		body := req.payload
+		if req.encode != nil {
+			body = req.encode(body)
+		}
+		hreq.Body = ioutil.NopCloser(body)
 	}
 
-	if s3.client == nil {
+	client := s3.HTTPClient
+	if client == nil && s3.client == nil {
 		s3.client = &http.Client{
 			Transport: &http.Transport{
-				Dial: func(netw, addr string) (c net.Conn, err error) {
-					c, err = net.DialTimeout(netw, addr, s3.ConnectTimeout)
+				DialContext: func(ctx context.Context, netw, addr string) (c net.Conn, err error) {
+					d := net.Dialer{Timeout: s3.ConnectTimeout}
+					c, err = d.DialContext(ctx, netw, addr)
 					if err != nil {
 						return
 					}
@@ -1005,7 +1375,14 @@ func (s3 *S3) run(req *request, resp interface{}) (*http.Response, error) {
 		}
 	}
 
-	hresp, err := s3.client.Do(&hreq)
+	httpReq := &hreq
+	if s3.ctx != nil {
+		httpReq = httpReq.WithContext(s3.ctx)
+	}
+	if client == nil {
+		client = s3.client
+	}
+	hresp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
@@ -1013,7 +1390,7 @@ func (s3 *S3) run(req *request, resp interface{}) (*http.Response, error) {
 		dump, _ := httputil.DumpResponse(hresp, true)
 		log.Printf("} -> %s\n", dump)
 	}
-	if hresp.StatusCode != 200 && hresp.StatusCode != 204 && hresp.StatusCode != 206 {
+	if hresp.StatusCode != 200 && hresp.StatusCode != 202 && hresp.StatusCode != 204 && hresp.StatusCode != 206 {
 		defer hresp.Body.Close()
 		return nil, buildError(hresp)
 	}
@@ -2,8 +2,11 @@ package s3_test
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -149,6 +152,31 @@ func (s *S) TestGetReader(c *C) {
 	c.Assert(req.Header["Date"], Not(Equals), "")
 }
 
+func (s *S) TestGetWithContext(c *C) {
+	for i := 0; i < 10; i++ {
+		testServer.Response(500, nil, InternalErrorDump)
+	}
+	s.s3.AttemptStrategy = aws.AttemptStrategy{
+		Total: 5 * time.Second,
+		Delay: time.Second,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	b := s.s3.Bucket("bucket").WithContext(ctx)
+	t0 := time.Now()
+	data, err := b.Get("name")
+	c.Assert(err, ErrorMatches, ".*context deadline exceeded")
+	c.Assert(data, IsNil)
+	if d := time.Since(t0); d > time.Second {
+		c.Errorf("request retried for %v after the context expired", d)
+	}
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.URL.Path, Equals, "/bucket/name")
+}
+
 func (s *S) TestGetNotFound(c *C) {
 	for i := 0; i < 10; i++ {
 		testServer.Response(404, nil, GetObjectErrorDump)
@@ -193,6 +221,47 @@ func (s *S) TestPutObject(c *C) {
 	c.Assert(req.Header["X-Amz-Acl"], DeepEquals, []string{"private"})
 }
 
+// tokenProvider supplies temporary credentials with access keys "key1",
+// "key2", ... and matching session tokens.
+type tokenProvider struct {
+	n int
+}
+
+func (p *tokenProvider) Credentials() (aws.Auth, error) {
+	p.n++
+	key, token := fmt.Sprintf("key%d", p.n), fmt.Sprintf("token%d", p.n)
+	return *aws.NewAuth(key, "secret", token, time.Now().Add(time.Hour)), nil
+}
+
+func (s *S) TestPutObjectExpiredToken(c *C) {
+	testServer.Response(400, nil, ExpiredTokenErrorDump)
+	testServer.Response(200, nil, "")
+
+	p := aws.NewRefreshingProvider(&tokenProvider{})
+	b := s3.NewWithProvider(p, s.s3.Region).Bucket("bucket")
+	err := b.PutReader("name", strings.NewReader("content"), 7, "content-type", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	reqs := testServer.WaitRequests(2)
+	for i, req := range reqs {
+		key, token := fmt.Sprintf("key%d", i+1), fmt.Sprintf("token%d", i+1)
+		c.Assert(req.Header.Get("Authorization"), Matches, "AWS "+key+":.*")
+		c.Assert(req.Header["X-Amz-Security-Token"], DeepEquals, []string{token})
+		c.Assert(req.Header["Content-Length"], DeepEquals, []string{"7"})
+		body, err := ioutil.ReadAll(req.Body)
+		c.Assert(err, IsNil)
+		c.Assert(string(body), Equals, "content")
+	}
+}
+
+func (s *S) TestPutObjectExpiredTokenStatic(c *C) {
+	testServer.Response(400, nil, ExpiredTokenErrorDump)
+
+	b := s.s3.Bucket("bucket")
+	err := b.Put("name", []byte("content"), "content-type", s3.Private, s3.Options{})
+	c.Assert(err, ErrorMatches, "The provided token has expired.")
+}
+
 func (s *S) TestPutObjectReadTimeout(c *C) {
 	s.s3.ReadTimeout = 50 * time.Millisecond
 	defer func() {
@@ -425,3 +494,179 @@ func (s *S) TestExistsNotFound403(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(result, Equals, false)
 }
+
+func (s *S) TestPutLifecycleConfiguration(c *C) {
+	testServer.Response(200, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.PutLifecycleConfiguration(&s3.LifecycleConfiguration{
+		Rules: []s3.LifecycleRule{{
+			ID:         "tmp",
+			Prefix:     "tmp/",
+			Status:     s3.LifecycleRuleEnabled,
+			Expiration: &s3.LifecycleExpiration{Date: "2016-01-01T00:00:00.000Z"},
+		}},
+	})
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "PUT")
+	c.Assert(req.URL.Path, Equals, "/bucket/")
+	c.Assert(req.Form["lifecycle"], DeepEquals, []string{""})
+	c.Assert(req.Header.Get("Content-MD5"), Not(Equals), "")
+	body, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		"<LifecycleConfiguration><Rule><ID>tmp</ID><Prefix>tmp/</Prefix><Status>Enabled</Status>"+
+		"<Expiration><Date>2016-01-01T00:00:00.000Z</Date></Expiration></Rule></LifecycleConfiguration>")
+}
+
+func (s *S) TestGetLifecycleConfiguration(c *C) {
+	testServer.Response(200, nil, GetLifecycleConfigurationDump)
+
+	b := s.s3.Bucket("bucket")
+	config, err := b.GetLifecycleConfiguration()
+	c.Assert(err, IsNil)
+	c.Assert(config.Rules, DeepEquals, []s3.LifecycleRule{{
+		ID:     "archive",
+		Prefix: "",
+		Status: "Enabled",
+		Transitions: []s3.LifecycleTransition{
+			{Days: 0, Date: "2016-01-01T00:00:00.000Z", StorageClass: "GLACIER"},
+		},
+		NoncurrentVersionExpiration:    &s3.NoncurrentVersionExpiration{NoncurrentDays: 30},
+		AbortIncompleteMultipartUpload: &s3.AbortIncompleteMultipartUpload{DaysAfterInitiation: 7},
+	}})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.URL.Path, Equals, "/bucket/")
+	c.Assert(req.Form["lifecycle"], DeepEquals, []string{""})
+}
+
+func (s *S) TestDeleteLifecycleConfiguration(c *C) {
+	testServer.Response(204, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.DeleteLifecycleConfiguration()
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "DELETE")
+	c.Assert(req.URL.Path, Equals, "/bucket/")
+	c.Assert(req.Form["lifecycle"], DeepEquals, []string{""})
+}
+
+func (s *S) TestPutBucketVersioning(c *C) {
+	testServer.Response(200, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.PutBucketVersioning(&s3.VersioningConfiguration{Status: s3.VersioningEnabled})
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "PUT")
+	c.Assert(req.URL.Path, Equals, "/bucket/")
+	c.Assert(req.Form["versioning"], DeepEquals, []string{""})
+	body, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		"<VersioningConfiguration><Status>Enabled</Status></VersioningConfiguration>")
+}
+
+func (s *S) TestGetBucketVersioning(c *C) {
+	testServer.Response(200, nil, `<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Status>Suspended</Status></VersioningConfiguration>`)
+
+	b := s.s3.Bucket("bucket")
+	config, err := b.GetBucketVersioning()
+	c.Assert(err, IsNil)
+	c.Assert(config.Status, Equals, s3.VersioningSuspended)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.Form["versioning"], DeepEquals, []string{""})
+}
+
+func (s *S) TestGetVersion(c *C) {
+	testServer.Response(200, nil, "content")
+
+	b := s.s3.Bucket("bucket")
+	data, err := b.GetVersion("name", "3/L4kqtJl40Nr8X8gdRQBpUMLUo")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "content")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.URL.Path, Equals, "/bucket/name")
+	c.Assert(req.Form["versionId"], DeepEquals, []string{"3/L4kqtJl40Nr8X8gdRQBpUMLUo"})
+}
+
+func (s *S) TestHeadVersion(c *C) {
+	testServer.Response(200, map[string]string{"x-amz-version-id": "v1"}, "")
+
+	b := s.s3.Bucket("bucket")
+	resp, err := b.HeadVersion("name", "v1", nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Header.Get("x-amz-version-id"), Equals, "v1")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "HEAD")
+	c.Assert(req.Form["versionId"], DeepEquals, []string{"v1"})
+}
+
+func (s *S) TestDelVersion(c *C) {
+	testServer.Response(204, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.DelVersion("name", "v1")
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "DELETE")
+	c.Assert(req.URL.Path, Equals, "/bucket/name")
+	c.Assert(req.Form["versionId"], DeepEquals, []string{"v1"})
+}
+
+func (s *S) TestPutCopyVersion(c *C) {
+	headers := map[string]string{
+		"x-amz-version-id":             "v2",
+		"x-amz-copy-source-version-id": "v1",
+	}
+	testServer.Response(200, headers, `<CopyObjectResult><ETag>"9b2cf535f27731c974343645a3985328"</ETag><LastModified>2009-10-28T22:32:00.000Z</LastModified></CopyObjectResult>`)
+
+	b := s.s3.Bucket("bucket")
+	result, err := b.PutCopyVersion("copy", s3.Private, s3.CopyOptions{}, "source/name", "v1")
+	c.Assert(err, IsNil)
+	c.Assert(result, DeepEquals, &s3.CopyObjectResult{
+		ETag:            `"9b2cf535f27731c974343645a3985328"`,
+		LastModified:    "2009-10-28T22:32:00.000Z",
+		VersionId:       "v2",
+		SourceVersionId: "v1",
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "PUT")
+	c.Assert(req.URL.Path, Equals, "/bucket/copy")
+	c.Assert(req.Header.Get("x-amz-copy-source"), Equals, "source/name?versionId=v1")
+}
+
+func (s *S) TestDelMultiResult(c *C) {
+	testServer.Response(200, nil, DelMultiResultDump)
+
+	b := s.s3.Bucket("bucket")
+	result, err := b.DelMultiResult(s3.Delete{Objects: []s3.Object{
+		{Key: "sample1.txt"}, {Key: "sample2.txt"}, {Key: "sample3.txt"},
+	}})
+	c.Assert(err, IsNil)
+	c.Assert(result.Deleted, DeepEquals, []s3.DeletedObject{
+		{Key: "sample1.txt"},
+		{Key: "sample2.txt", DeleteMarker: true, DeleteMarkerVersionId: "NeQt5xeFTfgPJD8B4CGWnkSLtluMr11s"},
+	})
+	c.Assert(result.Errors, DeepEquals, []s3.DeleteError{
+		{Key: "sample3.txt", Code: "AccessDenied", Message: "Access Denied"},
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "POST")
+	c.Assert(req.URL.RawQuery, Equals, "delete=")
+}
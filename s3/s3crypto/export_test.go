@@ -0,0 +1,9 @@
+package s3crypto
+
+func Encrypt(data, key, nonce []byte) ([]byte, error) {
+	return encrypt(data, key, nonce)
+}
+
+func Decrypt(data, key, nonce []byte) ([]byte, error) {
+	return decrypt(data, key, nonce)
+}
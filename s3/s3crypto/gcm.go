@@ -0,0 +1,75 @@
+package s3crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// Content is encrypted with the AES-GCM of crypto/cipher, using a 12-byte
+// nonce and a 16-byte tag, and no additional data. As cipher.AEAD only
+// encrypts and decrypts data held in memory, objects are read whole
+// before being encrypted or decrypted, which MaxSize bounds.
+
+const (
+	gcmTagSize   = 16
+	gcmNonceSize = 12
+)
+
+// ErrAuthentication is returned when decrypting content that was not
+// encrypted with the key and nonce it is decrypted with, or that was
+// altered.
+var ErrAuthentication = errors.New("s3crypto: message authentication failed")
+
+func newGCM(key, nonce []byte) (cipher.AEAD, error) {
+	if len(nonce) != gcmNonceSize {
+		return nil, errors.New("s3crypto: bad nonce size")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt returns the encryption of data followed by its authentication
+// tag.
+func encrypt(data, key, nonce []byte) ([]byte, error) {
+	if len(data) > MaxSize {
+		return nil, ErrTooLarge
+	}
+	aead, err := newGCM(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, nonce, data, nil), nil
+}
+
+// decrypt returns the decryption of data, the encryption of some content
+// followed by its authentication tag.
+func decrypt(data, key, nonce []byte) ([]byte, error) {
+	aead, err := newGCM(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, ErrAuthentication
+	}
+	return plaintext, nil
+}
+
+// readContent reads all of r, returning ErrTooLarge if it holds more
+// than max bytes.
+func readContent(r io.Reader, max int64) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > max {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
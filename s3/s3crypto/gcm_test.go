@@ -0,0 +1,71 @@
+package s3crypto_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+
+	"github.com/goamz/goamz/s3/s3crypto"
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&GCMSuite{})
+
+type GCMSuite struct{}
+
+var (
+	gcmKey   = bytes.Repeat([]byte("k"), 32)
+	gcmNonce = bytes.Repeat([]byte("n"), 12)
+)
+
+func seal(c *C, plaintext []byte) []byte {
+	block, err := aes.NewCipher(gcmKey)
+	c.Assert(err, IsNil)
+	aead, err := cipher.NewGCM(block)
+	c.Assert(err, IsNil)
+	return aead.Seal(nil, gcmNonce, plaintext, nil)
+}
+
+func content(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+var gcmSizes = []int{0, 1, 15, 16, 17, 100, 4096, 100000}
+
+func (s *GCMSuite) TestEncrypt(c *C) {
+	for _, n := range gcmSizes {
+		data := content(n)
+		got, err := s3crypto.Encrypt(data, gcmKey, gcmNonce)
+		c.Assert(err, IsNil)
+		c.Assert(got, DeepEquals, seal(c, data), Commentf("size %d", n))
+	}
+}
+
+func (s *GCMSuite) TestDecrypt(c *C) {
+	for _, n := range gcmSizes {
+		data := content(n)
+		got, err := s3crypto.Decrypt(seal(c, data), gcmKey, gcmNonce)
+		c.Assert(err, IsNil)
+		c.Assert(bytes.Equal(got, data), Equals, true, Commentf("size %d", n))
+	}
+}
+
+func (s *GCMSuite) TestDecryptAltered(c *C) {
+	sealed := seal(c, content(1000))
+	for _, i := range []int{0, 500, len(sealed) - 1} {
+		altered := append([]byte(nil), sealed...)
+		altered[i] ^= 1
+		_, err := s3crypto.Decrypt(altered, gcmKey, gcmNonce)
+		c.Assert(err, Equals, s3crypto.ErrAuthentication)
+	}
+
+	_, err := s3crypto.Decrypt(sealed[:10], gcmKey, gcmNonce)
+	c.Assert(err, Equals, s3crypto.ErrAuthentication)
+
+	_, err = s3crypto.Decrypt(sealed, bytes.Repeat([]byte("o"), 32), gcmNonce)
+	c.Assert(err, Equals, s3crypto.ErrAuthentication)
+}
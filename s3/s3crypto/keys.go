@@ -0,0 +1,138 @@
+package s3crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/goamz/goamz/kms"
+)
+
+// The algorithms master keys wrap data keys with.
+const (
+	WrapAlgorithmKMS    = "kms"
+	WrapAlgorithmAESGCM = "AES/GCM"
+)
+
+// contentAlgorithm is the algorithm the content of objects is encrypted
+// with.
+const contentAlgorithm = "AES/GCM/NoPadding"
+
+// A MasterKey protects the data keys objects are encrypted with. Each
+// object has its own data key, which is stored along with the object
+// wrapped by the master key.
+type MasterKey interface {
+	// WrapAlgorithm returns the name of the algorithm the key wraps
+	// data keys with, such as WrapAlgorithmKMS.
+	WrapAlgorithm() string
+
+	// GenerateDataKey returns a new 256-bit data key, the key wrapped,
+	// and the material description to store with it.
+	GenerateDataKey() (key, wrapped []byte, matdesc map[string]string, err error)
+
+	// DecryptDataKey unwraps a data key wrapped by GenerateDataKey and
+	// stored with the material description matdesc.
+	DecryptDataKey(wrapped []byte, matdesc map[string]string) ([]byte, error)
+}
+
+// newDataKey returns a new random 256-bit key.
+func newDataKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// localMasterKey is a master key held by the client.
+type localMasterKey struct {
+	aead    cipher.AEAD
+	matdesc map[string]string
+}
+
+// NewLocalMasterKey returns a master key wrapping data keys with key, an
+// AES key of 16, 24 or 32 bytes, using AES-GCM. The material description
+// matdesc, which may be nil, is stored with every object to tell which
+// key it was encrypted with.
+func NewLocalMasterKey(key []byte, matdesc map[string]string) (MasterKey, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if matdesc == nil {
+		matdesc = make(map[string]string)
+	}
+	return &localMasterKey{aead, matdesc}, nil
+}
+
+func (k *localMasterKey) WrapAlgorithm() string {
+	return WrapAlgorithmAESGCM
+}
+
+// GenerateDataKey wraps the data key as a random nonce followed by the
+// key encrypted with it, authenticating the content algorithm.
+func (k *localMasterKey) GenerateDataKey() (key, wrapped []byte, matdesc map[string]string, err error) {
+	key, err = newDataKey()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, nil, err
+	}
+	wrapped = k.aead.Seal(nonce, nonce, key, []byte(contentAlgorithm))
+	return key, wrapped, k.matdesc, nil
+}
+
+func (k *localMasterKey) DecryptDataKey(wrapped []byte, matdesc map[string]string) ([]byte, error) {
+	n := k.aead.NonceSize()
+	if len(wrapped) < n {
+		return nil, errors.New("s3crypto: wrapped key too short")
+	}
+	key, err := k.aead.Open(nil, wrapped[:n], wrapped[n:], []byte(contentAlgorithm))
+	if err != nil {
+		return nil, fmt.Errorf("s3crypto: cannot unwrap data key: %v", err)
+	}
+	return key, nil
+}
+
+// kmsCMKId is the material description entry holding the KMS key.
+const kmsCMKId = "kms_cmk_id"
+
+// kmsMasterKey is a master key held by KMS.
+type kmsMasterKey struct {
+	kms   *kms.KMS
+	keyId string
+}
+
+// NewKMSMasterKey returns a master key having KMS generate data keys
+// encrypted under the KMS key keyId, which may be a key id, ARN or alias.
+// The material description, which holds keyId, is used as the encryption
+// context.
+func NewKMSMasterKey(k *kms.KMS, keyId string) MasterKey {
+	return &kmsMasterKey{k, keyId}
+}
+
+func (k *kmsMasterKey) WrapAlgorithm() string {
+	return WrapAlgorithmKMS
+}
+
+func (k *kmsMasterKey) GenerateDataKey() (key, wrapped []byte, matdesc map[string]string, err error) {
+	matdesc = map[string]string{kmsCMKId: k.keyId}
+	dk, err := k.kms.GenerateDataKey(k.keyId, kms.AES256, matdesc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return dk.Plaintext, dk.CiphertextBlob, matdesc, nil
+}
+
+func (k *kmsMasterKey) DecryptDataKey(wrapped []byte, matdesc map[string]string) ([]byte, error) {
+	return k.kms.Decrypt(wrapped, matdesc)
+}
@@ -0,0 +1,250 @@
+// Package s3crypto stores objects in S3 encrypted on the client, so that
+// their content never leaves it in the clear.
+//
+// Objects are encrypted with AES-GCM using a data key of their own, which
+// a MasterKey wraps. The wrapped key and the other parameters needed to
+// decrypt the object are stored in its metadata, in the format of the
+// version 2 envelopes of the AWS SDK encryption clients, so objects stored
+// by either can be read by the other as long as they use the same master
+// key. Instruction files are not supported.
+//
+// The content of an object is encrypted and decrypted as a whole, in
+// memory, so objects larger than MaxSize are neither stored nor
+// retrieved.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/dev/UsingClientSideEncryption.html
+// for an overview.
+package s3crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/goamz/goamz/s3"
+)
+
+// The metadata holding the envelope of an object, without the
+// "x-amz-meta-" prefix S3 adds.
+const (
+	metaKey               = "x-amz-key-v2"
+	metaIV                = "x-amz-iv"
+	metaMatDesc           = "x-amz-matdesc"
+	metaWrapAlg           = "x-amz-wrap-alg"
+	metaCEKAlg            = "x-amz-cek-alg"
+	metaTagLen            = "x-amz-tag-len"
+	metaUnencryptedLength = "x-amz-unencrypted-content-length"
+)
+
+// MaxSize is the size of the largest content a Client stores or
+// retrieves, which it holds in memory whole.
+const MaxSize = 64 << 20
+
+// ErrTooLarge is returned when storing or retrieving content larger than
+// MaxSize.
+var ErrTooLarge = errors.New("s3crypto: content larger than MaxSize")
+
+// A Client stores and retrieves objects of Bucket encrypted with data
+// keys protected by MasterKey.
+type Client struct {
+	Bucket    *s3.Bucket
+	MasterKey MasterKey
+
+	// Uploader sends the objects stored with Upload.
+	Uploader *s3.Uploader
+}
+
+// New returns a client encrypting the objects of b with data keys wrapped
+// by key.
+func New(b *s3.Bucket, key MasterKey) *Client {
+	return &Client{
+		Bucket:    b,
+		MasterKey: key,
+		Uploader:  s3.NewUploader(b),
+	}
+}
+
+// seal returns a new data key and nonce to encrypt an object with, and
+// options holding its envelope in their metadata, which includes the
+// length of its content.
+func (c *Client) seal(options s3.Options, length int64) (key, nonce []byte, _ s3.Options, err error) {
+	key, wrapped, matdesc, err := c.MasterKey.GenerateDataKey()
+	if err != nil {
+		return nil, nil, options, err
+	}
+	nonce = make([]byte, gcmNonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, options, err
+	}
+	desc, err := json.Marshal(matdesc)
+	if err != nil {
+		return nil, nil, options, err
+	}
+	meta := make(map[string][]string, len(options.Meta)+7)
+	for k, v := range options.Meta {
+		meta[k] = v
+	}
+	meta[metaKey] = []string{base64.StdEncoding.EncodeToString(wrapped)}
+	meta[metaIV] = []string{base64.StdEncoding.EncodeToString(nonce)}
+	meta[metaMatDesc] = []string{string(desc)}
+	meta[metaWrapAlg] = []string{c.MasterKey.WrapAlgorithm()}
+	meta[metaCEKAlg] = []string{contentAlgorithm}
+	meta[metaTagLen] = []string{strconv.Itoa(gcmTagSize * 8)}
+	meta[metaUnencryptedLength] = []string{strconv.FormatInt(length, 10)}
+	options.Meta = meta
+	// The digest S3 checks is that of the encrypted content.
+	options.ContentMD5 = ""
+	return key, nonce, options, nil
+}
+
+// Put encrypts data, of at most MaxSize bytes, and stores it at path.
+// options.ContentMD5 is ignored.
+func (c *Client) Put(path string, data []byte, contType string, perm s3.ACL, options s3.Options) error {
+	if len(data) > MaxSize {
+		return ErrTooLarge
+	}
+	key, nonce, options, err := c.seal(options, int64(len(data)))
+	if err != nil {
+		return err
+	}
+	sealed, err := encrypt(data, key, nonce)
+	if err != nil {
+		return err
+	}
+	return c.Bucket.Put(path, sealed, contType, perm, options)
+}
+
+// PutReader encrypts the length bytes read from r and stores them at path.
+// The content is read whole before being encrypted, and so held in memory,
+// which length must not make larger than MaxSize.
+// options.ContentMD5 is ignored.
+func (c *Client) PutReader(path string, r io.Reader, length int64, contType string, perm s3.ACL, options s3.Options) error {
+	if length > MaxSize {
+		return ErrTooLarge
+	}
+	data, err := readContent(io.LimitReader(r, length), MaxSize)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) < length {
+		return io.ErrUnexpectedEOF
+	}
+	return c.Put(path, data, contType, perm, options)
+}
+
+// Upload encrypts all of r and stores it at path using a multipart upload
+// sent by c.Uploader, as Uploader.Upload does. The content is read whole
+// before being encrypted, and so held in memory, but its encryption is
+// sent in parts, several at once. ErrTooLarge is returned if r holds more
+// than MaxSize bytes. options.ContentMD5 is ignored.
+func (c *Client) Upload(path string, r io.Reader, contType string, perm s3.ACL, options s3.Options) error {
+	data, err := readContent(r, MaxSize)
+	if err != nil {
+		return err
+	}
+	key, nonce, options, err := c.seal(options, int64(len(data)))
+	if err != nil {
+		return err
+	}
+	sealed, err := encrypt(data, key, nonce)
+	if err != nil {
+		return err
+	}
+	m, err := c.Uploader.Bucket.InitMultiOptions(path, contType, perm, options)
+	if err != nil {
+		return err
+	}
+	parts, err := c.Uploader.PutAll(m, bytes.NewReader(sealed))
+	if err == nil {
+		err = m.Complete(parts)
+	}
+	if err != nil {
+		m.Abort()
+		return err
+	}
+	return nil
+}
+
+// Get retrieves the object at path and returns its decrypted content.
+// ErrAuthentication is returned if the object was altered, and
+// ErrTooLarge if its content is larger than MaxSize.
+func (c *Client) Get(path string) ([]byte, error) {
+	resp, err := c.Bucket.GetResponse(path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.ContentLength > MaxSize+gcmTagSize {
+		return nil, ErrTooLarge
+	}
+	return c.open(resp.Header, resp.Body)
+}
+
+// GetReader retrieves the object at path, returning a reader of its
+// decrypted content. The content is read whole and authenticated before
+// GetReader returns, as it is by Get, so none of it is read unless it can
+// be trusted.
+//
+// It is the caller's responsibility to call Close on rc when finished
+// reading.
+func (c *Client) GetReader(path string) (rc io.ReadCloser, err error) {
+	data, err := c.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// open returns the decryption of body, the content of an object whose
+// envelope is held by the headers h.
+func (c *Client) open(h http.Header, body io.Reader) ([]byte, error) {
+	get := func(name string) string {
+		return h.Get("x-amz-meta-" + name)
+	}
+	if get(metaKey) == "" {
+		if get("x-amz-key") != "" {
+			return nil, fmt.Errorf("s3crypto: version 1 envelopes not supported")
+		}
+		return nil, fmt.Errorf("s3crypto: object has no envelope")
+	}
+	if alg := get(metaWrapAlg); alg != c.MasterKey.WrapAlgorithm() {
+		return nil, fmt.Errorf("s3crypto: object key wrapped with %q, not %q", alg, c.MasterKey.WrapAlgorithm())
+	}
+	if alg := get(metaCEKAlg); alg != contentAlgorithm {
+		return nil, fmt.Errorf("s3crypto: unsupported content algorithm %q", alg)
+	}
+	if tagLen := get(metaTagLen); tagLen != strconv.Itoa(gcmTagSize*8) {
+		return nil, fmt.Errorf("s3crypto: unsupported tag length %q", tagLen)
+	}
+	if n, err := strconv.ParseInt(get(metaUnencryptedLength), 10, 64); err == nil && n > MaxSize {
+		return nil, ErrTooLarge
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(get(metaKey))
+	if err != nil {
+		return nil, fmt.Errorf("s3crypto: bad wrapped key: %v", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(get(metaIV))
+	if err != nil {
+		return nil, fmt.Errorf("s3crypto: bad iv: %v", err)
+	}
+	var matdesc map[string]string
+	if err := json.Unmarshal([]byte(get(metaMatDesc)), &matdesc); err != nil {
+		return nil, fmt.Errorf("s3crypto: bad material description: %v", err)
+	}
+	key, err := c.MasterKey.DecryptDataKey(wrapped, matdesc)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readContent(body, MaxSize+gcmTagSize)
+	if err != nil {
+		return nil, err
+	}
+	return decrypt(data, key, nonce)
+}
@@ -0,0 +1,259 @@
+package s3crypto_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goamz/goamz/aws"
+	"github.com/goamz/goamz/kms"
+	"github.com/goamz/goamz/s3"
+	"github.com/goamz/goamz/s3/s3crypto"
+	"github.com/goamz/goamz/s3/s3test"
+	"github.com/goamz/goamz/testutil"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&S{})
+
+type S struct {
+	srv    *s3test.Server
+	bucket *s3.Bucket
+	key    s3crypto.MasterKey
+	client *s3crypto.Client
+}
+
+// testServer stands for KMS, and for S3 in the tests of multipart uploads,
+// which s3test does not support. It does not use the default port, which
+// the tests of other packages run at the same time use.
+var testServer = &testutil.HTTPServer{URL: "http://localhost:4445", Timeout: 5 * time.Second}
+
+var auth = aws.Auth{AccessKey: "abc", SecretKey: "123"}
+
+func (s *S) SetUpSuite(c *C) {
+	testServer.Start()
+}
+
+func (s *S) SetUpTest(c *C) {
+	srv, err := s3test.NewServer(&s3test.Config{})
+	c.Assert(err, IsNil)
+	s.srv = srv
+	region := aws.Region{
+		Name:                 "faux-region-1",
+		S3Endpoint:           srv.URL(),
+		S3LocationConstraint: true,
+	}
+	s.bucket = s3.New(auth, region).Bucket("bucket")
+	err = s.bucket.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	s.key, err = s3crypto.NewLocalMasterKey(bytes.Repeat([]byte("m"), 32), map[string]string{"name": "test"})
+	c.Assert(err, IsNil)
+	s.client = s3crypto.New(s.bucket, s.key)
+}
+
+func (s *S) TearDownTest(c *C) {
+	s.srv.Quit()
+	testServer.Flush()
+}
+
+func (s *S) TestPutGet(c *C) {
+	err := s.client.Put("name", []byte("secret content"), "text/plain", s3.Private, s3.Options{
+		Meta:       map[string][]string{"user": {"value"}},
+		ContentMD5: "ignored",
+	})
+	c.Assert(err, IsNil)
+
+	data, err := s.client.Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "secret content")
+
+	resp, err := s.bucket.GetResponse("name")
+	c.Assert(err, IsNil)
+	stored, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(stored, HasLen, len("secret content")+16)
+	c.Assert(bytes.Contains(stored, []byte("secret")), Equals, false)
+
+	h := resp.Header
+	c.Assert(h.Get("x-amz-meta-user"), Equals, "value")
+	c.Assert(h.Get("x-amz-meta-x-amz-key-v2"), Not(Equals), "")
+	c.Assert(h.Get("x-amz-meta-x-amz-iv"), HasLen, 16)
+	c.Assert(h.Get("x-amz-meta-x-amz-matdesc"), Equals, `{"name":"test"}`)
+	c.Assert(h.Get("x-amz-meta-x-amz-wrap-alg"), Equals, "AES/GCM")
+	c.Assert(h.Get("x-amz-meta-x-amz-cek-alg"), Equals, "AES/GCM/NoPadding")
+	c.Assert(h.Get("x-amz-meta-x-amz-tag-len"), Equals, "128")
+	c.Assert(h.Get("x-amz-meta-x-amz-unencrypted-content-length"), Equals, "14")
+}
+
+func (s *S) TestPutReaderGetReader(c *C) {
+	data := strings.Repeat("0123456789", 10000)
+	err := s.client.PutReader("name", strings.NewReader(data), int64(len(data)), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	rc, err := s.client.GetReader("name")
+	c.Assert(err, IsNil)
+	got, err := ioutil.ReadAll(rc)
+	c.Assert(err, IsNil)
+	c.Assert(rc.Close(), IsNil)
+	c.Assert(string(got) == data, Equals, true)
+}
+
+func (s *S) TestGetAltered(c *C) {
+	err := s.client.Put("name", []byte("secret content"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	// Store the object again with the same envelope and one bit flipped.
+	resp, err := s.bucket.GetResponse("name")
+	c.Assert(err, IsNil)
+	stored, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	stored[3] ^= 1
+	meta := make(map[string][]string)
+	for name, v := range resp.Header {
+		if strings.HasPrefix(name, "X-Amz-Meta-") {
+			meta[strings.ToLower(name[len("X-Amz-Meta-"):])] = v
+		}
+	}
+	err = s.bucket.Put("name", stored, "text/plain", s3.Private, s3.Options{Meta: meta})
+	c.Assert(err, IsNil)
+
+	_, err = s.client.Get("name")
+	c.Assert(err, Equals, s3crypto.ErrAuthentication)
+}
+
+func (s *S) TestGetWrongKey(c *C) {
+	err := s.client.Put("name", []byte("secret content"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	key, err := s3crypto.NewLocalMasterKey(bytes.Repeat([]byte("o"), 32), nil)
+	c.Assert(err, IsNil)
+	_, err = s3crypto.New(s.bucket, key).Get("name")
+	c.Assert(err, ErrorMatches, "s3crypto: cannot unwrap data key: .*")
+}
+
+func (s *S) TestGetNotEncrypted(c *C) {
+	err := s.bucket.Put("name", []byte("content"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	_, err = s.client.Get("name")
+	c.Assert(err, ErrorMatches, "s3crypto: object has no envelope")
+}
+
+func (s *S) TestKMSMasterKey(c *C) {
+	testServer.Response(200, nil, `{"KeyId":"arn:aws:kms:us-east-1:123456789012:key/key-id",`+
+		`"Plaintext":"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=","CiphertextBlob":"d3JhcHBlZA=="}`)
+	testServer.Response(200, nil, `{"KeyId":"arn:aws:kms:us-east-1:123456789012:key/key-id",`+
+		`"Plaintext":"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="}`)
+
+	k := kms.New(auth, aws.Region{Name: "us-east-1", KMSEndpoint: testServer.URL})
+	client := s3crypto.New(s.bucket, s3crypto.NewKMSMasterKey(k, "alias/test"))
+	err := client.Put("name", []byte("secret content"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+	data, err := client.Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "secret content")
+
+	resp, err := s.bucket.Head("name", nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Header.Get("x-amz-meta-x-amz-wrap-alg"), Equals, "kms")
+	c.Assert(resp.Header.Get("x-amz-meta-x-amz-key-v2"), Equals, "d3JhcHBlZA==")
+	c.Assert(resp.Header.Get("x-amz-meta-x-amz-matdesc"), Equals, `{"kms_cmk_id":"alias/test"}`)
+
+	var params map[string]interface{}
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("X-Amz-Target"), Equals, "TrentService.GenerateDataKey")
+	c.Assert(json.NewDecoder(req.Body).Decode(&params), IsNil)
+	c.Assert(params["EncryptionContext"], DeepEquals, map[string]interface{}{"kms_cmk_id": "alias/test"})
+	req = testServer.WaitRequest()
+	c.Assert(req.Header.Get("X-Amz-Target"), Equals, "TrentService.Decrypt")
+	c.Assert(json.NewDecoder(req.Body).Decode(&params), IsNil)
+	c.Assert(params["CiphertextBlob"], Equals, "d3JhcHBlZA==")
+	c.Assert(params["EncryptionContext"], DeepEquals, map[string]interface{}{"kms_cmk_id": "alias/test"})
+}
+
+func (s *S) TestUpload(c *C) {
+	const parts = 3
+	testServer.Response(200, nil, `<InitiateMultipartUploadResult><UploadId>id</UploadId></InitiateMultipartUploadResult>`)
+	var listed strings.Builder
+	for i, size := range []int{40, 40, 36} {
+		testServer.Response(200, map[string]string{"ETag": `"etag"`}, "")
+		fmt.Fprintf(&listed, `<Part><PartNumber>%d</PartNumber><ETag>"etag"</ETag><Size>%d</Size></Part>`, i+1, size)
+	}
+	testServer.Response(200, nil, "<ListPartsResult>"+listed.String()+"</ListPartsResult>")
+	testServer.Response(200, nil, "")
+
+	b := s3.New(auth, aws.Region{Name: "faux-region-1", S3Endpoint: testServer.URL}).Bucket("bucket")
+	client := s3crypto.New(b, s.key)
+	client.Uploader.PartSize = 40
+	client.Uploader.Concurrency = 1
+	data := strings.Repeat("0123456789", 10)
+	// Hide the length of the content.
+	r := struct{ io.Reader }{strings.NewReader(data)}
+	err := client.Upload("name", r, "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["uploads"], DeepEquals, []string{""})
+	c.Assert(req.Header.Get("x-amz-meta-x-amz-unencrypted-content-length"), Equals, "100")
+	headers := make(map[string]string)
+	for name := range req.Header {
+		if strings.HasPrefix(name, "X-Amz-Meta-") {
+			headers[name] = req.Header.Get(name)
+		}
+	}
+	var stored []byte
+	for i := 0; i < parts; i++ {
+		req = testServer.WaitRequest()
+		c.Assert(req.Method, Equals, "PUT")
+		part, err := ioutil.ReadAll(req.Body)
+		c.Assert(err, IsNil)
+		stored = append(stored, part...)
+	}
+	c.Assert(stored, HasLen, len(data)+16)
+
+	testServer.Response(200, headers, string(stored))
+	got, err := client.Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(got), Equals, data)
+}
+
+func (s *S) TestTooLarge(c *C) {
+	data := make([]byte, s3crypto.MaxSize+1)
+	err := s.client.Put("name", data, "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, Equals, s3crypto.ErrTooLarge)
+	err = s.client.Upload("name", bytes.NewReader(data), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, Equals, s3crypto.ErrTooLarge)
+	// The length given is checked before anything is read.
+	err = s.client.PutReader("name", strings.NewReader(""), int64(len(data)), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, Equals, s3crypto.ErrTooLarge)
+	_, err = s.bucket.Head("name", nil)
+	c.Assert(err, NotNil)
+
+	// An object whose content is too large is not read.
+	err = s.client.Put("name", []byte("content"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+	resp, err := s.bucket.GetResponse("name")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	headers := map[string]string{"x-amz-meta-x-amz-unencrypted-content-length": fmt.Sprint(len(data))}
+	for name := range resp.Header {
+		if strings.HasPrefix(name, "X-Amz-Meta-X-Amz-") && name != "X-Amz-Meta-X-Amz-Unencrypted-Content-Length" {
+			headers[name] = resp.Header.Get(name)
+		}
+	}
+	testServer.Response(200, headers, "")
+	b := s3.New(auth, aws.Region{Name: "faux-region-1", S3Endpoint: testServer.URL}).Bucket("bucket")
+	_, err = s3crypto.New(b, s.key).Get("name")
+	c.Assert(err, Equals, s3crypto.ErrTooLarge)
+}
@@ -0,0 +1,335 @@
+// Package s3fs presents the objects of a bucket as a read-only file
+// system, implementing io/fs and, through HTTP, net/http interfaces.
+//
+// Object keys are slash-separated paths, and directories are the common
+// prefixes of keys up to a slash, as listed with the delimiter "/". Keys
+// that are not valid paths, such as keys ending with a slash, used as
+// folders by some tools, or holding empty elements, are not listed.
+//
+// Files read objects with range requests, starting from the current
+// offset after each seek, so that http.ServeContent only retrieves the
+// parts of large objects that are asked for.
+package s3fs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goamz/goamz/s3"
+)
+
+// FS is a read-only file system holding the objects of Bucket.
+type FS struct {
+	Bucket *s3.Bucket
+}
+
+var (
+	_ fs.FS        = (*FS)(nil)
+	_ fs.ReadDirFS = (*FS)(nil)
+	_ fs.StatFS    = (*FS)(nil)
+)
+
+// New returns a file system holding the objects of b.
+func New(b *s3.Bucket) *FS {
+	return &FS{Bucket: b}
+}
+
+// HTTP returns fsys as an http.FileSystem, to be served by
+// http.FileServer.
+func (fsys *FS) HTTP() http.FileSystem {
+	return http.FS(fsys)
+}
+
+// Open opens the object or directory name.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	info, err := fsys.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &dir{fsys: fsys, name: name, info: info}, nil
+	}
+	return &file{fsys: fsys, name: name, info: info}, nil
+}
+
+// Stat returns information about the object or directory name.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	return fsys.stat("stat", name)
+}
+
+func (fsys *FS) stat(op, name string) (*fileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &fileInfo{name: ".", dir: true}, nil
+	}
+	resp, err := fsys.Bucket.Head(name, nil)
+	if err == nil {
+		resp.Body.Close()
+		info := &fileInfo{
+			name: path.Base(name),
+			size: resp.ContentLength,
+			etag: resp.Header.Get("ETag"),
+		}
+		info.mtime, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+		return info, nil
+	}
+	if e, ok := err.(*s3.Error); !ok || e.StatusCode != 404 {
+		return nil, pathError(op, name, err)
+	}
+	list, err := fsys.Bucket.List(name+"/", "/", "", 1)
+	if err != nil {
+		return nil, pathError(op, name, err)
+	}
+	if len(list.Contents) == 0 && len(list.CommonPrefixes) == 0 {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return &fileInfo{name: path.Base(name), dir: true}, nil
+}
+
+// ReadDir returns the entries of the directory name sorted by name.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	prefix := ""
+	if name != "." {
+		prefix = name + "/"
+	}
+	var entries []fs.DirEntry
+	iter := fsys.Bucket.ListIter(prefix, "/")
+	for iter.Next() {
+		var info *fileInfo
+		if p := iter.CommonPrefix(); p != "" {
+			info = &fileInfo{name: strings.TrimSuffix(p[len(prefix):], "/"), dir: true}
+		} else {
+			key := iter.Key()
+			info = &fileInfo{
+				name: key.Key[len(prefix):],
+				size: key.Size,
+				etag: key.ETag,
+			}
+			// Head only gives the modification time to the second.
+			mtime, _ := time.Parse(time.RFC3339, key.LastModified)
+			info.mtime = mtime.Truncate(time.Second)
+		}
+		if info.name == "" || !fs.ValidPath(info.name) {
+			continue
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, pathError("readdir", name, err)
+	}
+	if len(entries) == 0 && name != "." {
+		// Directories only holding keys that are not listed are empty.
+		info, err := fsys.stat("readdir", name)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// pathError returns err, returned by S3 for the operation op on name, as
+// an *fs.PathError wrapping fs.ErrNotExist or fs.ErrPermission when
+// relevant.
+func pathError(op, name string, err error) error {
+	if e, ok := err.(*s3.Error); ok {
+		switch e.StatusCode {
+		case 404:
+			err = fmt.Errorf("%w: %v", fs.ErrNotExist, e)
+		case 403:
+			err = fmt.Errorf("%w: %v", fs.ErrPermission, e)
+		}
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// fileInfo describes an object or a directory.
+type fileInfo struct {
+	name  string
+	size  int64
+	mtime time.Time
+	etag  string
+	dir   bool
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) ModTime() time.Time { return fi.mtime }
+func (fi *fileInfo) IsDir() bool        { return fi.dir }
+func (fi *fileInfo) Sys() interface{}   { return nil }
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// file is an open object. The object is retrieved from the current
+// offset on the first read after opening the file or seeking, with an
+// If-Match header holding the ETag the object was opened with, so reads
+// fail if the object is replaced meanwhile.
+type file struct {
+	fsys   *FS
+	name   string
+	info   *fileInfo
+	offset int64
+
+	// body is the content of the object from bodyOffset on.
+	body       io.ReadCloser
+	bodyOffset int64
+	closed     bool
+}
+
+var _ io.ReadSeekCloser = (*file)(nil)
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrClosed}
+	}
+	if f.offset >= f.info.size {
+		return 0, io.EOF
+	}
+	if f.body != nil && f.bodyOffset != f.offset {
+		f.body.Close()
+		f.body = nil
+	}
+	if f.body == nil {
+		headers := map[string][]string{
+			"Range": {fmt.Sprintf("bytes=%d-", f.offset)},
+		}
+		if f.info.etag != "" {
+			headers["If-Match"] = []string{f.info.etag}
+		}
+		resp, err := f.fsys.Bucket.GetResponseWithHeaders(f.name, headers)
+		if err != nil {
+			return 0, pathError("read", f.name, err)
+		}
+		f.body = resp.Body
+		f.bodyOffset = f.offset
+	}
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	f.bodyOffset += int64(n)
+	if err == io.EOF {
+		if f.offset < f.info.size {
+			err = io.ErrUnexpectedEOF
+		}
+		f.body.Close()
+		f.body = nil
+	}
+	return n, err
+}
+
+// Seek sets the offset of the next read. No request is sent until then.
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrClosed}
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *file) Close() error {
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	if f.body != nil {
+		return f.body.Close()
+	}
+	return nil
+}
+
+// dir is an open directory. Its entries are listed on the first call to
+// ReadDir.
+type dir struct {
+	fsys    *FS
+	name    string
+	info    *fileInfo
+	entries []fs.DirEntry
+	listed  bool
+}
+
+var _ fs.ReadDirFile = (*dir)(nil)
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+// Seek only allows rewinding the directory, which lists it again on the
+// next call to ReadDir.
+func (d *dir) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, &fs.PathError{Op: "seek", Path: d.name, Err: fs.ErrInvalid}
+	}
+	d.entries = nil
+	d.listed = false
+	return 0, nil
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.listed {
+		entries, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.listed = true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+func (d *dir) Close() error {
+	return nil
+}
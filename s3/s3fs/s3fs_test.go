@@ -0,0 +1,181 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goamz/goamz/aws"
+	"github.com/goamz/goamz/s3"
+	"github.com/goamz/goamz/s3/s3fs"
+	"github.com/goamz/goamz/s3/s3test"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&S{})
+
+type S struct {
+	srv    *s3test.Server
+	bucket *s3.Bucket
+	fsys   *s3fs.FS
+}
+
+var content = map[string]string{
+	"a.txt":            "a",
+	"dir/b.txt":        "bb",
+	"dir/sub/c.html":   "<p>c</p>",
+	"dir/sub/d.bin":    strings.Repeat("0123456789", 1000),
+	"folder/":          "",
+	"empty/":           "",
+	"bad//key":         "ignored",
+	"with-marker/":     "",
+	"with-marker/e.go": "package e",
+}
+
+func (s *S) SetUpTest(c *C) {
+	srv, err := s3test.NewServer(&s3test.Config{})
+	c.Assert(err, IsNil)
+	s.srv = srv
+	region := aws.Region{
+		Name:                 "faux-region-1",
+		S3Endpoint:           srv.URL(),
+		S3LocationConstraint: true,
+	}
+	s.bucket = s3.New(aws.Auth{AccessKey: "abc", SecretKey: "123"}, region).Bucket("bucket")
+	err = s.bucket.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	for key, data := range content {
+		err := s.bucket.Put(key, []byte(data), "", s3.Private, s3.Options{})
+		c.Assert(err, IsNil)
+	}
+	s.fsys = s3fs.New(s.bucket)
+}
+
+func (s *S) TearDownTest(c *C) {
+	s.srv.Quit()
+}
+
+func (s *S) TestFS(c *C) {
+	err := fstest.TestFS(s.fsys, "a.txt", "dir/b.txt", "dir/sub/c.html", "dir/sub/d.bin", "with-marker/e.go")
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TestReadDir(c *C) {
+	entries, err := s.fsys.ReadDir(".")
+	c.Assert(err, IsNil)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	c.Assert(names, DeepEquals, []string{"a.txt", "bad", "dir", "empty", "folder", "with-marker"})
+	c.Assert(entries[0].IsDir(), Equals, false)
+	c.Assert(entries[2].IsDir(), Equals, true)
+
+	entries, err = s.fsys.ReadDir("empty")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 0)
+
+	_, err = s.fsys.ReadDir("a.txt")
+	c.Assert(err, ErrorMatches, "readdir a.txt: not a directory")
+	_, err = s.fsys.ReadDir("missing")
+	c.Assert(errors.Is(err, fs.ErrNotExist), Equals, true)
+}
+
+func (s *S) TestStat(c *C) {
+	info, err := s.fsys.Stat("dir/sub/d.bin")
+	c.Assert(err, IsNil)
+	c.Assert(info.Name(), Equals, "d.bin")
+	c.Assert(info.Size(), Equals, int64(10000))
+	c.Assert(info.IsDir(), Equals, false)
+	c.Assert(info.ModTime().IsZero(), Equals, false)
+
+	info, err = s.fsys.Stat("dir/sub")
+	c.Assert(err, IsNil)
+	c.Assert(info.IsDir(), Equals, true)
+
+	_, err = s.fsys.Stat("dir/missing")
+	c.Assert(errors.Is(err, fs.ErrNotExist), Equals, true)
+	_, err = s.fsys.Stat("/a.txt")
+	c.Assert(errors.Is(err, fs.ErrInvalid), Equals, true)
+}
+
+func (s *S) TestSeek(c *C) {
+	f, err := s.fsys.Open("dir/sub/d.bin")
+	c.Assert(err, IsNil)
+	defer f.Close()
+	rs := f.(io.ReadSeeker)
+
+	pos, err := rs.Seek(-5, io.SeekEnd)
+	c.Assert(err, IsNil)
+	c.Assert(pos, Equals, int64(9995))
+	data, err := ioutil.ReadAll(rs)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "56789")
+
+	_, err = rs.Seek(1003, io.SeekStart)
+	c.Assert(err, IsNil)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(rs, buf)
+	c.Assert(err, IsNil)
+	c.Assert(string(buf), Equals, "3456")
+	_, err = rs.Seek(-2, io.SeekCurrent)
+	c.Assert(err, IsNil)
+	_, err = io.ReadFull(rs, buf)
+	c.Assert(err, IsNil)
+	c.Assert(string(buf), Equals, "5678")
+
+	// Replacing the object fails further reads.
+	err = s.bucket.Put("dir/sub/d.bin", []byte("changed"), "", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+	_, err = rs.Seek(0, io.SeekStart)
+	c.Assert(err, IsNil)
+	_, err = rs.Read(buf)
+	c.Assert(err, ErrorMatches, "read dir/sub/d.bin: .*pre-conditions.*")
+}
+
+func (s *S) TestHTTP(c *C) {
+	srv := httptest.NewServer(http.FileServer(s.fsys.HTTP()))
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL+"/dir/sub/d.bin", nil)
+	c.Assert(err, IsNil)
+	req.Header.Set("Range", "bytes=9990-")
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusPartialContent)
+	c.Assert(resp.Header.Get("Content-Range"), Equals, "bytes 9990-9999/10000")
+	c.Assert(string(data), Equals, "0123456789")
+
+	resp, err = http.Get(srv.URL + "/dir/sub/c.html")
+	c.Assert(err, IsNil)
+	data, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(resp.Header.Get("Content-Type"), Matches, "text/html.*")
+	c.Assert(string(data), Equals, "<p>c</p>")
+
+	resp, err = http.Get(srv.URL + "/dir/")
+	c.Assert(err, IsNil)
+	data, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Matches, `(?s).*<a href="b.txt">b.txt</a>.*<a href="sub/">sub/</a>.*`)
+
+	resp, err = http.Get(srv.URL + "/missing")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
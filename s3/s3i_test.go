@@ -3,6 +3,8 @@ package s3_test
 import (
 	"bytes"
 	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -129,6 +131,11 @@ func killBucket(b *s3.Bucket) {
 					_ = b.Del(key.Key)
 				}
 			}
+			versions := b.VersionsIter("", "")
+			for versions.Next() {
+				v := versions.Version()
+				_ = b.DelVersion(v.Key, v.VersionId)
+			}
 			multis, _, _ := b.ListMulti("", "")
 			for _, m := range multis {
 				_ = m.Abort()
@@ -405,6 +412,496 @@ func checkContents(c *C, contents []s3.Key, data map[string][]byte, expected []s
 	}
 }
 
+func (s *ClientTests) TestLifecycleConfiguration(c *C) {
+	b := testBucket(s.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	_, err = b.GetLifecycleConfiguration()
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "NoSuchLifecycleConfiguration")
+
+	config := &s3.LifecycleConfiguration{
+		Rules: []s3.LifecycleRule{{
+			ID:     "logs",
+			Prefix: "logs/",
+			Status: s3.LifecycleRuleEnabled,
+			Transitions: []s3.LifecycleTransition{
+				{Days: 30, StorageClass: s3.StorageClassStandardIA},
+				{Days: 90, StorageClass: s3.StorageClassGlacier},
+			},
+			Expiration: &s3.LifecycleExpiration{Days: 365},
+		}, {
+			ID:                             "uploads",
+			Status:                         s3.LifecycleRuleDisabled,
+			NoncurrentVersionExpiration:    &s3.NoncurrentVersionExpiration{NoncurrentDays: 7},
+			AbortIncompleteMultipartUpload: &s3.AbortIncompleteMultipartUpload{DaysAfterInitiation: 2},
+		}},
+	}
+	err = b.PutLifecycleConfiguration(config)
+	c.Assert(err, IsNil)
+
+	got, err := b.GetLifecycleConfiguration()
+	c.Assert(err, IsNil)
+	c.Assert(got.Rules, DeepEquals, config.Rules)
+
+	err = b.DeleteLifecycleConfiguration()
+	c.Assert(err, IsNil)
+	_, err = b.GetLifecycleConfiguration()
+	c.Assert(err, NotNil)
+}
+
+func (s *ClientTests) TestACL(c *C) {
+	b := testBucket(s.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	p, err := b.GetBucketACL()
+	c.Assert(err, IsNil)
+	c.Assert(p.Owner.ID, Not(Equals), "")
+	c.Assert(p.Grants, DeepEquals, []s3.Grant{{
+		Grantee:    s3.Grantee{Type: s3.GranteeCanonicalUser, ID: p.Owner.ID, DisplayName: p.Owner.DisplayName},
+		Permission: s3.PermissionFullControl,
+	}})
+
+	logDelivery := s3.Grantee{Type: s3.GranteeGroup, URI: s3.LogDeliveryGroup}
+	p.Grants = append(p.Grants,
+		s3.Grant{Grantee: logDelivery, Permission: s3.PermissionWrite},
+		s3.Grant{Grantee: logDelivery, Permission: s3.PermissionReadACP},
+	)
+	err = b.PutBucketACL(p)
+	c.Assert(err, IsNil)
+	got, err := b.GetBucketACL()
+	c.Assert(err, IsNil)
+	c.Assert(got.Grants, DeepEquals, p.Grants)
+
+	err = b.Put("name", []byte("content"), "text/plain", s3.PublicRead, s3.Options{})
+	c.Assert(err, IsNil)
+	p, err = b.GetACL("name")
+	c.Assert(err, IsNil)
+	c.Assert(p.Grants, HasLen, 2)
+	c.Assert(p.Grants[1], DeepEquals, s3.Grant{
+		Grantee:    s3.Grantee{Type: s3.GranteeGroup, URI: s3.AllUsersGroup},
+		Permission: s3.PermissionRead,
+	})
+
+	p.Grants = p.Grants[:1]
+	err = b.PutACL("name", p)
+	c.Assert(err, IsNil)
+	got, err = b.GetACL("name")
+	c.Assert(err, IsNil)
+	c.Assert(got.Grants, DeepEquals, p.Grants)
+
+	_, err = b.GetACL("missing")
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "NoSuchKey")
+}
+
+func (s *ClientTests) TestBucketPolicy(c *C) {
+	b := testBucket(s.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	_, err = b.GetBucketPolicy()
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "NoSuchBucketPolicy")
+
+	policy := `{"Version":"2012-10-17","Statement":[{"Sid":"PublicRead","Effect":"Allow",` +
+		`"Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::` + b.Name + `/*"}]}`
+	err = b.PutBucketPolicy([]byte(policy))
+	c.Assert(err, IsNil)
+
+	data, err := b.GetBucketPolicy()
+	c.Assert(err, IsNil)
+	var got struct {
+		Statement []struct{ Sid string }
+	}
+	err = json.Unmarshal(data, &got)
+	c.Assert(err, IsNil)
+	c.Assert(got.Statement, HasLen, 1)
+	c.Assert(got.Statement[0].Sid, Equals, "PublicRead")
+
+	err = b.DeleteBucketPolicy()
+	c.Assert(err, IsNil)
+	_, err = b.GetBucketPolicy()
+	c.Assert(err, NotNil)
+}
+
+func (s *ClientTests) TestCORS(c *C) {
+	b := testBucket(s.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	_, err = b.GetBucketCORS()
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "NoSuchCORSConfiguration")
+
+	config := &s3.CORSConfiguration{
+		Rules: []s3.CORSRule{{
+			ID:             "upload",
+			AllowedOrigins: []string{"http://www.example.com"},
+			AllowedMethods: []string{"PUT", "POST"},
+			AllowedHeaders: []string{"*"},
+			MaxAgeSeconds:  3000,
+		}},
+	}
+	err = b.PutBucketCORS(config)
+	c.Assert(err, IsNil)
+
+	got, err := b.GetBucketCORS()
+	c.Assert(err, IsNil)
+	c.Assert(got.Rules, DeepEquals, config.Rules)
+
+	err = b.DeleteBucketCORS()
+	c.Assert(err, IsNil)
+	_, err = b.GetBucketCORS()
+	c.Assert(err, NotNil)
+}
+
+// TestNotification sends notifications to an SQS queue, which must exist
+// and allow S3 to send messages when testing against AWS.
+func (s *ClientTests) TestNotification(c *C) {
+	b := testBucket(s.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	config, err := b.GetBucketNotification()
+	c.Assert(err, IsNil)
+	c.Assert(config.Queues, HasLen, 0)
+
+	config = &s3.NotificationConfiguration{
+		Queues: []s3.QueueConfiguration{{
+			Id:     "images",
+			Queue:  "arn:aws:sqs:us-east-1:123456789012:queue",
+			Events: []string{s3.EventObjectCreated},
+			Filter: s3.KeyFilter("images/", ""),
+		}},
+	}
+	err = b.PutBucketNotification(config)
+	c.Assert(err, IsNil)
+
+	got, err := b.GetBucketNotification()
+	c.Assert(err, IsNil)
+	c.Assert(got.Queues, DeepEquals, config.Queues)
+
+	err = b.DeleteBucketNotification()
+	c.Assert(err, IsNil)
+	got, err = b.GetBucketNotification()
+	c.Assert(err, IsNil)
+	c.Assert(got.Queues, HasLen, 0)
+}
+
+// TestServerSideEncryption stores objects encrypted with S3 managed and
+// customer provided keys, which S3 only accepts over HTTPS.
+func (s *ClientTests) TestServerSideEncryption(c *C) {
+	b := testBucket(s.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	err = b.Put("sse", []byte("content"), "text/plain", s3.Private, s3.Options{SSE: true})
+	c.Assert(err, IsNil)
+	resp, err := b.Head("sse", nil)
+	c.Assert(err, IsNil)
+	c.Assert(s3.ResponseEncryption(resp.Header).Algorithm, Equals, s3.SSEAlgorithmAES256)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	err = b.Put("ssec", []byte("secret"), "text/plain", s3.Private, s3.Options{SSECustomerKey: key})
+	c.Assert(err, IsNil)
+
+	_, err = b.Get("ssec")
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).StatusCode, Equals, 400)
+
+	_, err = b.GetResponseWithHeaders("ssec", s3.SSECustomerKeyHeaders([]byte("fedcba9876543210fedcba9876543210")))
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).StatusCode, Equals, 403)
+
+	resp, err = b.GetResponseWithHeaders("ssec", s3.SSECustomerKeyHeaders(key))
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "secret")
+	c.Assert(s3.ResponseEncryption(resp.Header).CustomerAlgorithm, Equals, s3.SSEAlgorithmAES256)
+
+	_, err = b.PutCopy("copy", s3.Private, s3.CopyOptions{}, b.Name+"/ssec")
+	c.Assert(err, NotNil)
+	options := s3.CopyOptions{CopySourceSSECustomerKey: key}
+	options.SSE = true
+	result, err := b.PutCopy("copy", s3.Private, options, b.Name+"/ssec")
+	c.Assert(err, IsNil)
+	c.Assert(result.Encryption.Algorithm, Equals, s3.SSEAlgorithmAES256)
+	data, err = b.Get("copy")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "secret")
+}
+
+// TestDownloadSSECustomerKey downloads by ranges an object encrypted with
+// a customer provided key.
+func (s *ClientTests) TestDownloadSSECustomerKey(c *C) {
+	b := testBucket(s.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	content := "some secret content"
+	err = b.Put("ssec", []byte(content), "text/plain", s3.Private, s3.Options{SSECustomerKey: key})
+	c.Assert(err, IsNil)
+
+	d := s3.NewDownloader(b)
+	d.PartSize = 4
+	_, err = d.Download("ssec", &memWriterAt{})
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).StatusCode, Equals, 400)
+
+	d.SSECustomerKey = key
+	var w memWriterAt
+	n, err := d.Download("ssec", &w)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(content)))
+	c.Assert(string(w.data), Equals, content)
+}
+
+func (s *ClientTests) TestStorageClass(c *C) {
+	b := testBucket(s.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	err = b.Put("ia", []byte("content"), "text/plain", s3.Private, s3.Options{StorageClass: s3.StorageClassStandardIA})
+	c.Assert(err, IsNil)
+	err = b.Put("standard", []byte("content"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	resp, err := b.Head("ia", nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Header.Get("x-amz-storage-class"), Equals, s3.StorageClassStandardIA)
+
+	list, err := b.List("", "", "", 0)
+	c.Assert(err, IsNil)
+	c.Assert(list.Contents, HasLen, 2)
+	c.Assert(list.Contents[0].StorageClass, Equals, s3.StorageClassStandardIA)
+	c.Assert(list.Contents[1].StorageClass, Equals, s3.StorageClassStandard)
+
+	err = b.Put("bad", []byte("content"), "text/plain", s3.Private, s3.Options{StorageClass: "COLD"})
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).StatusCode, Equals, 400)
+}
+
+func (s *ClientTests) TestTagging(c *C) {
+	b := testBucket(s.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	tags := []s3.Tag{{"project", "goamz"}, {"owner", "a b&c"}}
+	err = b.Put("name", []byte("content"), "text/plain", s3.Private, s3.Options{Tags: tags})
+	c.Assert(err, IsNil)
+	got, err := b.GetTagging("name")
+	c.Assert(err, IsNil)
+	c.Assert(got, DeepEquals, tags)
+
+	resp, err := b.Head("name", nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Header.Get("x-amz-tagging-count"), Equals, "2")
+
+	_, err = b.PutCopy("copy", s3.Private, s3.CopyOptions{}, b.Name+"/name")
+	c.Assert(err, IsNil)
+	got, err = b.GetTagging("copy")
+	c.Assert(err, IsNil)
+	c.Assert(got, DeepEquals, tags)
+
+	options := s3.CopyOptions{TaggingDirective: "REPLACE"}
+	options.Tags = []s3.Tag{{"copied", "yes"}}
+	_, err = b.PutCopy("copy", s3.Private, options, b.Name+"/name")
+	c.Assert(err, IsNil)
+	got, err = b.GetTagging("copy")
+	c.Assert(err, IsNil)
+	c.Assert(got, DeepEquals, []s3.Tag{{"copied", "yes"}})
+
+	err = b.PutTagging("name", []s3.Tag{{"project", "other"}})
+	c.Assert(err, IsNil)
+	got, err = b.GetTagging("name")
+	c.Assert(err, IsNil)
+	c.Assert(got, DeepEquals, []s3.Tag{{"project", "other"}})
+
+	err = b.PutTagging("name", []s3.Tag{{"k", "1"}, {"k", "2"}})
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "InvalidTag")
+
+	err = b.DeleteTagging("name")
+	c.Assert(err, IsNil)
+	got, err = b.GetTagging("name")
+	c.Assert(err, IsNil)
+	c.Assert(got, HasLen, 0)
+}
+
+func (s *ClientTests) TestGetRange(c *C) {
+	b := testBucket(s.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	err = b.Put("name", []byte("0123456789"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	for _, t := range []struct{ r, data, contentRange string }{
+		{"bytes=2-4", "234", "bytes 2-4/10"},
+		{"bytes=7-", "789", "bytes 7-9/10"},
+		{"bytes=-2", "89", "bytes 8-9/10"},
+		{"bytes=8-20", "89", "bytes 8-9/10"},
+	} {
+		resp, err := b.GetResponseWithHeaders("name", map[string][]string{"Range": {t.r}})
+		c.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		c.Assert(err, IsNil)
+		c.Assert(resp.StatusCode, Equals, 206)
+		c.Assert(string(data), Equals, t.data)
+		c.Assert(resp.Header.Get("Content-Range"), Equals, t.contentRange)
+	}
+
+	_, err = b.GetResponseWithHeaders("name", map[string][]string{"Range": {"bytes=10-"}})
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).StatusCode, Equals, 416)
+
+	_, err = b.GetResponseWithHeaders("name", map[string][]string{"If-Match": {`"0123"`}})
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).StatusCode, Equals, 412)
+}
+
+func (s *ClientTests) TestRestore(c *C) {
+	b := testBucket(s.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	err = b.Put("archived", []byte("content"), "text/plain", s3.Private, s3.Options{StorageClass: s3.StorageClassGlacier})
+	c.Assert(err, IsNil)
+	_, err = b.Get("archived")
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "InvalidObjectState")
+	status, err := b.RestoreStatus("archived")
+	c.Assert(err, IsNil)
+	c.Assert(status, IsNil)
+
+	err = b.RestoreObject("archived", 1, s3.RestoreTierExpedited)
+	c.Assert(err, IsNil)
+	status, err = b.RestoreStatus("archived")
+	c.Assert(err, IsNil)
+	c.Assert(status, NotNil)
+	// Real restores take minutes at least, so only check the content of
+	// objects restored right away.
+	if !status.Ongoing {
+		c.Assert(status.Expiry.After(time.Now()), Equals, true)
+		data, err := b.Get("archived")
+		c.Assert(err, IsNil)
+		c.Assert(string(data), Equals, "content")
+	}
+
+	err = b.Put("standard", []byte("content"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+	err = b.RestoreObject("standard", 1, "")
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "InvalidObjectState")
+}
+
+func (s *ClientTests) TestVersioning(c *C) {
+	b := testBucket(s.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	config, err := b.GetBucketVersioning()
+	c.Assert(err, IsNil)
+	c.Assert(config.Status, Equals, "")
+
+	err = b.PutBucketVersioning(&s3.VersioningConfiguration{Status: s3.VersioningEnabled})
+	c.Assert(err, IsNil)
+	config, err = b.GetBucketVersioning()
+	c.Assert(err, IsNil)
+	c.Assert(config.Status, Equals, s3.VersioningEnabled)
+
+	err = b.Put("name", []byte("v1"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+	err = b.Put("name", []byte("v2"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	versions := func() []s3.Version {
+		var versions []s3.Version
+		iter := b.VersionsIter("", "")
+		for iter.Next() {
+			versions = append(versions, iter.Version())
+		}
+		c.Assert(iter.Err(), IsNil)
+		return versions
+	}
+	vs := versions()
+	c.Assert(vs, HasLen, 2)
+	c.Assert(vs[0].IsLatest, Equals, true)
+	c.Assert(vs[1].IsLatest, Equals, false)
+	c.Assert(vs[0].Size, Equals, int64(2))
+	v2, v1 := vs[0].VersionId, vs[1].VersionId
+	c.Assert(v1, Not(Equals), v2)
+
+	data, err := b.Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "v2")
+	data, err = b.GetVersion("name", v1)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "v1")
+	resp, err := b.HeadVersion("name", v1, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Header.Get("x-amz-version-id"), Equals, v1)
+
+	// Deleting the object hides its versions behind a delete marker.
+	err = b.Del("name")
+	c.Assert(err, IsNil)
+	_, err = b.Get("name")
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).StatusCode, Equals, 404)
+	vs = versions()
+	c.Assert(vs, HasLen, 3)
+	c.Assert(vs[0].IsDeleteMarker, Equals, true)
+	c.Assert(vs[0].IsLatest, Equals, true)
+	c.Assert(vs[1].VersionId, Equals, v2)
+	marker := vs[0].VersionId
+
+	copied, err := b.PutCopyVersion("copy", s3.Private, s3.CopyOptions{}, b.Name+"/name", v1)
+	c.Assert(err, IsNil)
+	c.Assert(copied.SourceVersionId, Equals, v1)
+	c.Assert(copied.VersionId, Not(Equals), "")
+	data, err = b.Get("copy")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "v1")
+
+	result, err := b.DelMultiResult(s3.Delete{Objects: []s3.Object{
+		{Key: "copy"},
+		{Key: "name", VersionId: marker},
+	}})
+	c.Assert(err, IsNil)
+	c.Assert(result.Errors, HasLen, 0)
+	deleted := make(map[string]s3.DeletedObject)
+	for _, d := range result.Deleted {
+		deleted[d.Key] = d
+	}
+	c.Assert(deleted["copy"].DeleteMarker, Equals, true)
+	c.Assert(deleted["copy"].DeleteMarkerVersionId, Not(Equals), "")
+	c.Assert(deleted["name"].DeleteMarker, Equals, true)
+	c.Assert(deleted["name"].VersionId, Equals, marker)
+
+	// Removing the delete marker, then the newest version, brings back
+	// the previous ones.
+	data, err = b.Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "v2")
+	err = b.DelVersion("name", v2)
+	c.Assert(err, IsNil)
+	data, err = b.Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "v1")
+
+	err = b.PutBucketVersioning(&s3.VersioningConfiguration{Status: s3.VersioningSuspended})
+	c.Assert(err, IsNil)
+	config, err = b.GetBucketVersioning()
+	c.Assert(err, IsNil)
+	c.Assert(config.Status, Equals, s3.VersioningSuspended)
+}
+
 func (s *ClientTests) TestMultiInitPutList(c *C) {
 	b := testBucket(s.s3)
 	err := b.PutBucket(s3.Private)
@@ -487,6 +984,62 @@ func (s *ClientTests) TestMultiComplete(c *C) {
 	c.Assert(string(data[len(data1):]), Equals, string(data2))
 }
 
+func (s *ClientTests) TestMultiPartCopy(c *C) {
+	b := testBucket(s.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	src := make([]byte, 5*1024*1024+10)
+	for i := range src {
+		src[i] = byte(i)
+	}
+	err = b.Put("source", src, "application/octet-stream", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	multi, err := b.InitMulti("multi", "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+	defer multi.Abort()
+
+	part1, err := multi.PutPartCopy(1, b.Name+"/source", 0, 5*1024*1024)
+	c.Assert(err, IsNil)
+	c.Assert(part1.Size, Equals, int64(5*1024*1024))
+	part2, err := multi.PutPart(2, strings.NewReader("<part 2>"))
+	c.Assert(err, IsNil)
+
+	err = multi.Complete([]s3.Part{part1, part1})
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "InvalidPartOrder")
+	err = multi.Complete([]s3.Part{part1, {N: 2, ETag: part1.ETag}})
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "InvalidPart")
+	err = multi.Complete([]s3.Part{part1, {N: 3, ETag: part2.ETag}})
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "InvalidPart")
+
+	err = multi.Complete([]s3.Part{part1, part2})
+	c.Assert(err, IsNil)
+	data, err := b.Get("multi")
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(data[:5*1024*1024], src[:5*1024*1024]), Equals, true)
+	c.Assert(string(data[5*1024*1024:]), Equals, "<part 2>")
+
+	// The ETag of objects uploaded in parts is the MD5 of the MD5s of
+	// the parts followed by their number.
+	sum := md5.New()
+	for _, p := range []s3.Part{part1, part2} {
+		digest, err := hex.DecodeString(strings.Trim(p.ETag, `"`))
+		c.Assert(err, IsNil)
+		sum.Write(digest)
+	}
+	resp, err := b.Head("multi", nil)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Trim(resp.Header.Get("ETag"), `"`), Equals, fmt.Sprintf("%x-2", sum.Sum(nil)))
+
+	_, err = multi.ListParts()
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "NoSuchUpload")
+}
+
 type multiList []*s3.Multi
 
 func (l multiList) Len() int           { return len(l) }
@@ -0,0 +1,121 @@
+// Command s3sync mirrors a local directory to S3, or S3 to a local
+// directory:
+//
+//	s3sync [flags] DIR s3://BUCKET/PREFIX
+//	s3sync [flags] s3://BUCKET/PREFIX DIR
+//
+// Credentials are taken from the environment or the instance role.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/goamz/goamz/aws"
+	"github.com/goamz/goamz/s3"
+	"github.com/goamz/goamz/s3/s3sync"
+)
+
+// patterns is a flag that can be repeated.
+type patterns []string
+
+func (p *patterns) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patterns) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
+
+var (
+	region    = flag.String("region", aws.USEast.Name, "region of the bucket")
+	del       = flag.Bool("delete", false, "delete the files or objects missing from the source")
+	dryRun    = flag.Bool("dryrun", false, "only print what would be done")
+	threshold = flag.Int64("multipart-threshold", s3sync.DefaultMultipartThreshold, "size from which files are sent and retrieved in parts")
+	acl       = flag.String("acl", string(s3.Private), "canned ACL of the objects stored")
+	quiet     = flag.Bool("q", false, "do not print what is done")
+	include   patterns
+	exclude   patterns
+)
+
+func main() {
+	flag.Var(&include, "include", "only sync the paths matching this glob (repeatable)")
+	flag.Var(&exclude, "exclude", "do not sync the paths matching this glob (repeatable)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: s3sync [flags] DIR s3://BUCKET/PREFIX\n")
+		fmt.Fprintf(os.Stderr, "       s3sync [flags] s3://BUCKET/PREFIX DIR\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("s3sync: ")
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	src, dst := flag.Arg(0), flag.Arg(1)
+	upload := strings.HasPrefix(dst, "s3://")
+	if upload == strings.HasPrefix(src, "s3://") {
+		log.Fatal("exactly one of the source and destination must be an s3:// URL")
+	}
+	url, dir := src, dst
+	if upload {
+		url, dir = dst, src
+	}
+	bucket, prefix := splitURL(url)
+	if bucket == "" {
+		log.Fatalf("no bucket in %q", url)
+	}
+
+	r, ok := aws.Regions[*region]
+	if !ok {
+		log.Fatalf("unknown region %q", *region)
+	}
+	auth, err := aws.GetAuth("", "", "", time.Time{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := s3sync.New(s3.New(auth, r).Bucket(bucket))
+	s.Delete = *del
+	s.DryRun = *dryRun
+	s.MultipartThreshold = *threshold
+	s.Perm = s3.ACL(*acl)
+	s.Include = include
+	s.Exclude = exclude
+	if !*quiet {
+		s.Log = func(a s3sync.Action) {
+			prefix := ""
+			if *dryRun {
+				prefix = "(dryrun) "
+			}
+			fmt.Printf("%s%s %s\n", prefix, a.Op, a.Path)
+		}
+	}
+
+	var result *s3sync.Result
+	if upload {
+		result, err = s.Upload(dir, prefix)
+	} else {
+		result, err = s.Download(prefix, dir)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !*quiet {
+		fmt.Printf("%d changed, %d unchanged\n", len(result.Actions), result.Unchanged)
+	}
+}
+
+// splitURL returns the bucket and key prefix of an s3://BUCKET/PREFIX URL.
+func splitURL(url string) (bucket, prefix string) {
+	rest := strings.TrimPrefix(url, "s3://")
+	if i := strings.Index(rest, "/"); i >= 0 {
+		return rest[:i], rest[i+1:]
+	}
+	return rest, ""
+}
@@ -0,0 +1,433 @@
+// Package s3sync mirrors local directories to S3 and back.
+//
+// Files are compared with the objects under a key prefix by size, and
+// then by content when the ETag of the object is the MD5 of its content,
+// or by modification time otherwise, as for objects stored by multipart
+// uploads. Only the files and objects found to differ are sent.
+package s3sync
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goamz/goamz/s3"
+)
+
+// DefaultMultipartThreshold is the size from which files are sent and
+// retrieved in parts by syncers created by New.
+const DefaultMultipartThreshold = 16 * 1024 * 1024
+
+// maxDeleteObjects is the number of objects DelMulti removes at most.
+const maxDeleteObjects = 1000
+
+// The operations done by a sync.
+const (
+	OpUpload   = "upload"
+	OpDownload = "download"
+	OpDelete   = "delete"
+)
+
+// An Action is an operation done by a sync on a file or object.
+type Action struct {
+	Op string
+
+	// Path is the slash-separated path of the file relative to the
+	// directory synced, which is also the key of the object relative
+	// to the prefix synced.
+	Path string
+
+	// Size is the number of bytes sent or retrieved.
+	Size int64
+}
+
+// Result reports what a sync did.
+type Result struct {
+	// Actions are the operations done, or that would have been done
+	// with DryRun, in the order they were done.
+	Actions []Action
+
+	// Unchanged is the number of files found identical to their object.
+	Unchanged int
+}
+
+// A Syncer mirrors local directories to a prefix of Bucket, or a prefix
+// of Bucket to local directories.
+type Syncer struct {
+	Bucket *s3.Bucket
+
+	// Uploader sends the files of at least MultipartThreshold bytes, and
+	// Downloader retrieves the objects of at least that size. Smaller
+	// ones are sent and retrieved with a single request.
+	Uploader           *s3.Uploader
+	Downloader         *s3.Downloader
+	MultipartThreshold int64
+
+	// Perm and Options are used for all the objects stored.
+	Perm    s3.ACL
+	Options s3.Options
+
+	// Delete is whether to remove the objects or files missing from the
+	// source of the sync.
+	Delete bool
+
+	// DryRun is whether to only report what the sync would do.
+	DryRun bool
+
+	// Include and Exclude are glob patterns, as used by path.Match,
+	// selecting the files and objects synced. Patterns are matched
+	// against slash-separated relative paths, or against base names if
+	// they hold no slash. If Include is not empty, only the paths it
+	// matches are synced; the paths Exclude matches are never synced,
+	// nor deleted.
+	Include []string
+	Exclude []string
+
+	// Log, if set, is called before each action is done.
+	Log func(a Action)
+}
+
+// New returns a syncer for b using the default settings.
+func New(b *s3.Bucket) *Syncer {
+	return &Syncer{
+		Bucket:             b,
+		Uploader:           s3.NewUploader(b),
+		Downloader:         s3.NewDownloader(b),
+		MultipartThreshold: DefaultMultipartThreshold,
+		Perm:               s3.Private,
+	}
+}
+
+// entry is a file or an object found by a sync.
+type entry struct {
+	size  int64
+	mtime time.Time
+	// etag is the ETag of objects, without quotes.
+	etag string
+}
+
+// Upload sends the files under dir that differ from the objects under
+// prefix, which are stored at prefix followed by their relative path.
+// A slash is added to prefix if it does not end with one.
+func (s *Syncer) Upload(dir, prefix string) (*Result, error) {
+	prefix = normPrefix(prefix)
+	files, err := s.walk(dir)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := s.list(prefix)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{}
+	for _, name := range sortedNames(files) {
+		f := files[name]
+		local := filepath.Join(dir, filepath.FromSlash(name))
+		if obj, ok := objects[name]; ok {
+			same, err := s.same(local, f, obj, false)
+			if err != nil {
+				return result, err
+			}
+			if same {
+				result.Unchanged++
+				continue
+			}
+		}
+		if err := s.do(result, Action{OpUpload, name, f.size}, func() error {
+			return s.put(prefix+name, local, f.size)
+		}); err != nil {
+			return result, err
+		}
+	}
+	if !s.Delete {
+		return result, nil
+	}
+	var del s3.Delete
+	for _, name := range sortedNames(objects) {
+		if _, ok := files[name]; ok {
+			continue
+		}
+		if err := s.do(result, Action{Op: OpDelete, Path: name}, nil); err != nil {
+			return result, err
+		}
+		del.Objects = append(del.Objects, s3.Object{Key: prefix + name})
+	}
+	if s.DryRun {
+		return result, nil
+	}
+	for len(del.Objects) > 0 {
+		n := len(del.Objects)
+		if n > maxDeleteObjects {
+			n = maxDeleteObjects
+		}
+		if err := s.Bucket.DelMulti(s3.Delete{Quiet: true, Objects: del.Objects[:n]}); err != nil {
+			return result, err
+		}
+		del.Objects = del.Objects[n:]
+	}
+	return result, nil
+}
+
+// Download retrieves the objects under prefix that differ from the files
+// under dir, which are written at their key relative to prefix. A slash
+// is added to prefix if it does not end with one. The files retrieved
+// have the modification time of their object.
+func (s *Syncer) Download(prefix, dir string) (*Result, error) {
+	prefix = normPrefix(prefix)
+	objects, err := s.list(prefix)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.walk(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	result := &Result{}
+	for _, name := range sortedNames(objects) {
+		obj := objects[name]
+		local := filepath.Join(dir, filepath.FromSlash(name))
+		if f, ok := files[name]; ok {
+			same, err := s.same(local, f, obj, true)
+			if err != nil {
+				return result, err
+			}
+			if same {
+				result.Unchanged++
+				continue
+			}
+		}
+		if err := s.do(result, Action{OpDownload, name, obj.size}, func() error {
+			return s.get(prefix+name, local, obj)
+		}); err != nil {
+			return result, err
+		}
+	}
+	if !s.Delete {
+		return result, nil
+	}
+	for _, name := range sortedNames(files) {
+		if _, ok := objects[name]; ok {
+			continue
+		}
+		local := filepath.Join(dir, filepath.FromSlash(name))
+		if err := s.do(result, Action{Op: OpDelete, Path: name}, func() error {
+			return os.Remove(local)
+		}); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// do records a, and calls f unless it is nil or this is a dry run.
+func (s *Syncer) do(result *Result, a Action, f func() error) error {
+	if s.Log != nil {
+		s.Log(a)
+	}
+	result.Actions = append(result.Actions, a)
+	if s.DryRun || f == nil {
+		return nil
+	}
+	if err := f(); err != nil {
+		return fmt.Errorf("s3sync: %s %s: %v", a.Op, a.Path, err)
+	}
+	return nil
+}
+
+// selected reports whether the relative path name is synced.
+func (s *Syncer) selected(name string) bool {
+	if len(s.Include) > 0 && !match(s.Include, name) {
+		return false
+	}
+	return !match(s.Exclude, name)
+}
+
+func match(patterns []string, name string) bool {
+	for _, p := range patterns {
+		target := name
+		if !strings.Contains(p, "/") {
+			target = path.Base(name)
+		}
+		if ok, _ := path.Match(p, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// walk returns the regular files under dir selected for syncing, by
+// slash-separated relative path.
+func (s *Syncer) walk(dir string) (map[string]entry, error) {
+	files := make(map[string]entry)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if s.selected(name) {
+			files[name] = entry{size: info.Size(), mtime: info.ModTime()}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// list returns the objects under prefix selected for syncing, by key
+// relative to prefix. Keys ending with a slash, used as folders by some
+// tools, are skipped.
+func (s *Syncer) list(prefix string) (map[string]entry, error) {
+	objects := make(map[string]entry)
+	iter := s.Bucket.ListIter(prefix, "")
+	for iter.Next() {
+		key := iter.Key()
+		name := key.Key[len(prefix):]
+		if name == "" || strings.HasSuffix(name, "/") || !s.selected(name) {
+			continue
+		}
+		if !safePath(name) {
+			return nil, fmt.Errorf("s3sync: key %q cannot be synced to a file", key.Key)
+		}
+		mtime, err := time.Parse(time.RFC3339, key.LastModified)
+		if err != nil {
+			return nil, fmt.Errorf("s3sync: bad modification time of %q: %v", key.Key, err)
+		}
+		objects[name] = entry{
+			size:  key.Size,
+			mtime: mtime,
+			etag:  strings.Trim(key.ETag, `"`),
+		}
+	}
+	return objects, iter.Err()
+}
+
+// safePath reports whether the relative path name stays within the
+// directory it is relative to.
+func safePath(name string) bool {
+	for _, elem := range strings.Split(name, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// same reports whether the file at local, described by f, is identical
+// to the object described by obj. Files are compared by content if the
+// ETag of the object is the MD5 of its content, and otherwise considered
+// changed if the source of the sync is the most recent.
+func (s *Syncer) same(local string, f, obj entry, download bool) (bool, error) {
+	if f.size != obj.size {
+		return false, nil
+	}
+	if len(obj.etag) != 2*md5.Size || strings.Contains(obj.etag, "-") {
+		if download {
+			return !obj.mtime.After(f.mtime), nil
+		}
+		return !f.mtime.After(obj.mtime), nil
+	}
+	file, err := os.Open(local)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return false, err
+	}
+	return hex.EncodeToString(h.Sum(nil)) == obj.etag, nil
+}
+
+// put stores the size bytes of the file at local at key.
+func (s *Syncer) put(key, local string, size int64) error {
+	f, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	contType := mime.TypeByExtension(path.Ext(key))
+	if contType == "" {
+		contType = "application/octet-stream"
+	}
+	if size < s.MultipartThreshold {
+		return s.Bucket.PutReader(key, io.LimitReader(f, size), size, contType, s.Perm, s.Options)
+	}
+	m, err := s.Uploader.Bucket.InitMultiOptions(key, contType, s.Perm, s.Options)
+	if err != nil {
+		return err
+	}
+	parts, err := s.Uploader.PutAll(m, f)
+	if err == nil {
+		err = m.Complete(parts)
+	}
+	if err != nil {
+		m.Abort()
+		return err
+	}
+	return nil
+}
+
+// get writes the object at key, described by obj, to the file at local.
+// The object is written to a temporary file first, so that the file is
+// left unchanged if the object cannot be retrieved.
+func (s *Syncer) get(key, local string, obj entry) error {
+	dir := filepath.Dir(local)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	tmp, err := os.OpenFile(filepath.Join(dir, "."+filepath.Base(local)+".s3sync"), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if obj.size < s.MultipartThreshold {
+		var rc io.ReadCloser
+		rc, err = s.Bucket.GetReader(key)
+		if err == nil {
+			_, err = io.Copy(tmp, rc)
+			rc.Close()
+		}
+	} else {
+		_, err = s.Downloader.Download(key, tmp)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp.Name(), obj.mtime, obj.mtime); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), local)
+}
+
+func normPrefix(prefix string) string {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix
+}
+
+func sortedNames(entries map[string]entry) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
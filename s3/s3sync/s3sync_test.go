@@ -0,0 +1,204 @@
+package s3sync_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goamz/goamz/aws"
+	"github.com/goamz/goamz/s3"
+	"github.com/goamz/goamz/s3/s3sync"
+	"github.com/goamz/goamz/s3/s3test"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&S{})
+
+type S struct {
+	srv    *s3test.Server
+	bucket *s3.Bucket
+	dir    string
+}
+
+func (s *S) SetUpTest(c *C) {
+	srv, err := s3test.NewServer(&s3test.Config{})
+	c.Assert(err, IsNil)
+	s.srv = srv
+	region := aws.Region{
+		Name:                 "faux-region-1",
+		S3Endpoint:           srv.URL(),
+		S3LocationConstraint: true,
+	}
+	s.bucket = s3.New(aws.Auth{AccessKey: "abc", SecretKey: "123"}, region).Bucket("bucket")
+	err = s.bucket.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	s.dir = c.MkDir()
+}
+
+func (s *S) TearDownTest(c *C) {
+	s.srv.Quit()
+}
+
+func (s *S) writeFile(c *C, name, content string) {
+	p := filepath.Join(s.dir, filepath.FromSlash(name))
+	err := os.MkdirAll(filepath.Dir(p), 0777)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(p, []byte(content), 0666)
+	c.Assert(err, IsNil)
+}
+
+func (s *S) keys(c *C) map[string]string {
+	resp, err := s.bucket.List("", "", "", 0)
+	c.Assert(err, IsNil)
+	keys := make(map[string]string)
+	for _, key := range resp.Contents {
+		data, err := s.bucket.Get(key.Key)
+		c.Assert(err, IsNil)
+		keys[key.Key] = string(data)
+	}
+	return keys
+}
+
+func ops(result *s3sync.Result) []string {
+	var ops []string
+	for _, a := range result.Actions {
+		ops = append(ops, a.Op+" "+a.Path)
+	}
+	return ops
+}
+
+func (s *S) TestUpload(c *C) {
+	s.writeFile(c, "a.txt", "a")
+	s.writeFile(c, "sub/b.txt", "bb")
+	s.writeFile(c, "sub/c.tmp", "ignored")
+	err := s.bucket.Put("other/key", []byte("x"), "", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	syncer := s3sync.New(s.bucket)
+	syncer.Exclude = []string{"*.tmp"}
+	var logged []s3sync.Action
+	syncer.Log = func(a s3sync.Action) {
+		logged = append(logged, a)
+	}
+	result, err := syncer.Upload(s.dir, "prefix")
+	c.Assert(err, IsNil)
+	c.Assert(result.Actions, DeepEquals, []s3sync.Action{
+		{s3sync.OpUpload, "a.txt", 1},
+		{s3sync.OpUpload, "sub/b.txt", 2},
+	})
+	c.Assert(logged, DeepEquals, result.Actions)
+	c.Assert(s.keys(c), DeepEquals, map[string]string{
+		"other/key":        "x",
+		"prefix/a.txt":     "a",
+		"prefix/sub/b.txt": "bb",
+	})
+	resp, err := s.bucket.Head("prefix/a.txt", nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Header.Get("Content-Type"), Matches, "text/plain.*")
+
+	result, err = syncer.Upload(s.dir, "prefix/")
+	c.Assert(err, IsNil)
+	c.Assert(result.Actions, HasLen, 0)
+	c.Assert(result.Unchanged, Equals, 2)
+
+	// Same size, different content.
+	s.writeFile(c, "sub/b.txt", "BB")
+	result, err = syncer.Upload(s.dir, "prefix")
+	c.Assert(err, IsNil)
+	c.Assert(ops(result), DeepEquals, []string{"upload sub/b.txt"})
+	c.Assert(s.keys(c)["prefix/sub/b.txt"], Equals, "BB")
+}
+
+func (s *S) TestUploadDelete(c *C) {
+	s.writeFile(c, "a.txt", "a")
+	for _, key := range []string{"prefix/a.txt", "prefix/gone", "prefix/keep.tmp", "prefixed"} {
+		err := s.bucket.Put(key, []byte("old"), "", s3.Private, s3.Options{})
+		c.Assert(err, IsNil)
+	}
+
+	syncer := s3sync.New(s.bucket)
+	syncer.Exclude = []string{"*.tmp"}
+	syncer.Delete = true
+	syncer.DryRun = true
+	result, err := syncer.Upload(s.dir, "prefix")
+	c.Assert(err, IsNil)
+	c.Assert(ops(result), DeepEquals, []string{"upload a.txt", "delete gone"})
+	c.Assert(s.keys(c), HasLen, 4)
+
+	syncer.DryRun = false
+	result, err = syncer.Upload(s.dir, "prefix")
+	c.Assert(err, IsNil)
+	c.Assert(ops(result), DeepEquals, []string{"upload a.txt", "delete gone"})
+	c.Assert(s.keys(c), DeepEquals, map[string]string{
+		"prefix/a.txt":    "a",
+		"prefix/keep.tmp": "old",
+		"prefixed":        "old",
+	})
+}
+
+func (s *S) TestDownload(c *C) {
+	for key, data := range map[string]string{
+		"prefix/a.jpg":     "a",
+		"prefix/sub/b.txt": "bb",
+		"prefix/folder/":   "",
+		"other":            "x",
+	} {
+		err := s.bucket.Put(key, []byte(data), "", s3.Private, s3.Options{})
+		c.Assert(err, IsNil)
+	}
+	s.writeFile(c, "extra.txt", "e")
+	s.writeFile(c, "sub/b.txt", "BB")
+
+	syncer := s3sync.New(s.bucket)
+	syncer.Include = []string{"*.txt"}
+	syncer.Delete = true
+	dir := filepath.Join(s.dir, "new")
+	result, err := syncer.Download("prefix", dir)
+	c.Assert(err, IsNil)
+	c.Assert(ops(result), DeepEquals, []string{"download sub/b.txt"})
+	data, err := ioutil.ReadFile(filepath.Join(dir, "sub", "b.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "bb")
+
+	resp, err := s.bucket.Head("prefix/sub/b.txt", nil)
+	c.Assert(err, IsNil)
+	mtime, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	c.Assert(err, IsNil)
+	info, err := os.Stat(filepath.Join(dir, "sub", "b.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(info.ModTime().Unix(), Equals, mtime.Unix())
+
+	syncer.Include = nil
+	result, err = syncer.Download("prefix", s.dir)
+	c.Assert(err, IsNil)
+	c.Assert(ops(result), DeepEquals, []string{
+		"download a.jpg",
+		"download sub/b.txt",
+		"delete extra.txt",
+		"delete new/sub/b.txt",
+	})
+	_, err = os.Stat(filepath.Join(s.dir, "extra.txt"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+	data, err = ioutil.ReadFile(filepath.Join(s.dir, "sub", "b.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "bb")
+
+	result, err = syncer.Download("prefix", s.dir)
+	c.Assert(err, IsNil)
+	c.Assert(result.Actions, HasLen, 0)
+	c.Assert(result.Unchanged, Equals, 2)
+}
+
+func (s *S) TestDownloadUnsafeKey(c *C) {
+	err := s.bucket.Put("prefix/../escape", []byte("x"), "", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	_, err = s3sync.New(s.bucket).Download("prefix", s.dir)
+	c.Assert(err, ErrorMatches, `s3sync: key "prefix/../escape" cannot be synced to a file`)
+}
@@ -1,6 +1,15 @@
 package s3_test
 
 import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
 	"github.com/goamz/goamz/aws"
 	"github.com/goamz/goamz/s3"
 	"github.com/goamz/goamz/s3/s3test"
@@ -12,10 +21,18 @@ type LocalServer struct {
 	region aws.Region
 	srv    *s3test.Server
 	config *s3test.Config
+	signer uint
 }
 
 func (s *LocalServer) SetUp(c *C) {
-	srv, err := s3test.NewServer(s.config)
+	// The server only accepts requests signed with these credentials.
+	s.auth = aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	config := s3test.Config{}
+	if s.config != nil {
+		config = *s.config
+	}
+	config.Auth = &s.auth
+	srv, err := s3test.NewServer(&config)
 	c.Assert(err, IsNil)
 	c.Assert(srv, NotNil)
 
@@ -24,6 +41,7 @@ func (s *LocalServer) SetUp(c *C) {
 		Name:                 "faux-region-1",
 		S3Endpoint:           srv.URL(),
 		S3LocationConstraint: true, // s3test server requires a LocationConstraint
+		S3Signer:             s.signer,
 	}
 }
 
@@ -47,14 +65,18 @@ var (
 			},
 		},
 	})
+	// and once more signing requests with Signature Version 4.
+	_ = Suite(&LocalServerSuite{
+		srv: LocalServer{
+			signer: aws.V4Signature,
+		},
+	})
 )
 
 func (s *LocalServerSuite) SetUpSuite(c *C) {
 	s.srv.SetUp(c)
 	s.clientTests.s3 = s3.New(s.srv.auth, s.srv.region)
 
-	// TODO Sadly the fake server ignores auth completely right now. :-(
-	s.clientTests.authIsBroken = true
 	s.clientTests.Cleanup()
 }
 
@@ -77,3 +99,365 @@ func (s *LocalServerSuite) TestBucketList(c *C) {
 func (s *LocalServerSuite) TestDoublePutBucket(c *C) {
 	s.clientTests.TestDoublePutBucket(c)
 }
+
+func (s *LocalServerSuite) TestLifecycleConfiguration(c *C) {
+	s.clientTests.TestLifecycleConfiguration(c)
+}
+
+func (s *LocalServerSuite) TestACL(c *C) {
+	s.clientTests.TestACL(c)
+}
+
+func (s *LocalServerSuite) TestBucketPolicy(c *C) {
+	s.clientTests.TestBucketPolicy(c)
+}
+
+func (s *LocalServerSuite) TestCORS(c *C) {
+	s.clientTests.TestCORS(c)
+}
+
+func (s *LocalServerSuite) TestNotification(c *C) {
+	s.clientTests.TestNotification(c)
+}
+
+func (s *LocalServerSuite) TestServerSideEncryption(c *C) {
+	s.clientTests.TestServerSideEncryption(c)
+}
+
+func (s *LocalServerSuite) TestDownloadSSECustomerKey(c *C) {
+	s.clientTests.TestDownloadSSECustomerKey(c)
+}
+
+func (s *LocalServerSuite) TestStorageClass(c *C) {
+	s.clientTests.TestStorageClass(c)
+}
+
+func (s *LocalServerSuite) TestTagging(c *C) {
+	s.clientTests.TestTagging(c)
+}
+
+func (s *LocalServerSuite) TestRestore(c *C) {
+	s.clientTests.TestRestore(c)
+}
+
+func (s *LocalServerSuite) TestGetRange(c *C) {
+	s.clientTests.TestGetRange(c)
+}
+
+func (s *LocalServerSuite) TestMultiInitPutList(c *C) {
+	s.clientTests.TestMultiInitPutList(c)
+}
+
+func (s *LocalServerSuite) TestMultiComplete(c *C) {
+	s.clientTests.TestMultiComplete(c)
+}
+
+func (s *LocalServerSuite) TestMultiPartCopy(c *C) {
+	s.clientTests.TestMultiPartCopy(c)
+}
+
+func (s *LocalServerSuite) TestListMulti(c *C) {
+	s.clientTests.TestListMulti(c)
+}
+
+func (s *LocalServerSuite) TestMultiMinPartSize(c *C) {
+	srv := LocalServer{config: &s3test.Config{MinPartSize: 4}}
+	srv.SetUp(c)
+	defer srv.srv.Quit()
+	b := s3.New(srv.auth, srv.region).Bucket("bucket")
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	multi, err := b.InitMulti("multi", "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+	parts, err := multi.PutAll(strings.NewReader("abcdefghi"), 4)
+	c.Assert(err, IsNil)
+	c.Assert(parts, HasLen, 3)
+	err = multi.Complete(parts)
+	c.Assert(err, IsNil)
+	data, err := b.Get("multi")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "abcdefghi")
+
+	multi, err = b.InitMulti("multi", "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+	parts, err = multi.PutAll(strings.NewReader("abcdefghi"), 3)
+	c.Assert(err, IsNil)
+	err = multi.Complete(parts)
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "EntityTooSmall")
+}
+
+func (s *LocalServerSuite) TestRestoreOngoing(c *C) {
+	srv := LocalServer{config: &s3test.Config{RestoreDelay: time.Hour}}
+	srv.SetUp(c)
+	defer srv.srv.Quit()
+	b := s3.New(srv.auth, srv.region).Bucket("bucket")
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	err = b.Put("archived", []byte("content"), "text/plain", s3.Private, s3.Options{StorageClass: s3.StorageClassGlacier})
+	c.Assert(err, IsNil)
+
+	err = b.RestoreObject("archived", 1, "")
+	c.Assert(err, IsNil)
+	status, err := b.RestoreStatus("archived")
+	c.Assert(err, IsNil)
+	c.Assert(status, DeepEquals, &s3.RestoreStatus{Ongoing: true})
+	_, err = b.Get("archived")
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "InvalidObjectState")
+
+	err = b.RestoreObject("archived", 1, "")
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "RestoreAlreadyInProgress")
+}
+
+func (s *LocalServerSuite) TestVersioning(c *C) {
+	s.clientTests.TestVersioning(c)
+}
+
+func (s *LocalServerSuite) TestAuthRejected(c *C) {
+	b := testBucket(s.clientTests.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	wrong := s3.New(aws.Auth{AccessKey: "abc", SecretKey: "wrong"}, s.srv.region)
+	_, err = wrong.Bucket(b.Name).Get("name")
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "SignatureDoesNotMatch")
+
+	unknown := s3.New(aws.Auth{AccessKey: "unknown", SecretKey: "123"}, s.srv.region)
+	_, err = unknown.Bucket(b.Name).Get("name")
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "InvalidAccessKeyId")
+
+	// The body of the error tells what should have been signed.
+	u := wrong.Bucket(b.Name).SignedURL("name", time.Now().Add(time.Hour))
+	resp, err := http.Get(u)
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, 403)
+	c.Assert(string(data), Matches, `(?s).*<Code>SignatureDoesNotMatch</Code>.*<StringToSign>.+</StringToSign>.*`)
+}
+
+func (s *LocalServerSuite) TestAuthAnonymous(c *C) {
+	b := testBucket(s.clientTests.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	err = b.Put("private", []byte("private"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+	err = b.Put("public", []byte("public"), "text/plain", s3.PublicRead, s3.Options{})
+	c.Assert(err, IsNil)
+
+	data, err := get(b.URL("public"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "public")
+
+	resp, err := http.Get(b.URL("private"))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, 403)
+
+	req, err := http.NewRequest("PUT", b.URL("public"), strings.NewReader("changed"))
+	c.Assert(err, IsNil)
+	resp, err = http.DefaultClient.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, 403)
+}
+
+func (s *LocalServerSuite) TestAuthSignedURL(c *C) {
+	b := testBucket(s.clientTests.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	err = b.Put("name", []byte("content"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	data, err := get(b.SignedURL("name", time.Now().Add(time.Hour)))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "content")
+
+	resp, err := http.Get(b.SignedURL("name", time.Now().Add(-time.Hour)))
+	c.Assert(err, IsNil)
+	data, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, 403)
+	c.Assert(string(data), Matches, `(?s).*<Message>Request has expired</Message>.*`)
+
+	// A URL signed for one object does not give access to another.
+	u := strings.Replace(b.SignedURL("name", time.Now().Add(time.Hour)), "/name?", "/other?", 1)
+	resp, err = http.Get(u)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, 403)
+}
+
+func (s *LocalServerSuite) TestPostFormArgs(c *C) {
+	b := testBucket(s.clientTests.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	post := func(fields map[string]string, action string) (int, string) {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		for name, value := range fields {
+			w.WriteField(name, value)
+		}
+		f, err := w.CreateFormFile("file", "file.txt")
+		c.Assert(err, IsNil)
+		f.Write([]byte("content"))
+		c.Assert(w.Close(), IsNil)
+		resp, err := http.Post(action, w.FormDataContentType(), &body)
+		c.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		c.Assert(err, IsNil)
+		return resp.StatusCode, string(data)
+	}
+
+	action, fields := b.PostFormArgs("uploads/name", time.Now().Add(time.Hour), "")
+	status, _ := post(fields, action)
+	c.Assert(status, Equals, 204)
+	data, err := b.Get("uploads/name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "content")
+
+	fields["key"] = "uploads/other"
+	status, body := post(fields, action)
+	c.Assert(status, Equals, 403)
+	c.Assert(body, Matches, `(?s).*Policy Condition failed.*`)
+
+	action, fields = b.PostFormArgs("uploads/name", time.Now().Add(-time.Hour), "")
+	status, body = post(fields, action)
+	c.Assert(status, Equals, 403)
+	c.Assert(body, Matches, `(?s).*Policy expired.*`)
+
+	action, fields = b.PostFormArgs("uploads/name", time.Now().Add(time.Hour), "")
+	fields["signature"] = "wrong"
+	status, body = post(fields, action)
+	c.Assert(status, Equals, 403)
+	c.Assert(body, Matches, `(?s).*<Code>SignatureDoesNotMatch</Code>.*`)
+}
+
+func (s *LocalServerSuite) TestDir(c *C) {
+	dir := c.MkDir()
+	srv := LocalServer{config: &s3test.Config{Dir: dir}}
+	srv.SetUp(c)
+	b := s3.New(srv.auth, srv.region).Bucket("bucket")
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	err = b.Put("dir/name", []byte("content"), "text/plain", s3.Private, s3.Options{Meta: map[string][]string{"Key": {"value"}}})
+	c.Assert(err, IsNil)
+	err = b.PutTagging("dir/name", []s3.Tag{{Key: "k", Value: "v"}})
+	c.Assert(err, IsNil)
+	err = b.Put("gone", []byte("gone"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+	err = b.Del("gone")
+	c.Assert(err, IsNil)
+
+	vb := s3.New(srv.auth, srv.region).Bucket("versioned")
+	err = vb.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	err = vb.PutBucketVersioning(&s3.VersioningConfiguration{Status: s3.VersioningEnabled})
+	c.Assert(err, IsNil)
+	err = vb.Put("name", []byte("v1"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+	err = vb.Put("name", []byte("v2"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+	err = vb.Del("name")
+	c.Assert(err, IsNil)
+	srv.srv.Quit()
+
+	// Objects are kept as files.
+	data, err := ioutil.ReadFile(filepath.Join(dir, "bucket", "objects", "dir%2Fname"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "content")
+	_, err = os.Stat(filepath.Join(dir, "bucket", "objects", "gone"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	srv.SetUp(c)
+	defer srv.srv.Quit()
+	b = s3.New(srv.auth, srv.region).Bucket("bucket")
+	resp, err := b.GetResponse("dir/name")
+	c.Assert(err, IsNil)
+	data, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "content")
+	c.Assert(resp.Header.Get("x-amz-meta-key"), Equals, "value")
+	tags, err := b.GetTagging("dir/name")
+	c.Assert(err, IsNil)
+	c.Assert(tags, DeepEquals, []s3.Tag{{Key: "k", Value: "v"}})
+	_, err = b.Get("gone")
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "NoSuchKey")
+
+	vb = s3.New(srv.auth, srv.region).Bucket("versioned")
+	versions, err := vb.Versions("", "", "", "", 0)
+	c.Assert(err, IsNil)
+	c.Assert(versions.Versions, HasLen, 2)
+	c.Assert(versions.DeleteMarkers, HasLen, 1)
+	data, err = vb.GetVersion("name", versions.Versions[1].VersionId)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "v1")
+
+	// New versions follow the ones loaded.
+	err = vb.Put("name", []byte("v3"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+	versions, err = vb.Versions("", "", "", "", 0)
+	c.Assert(err, IsNil)
+	c.Assert(versions.Versions, HasLen, 3)
+	c.Assert(versions.Versions[0].VersionId > versions.DeleteMarkers[0].VersionId, Equals, true)
+}
+
+func (s *LocalServerSuite) TestSnapshot(c *C) {
+	srv := LocalServer{}
+	srv.SetUp(c)
+	defer srv.srv.Quit()
+	b := s3.New(srv.auth, srv.region).Bucket("bucket")
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	err = b.Put("name", []byte("content"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	snapshot := filepath.Join(c.MkDir(), "snapshot")
+	err = srv.srv.Snapshot(snapshot)
+	c.Assert(err, IsNil)
+	err = srv.srv.Snapshot(snapshot)
+	c.Assert(err, ErrorMatches, `snapshot directory ".*" is not empty`)
+
+	err = b.Del("name")
+	c.Assert(err, IsNil)
+	err = b.Put("other", []byte("other"), "text/plain", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+	err = s3.New(srv.auth, srv.region).Bucket("another").PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	err = srv.srv.Restore(snapshot)
+	c.Assert(err, IsNil)
+	data, err := b.Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "content")
+	_, err = b.Get("other")
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "NoSuchKey")
+	_, err = s3.New(srv.auth, srv.region).Bucket("another").List("", "", "", 0)
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "NoSuchBucket")
+
+	// A snapshot can be restored by a server keeping its buckets on disk.
+	dir := c.MkDir()
+	dsrv := LocalServer{config: &s3test.Config{Dir: dir}}
+	dsrv.SetUp(c)
+	err = dsrv.srv.Restore(snapshot)
+	c.Assert(err, IsNil)
+	dsrv.srv.Quit()
+	dsrv.SetUp(c)
+	defer dsrv.srv.Quit()
+	data, err = s3.New(dsrv.auth, dsrv.region).Bucket("bucket").Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "content")
+}
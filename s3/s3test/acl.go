@@ -0,0 +1,135 @@
+package s3test
+
+import (
+	"encoding/xml"
+
+	"github.com/goamz/goamz/s3"
+)
+
+// owner owns all the buckets and objects of the server.
+var owner = s3.Owner{
+	ID:          "8a6925ce4adf588a4532f9d9b1ea2d9d0e7ba6a9f7d5f5e3b7c4ee8d9d6a1f0c",
+	DisplayName: "s3test",
+}
+
+// cannedACL returns the access control policy the canned ACL acl stands for.
+// http://docs.aws.amazon.com/AmazonS3/latest/dev/acl-overview.html#canned-acl
+func cannedACL(acl s3.ACL) *s3.AccessControlPolicy {
+	p := &s3.AccessControlPolicy{Owner: owner}
+	grant := func(grantee s3.Grantee, perms ...string) {
+		for _, perm := range perms {
+			p.Grants = append(p.Grants, s3.Grant{Grantee: grantee, Permission: perm})
+		}
+	}
+	grant(s3.Grantee{Type: s3.GranteeCanonicalUser, ID: owner.ID, DisplayName: owner.DisplayName}, s3.PermissionFullControl)
+	allUsers := s3.Grantee{Type: s3.GranteeGroup, URI: s3.AllUsersGroup}
+	switch acl {
+	case s3.PublicRead:
+		grant(allUsers, s3.PermissionRead)
+	case s3.PublicReadWrite:
+		grant(allUsers, s3.PermissionRead, s3.PermissionWrite)
+	case s3.AuthenticatedRead:
+		grant(s3.Grantee{Type: s3.GranteeGroup, URI: s3.AuthenticatedUsersGroup}, s3.PermissionRead)
+	}
+	return p
+}
+
+var permissions = map[string]bool{
+	s3.PermissionFullControl: true,
+	s3.PermissionRead:        true,
+	s3.PermissionWrite:       true,
+	s3.PermissionReadACP:     true,
+	s3.PermissionWriteACP:    true,
+}
+
+var groups = map[string]bool{
+	s3.AllUsersGroup:           true,
+	s3.AuthenticatedUsersGroup: true,
+	s3.LogDeliveryGroup:        true,
+}
+
+// readACL returns the access control policy a PUT acl request sets, given
+// either as a canned ACL in the x-amz-acl header or in the body.
+func readACL(a *action) *s3.AccessControlPolicy {
+	if acl := a.req.Header.Get("x-amz-acl"); acl != "" {
+		return cannedACL(s3.ACL(acl))
+	}
+	var p s3.AccessControlPolicy
+	if err := xml.Unmarshal(readBody(a), &p); err != nil || p.Owner.ID == "" {
+		fatalf(400, "MalformedACLError", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	if p.Owner.ID != owner.ID {
+		fatalf(403, "AccessDenied", "Access Denied")
+	}
+	for _, g := range p.Grants {
+		if !permissions[g.Permission] {
+			fatalf(400, "MalformedACLError", "The XML you provided was not well-formed or did not validate against our published schema")
+		}
+		switch g.Grantee.Type {
+		case s3.GranteeCanonicalUser:
+			if g.Grantee.ID == "" {
+				fatalf(400, "InvalidArgument", "Invalid id")
+			}
+		case s3.GranteeEmail:
+			if g.Grantee.EmailAddress == "" {
+				fatalf(400, "UnresolvableGrantByEmailAddress", "The e-mail address you provided does not match any account on record.")
+			}
+		case s3.GranteeGroup:
+			if !groups[g.Grantee.URI] {
+				fatalf(400, "InvalidArgument", "Invalid group uri")
+			}
+		default:
+			fatalf(400, "MalformedACLError", "The XML you provided was not well-formed or did not validate against our published schema")
+		}
+	}
+	p.Owner.DisplayName = owner.DisplayName
+	return &p
+}
+
+// aclResource is the access control policy of a bucket.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUTacl.html
+type aclResource struct {
+	bucketResource
+}
+
+func (r aclResource) put(a *action) interface{} {
+	b := r.existingBucket()
+	b.acp = readACL(a)
+	return nil
+}
+
+func (r aclResource) get(a *action) interface{} {
+	return r.existingBucket().acp
+}
+
+func (r aclResource) delete(a *action) interface{} {
+	return notAllowed()
+}
+
+func (r aclResource) post(a *action) interface{} {
+	return notAllowed()
+}
+
+// objectACLResource is the access control policy of an object.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPUTacl.html
+type objectACLResource struct {
+	objectResource
+}
+
+func (r objectACLResource) put(a *action) interface{} {
+	obj := r.lookup(a)
+	obj.acp = readACL(a)
+	return nil
+}
+
+func (r objectACLResource) get(a *action) interface{} {
+	return r.lookup(a).acp
+}
+
+func (r objectACLResource) delete(a *action) interface{} {
+	return notAllowed()
+}
+
+func (r objectACLResource) post(a *action) interface{} {
+	return notAllowed()
+}
@@ -0,0 +1,498 @@
+package s3test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goamz/goamz/aws"
+	"github.com/goamz/goamz/s3"
+)
+
+// maxClockSkew is how far the time requests are signed at may be from
+// the time they are received.
+const maxClockSkew = 15 * time.Minute
+
+// v2Subresources holds the query parameters part of the resource signed
+// with AWS signature version 2.
+// http://docs.aws.amazon.com/AmazonS3/latest/dev/RESTAuthentication.html#ConstructingTheCanonicalizedResourceElement
+var v2Subresources = map[string]bool{
+	"acl":                          true,
+	"cors":                         true,
+	"delete":                       true,
+	"lifecycle":                    true,
+	"location":                     true,
+	"logging":                      true,
+	"notification":                 true,
+	"partNumber":                   true,
+	"policy":                       true,
+	"requestPayment":               true,
+	"restore":                      true,
+	"tagging":                      true,
+	"torrent":                      true,
+	"uploadId":                     true,
+	"uploads":                      true,
+	"versionId":                    true,
+	"versioning":                   true,
+	"versions":                     true,
+	"website":                      true,
+	"response-content-type":        true,
+	"response-content-language":    true,
+	"response-expires":             true,
+	"response-cache-control":       true,
+	"response-content-disposition": true,
+	"response-content-encoding":    true,
+}
+
+// authenticate fails unless the request is signed with the credentials
+// of the server, if it has any. It returns whether the request is
+// anonymous, to be checked by checkAnonymous.
+func (srv *Server) authenticate(a *action) (anonymous bool) {
+	auth := srv.config.auth()
+	if auth == nil {
+		return false
+	}
+	req := a.req
+	q := req.URL.Query()
+	switch h := req.Header.Get("Authorization"); {
+	case strings.HasPrefix(h, "AWS4-HMAC-SHA256 "):
+		checkV4Header(a, auth, h[len("AWS4-HMAC-SHA256 "):])
+	case strings.HasPrefix(h, "AWS "):
+		checkV2Header(a, auth, h[len("AWS "):])
+	case h != "":
+		fatalf(400, "InvalidArgument", "Unsupported Authorization Type")
+	case q.Get("X-Amz-Algorithm") != "":
+		checkV4Query(a, auth)
+	case q.Get("Signature") != "" || q.Get("AWSAccessKeyId") != "":
+		checkV2Query(a, auth)
+	default:
+		return true
+	}
+	return false
+}
+
+// checkAnonymous fails unless the anonymous request on r is allowed:
+// only reading buckets and objects granted to everyone is, along with
+// POST uploads, whose policy is checked by postObject.
+func checkAnonymous(a *action, r resource) {
+	read := a.req.Method == "GET" || a.req.Method == "HEAD"
+	switch r := r.(type) {
+	case objectResource:
+		if read && r.object != nil && r.version == "" && grantedToAll(r.object.acp, s3.PermissionRead) {
+			return
+		}
+	case bucketResource:
+		if read && r.bucket != nil && grantedToAll(r.bucket.acp, s3.PermissionRead) {
+			return
+		}
+		if a.req.Method == "POST" && strings.HasPrefix(a.req.Header.Get("Content-Type"), "multipart/form-data") {
+			return
+		}
+	}
+	fatalf(403, "AccessDenied", "Access Denied")
+}
+
+// grantedToAll reports whether p grants perm to everyone.
+func grantedToAll(p *s3.AccessControlPolicy, perm string) bool {
+	if p == nil {
+		return false
+	}
+	for _, g := range p.Grants {
+		if g.Grantee.Type == s3.GranteeGroup && g.Grantee.URI == s3.AllUsersGroup &&
+			(g.Permission == perm || g.Permission == s3.PermissionFullControl) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAccessKey fails unless accessKey is the access key of auth.
+func checkAccessKey(auth *aws.Auth, accessKey string) {
+	if accessKey != auth.AccessKey {
+		panic(&s3Error{
+			statusCode:     403,
+			Code:           "InvalidAccessKeyId",
+			Message:        "The AWS Access Key Id you provided does not exist in our records.",
+			AWSAccessKeyId: accessKey,
+		})
+	}
+}
+
+// checkSignature fails unless the signature provided is the one computed
+// from stringToSign and, with signature version 4, canonicalRequest.
+func checkSignature(accessKey, stringToSign, canonicalRequest, provided, computed string) {
+	if hmac.Equal([]byte(provided), []byte(computed)) {
+		return
+	}
+	err := &s3Error{
+		statusCode:        403,
+		Code:              "SignatureDoesNotMatch",
+		Message:           "The request signature we calculated does not match the signature you provided. Check your key and signing method.",
+		AWSAccessKeyId:    accessKey,
+		StringToSign:      stringToSign,
+		SignatureProvided: provided,
+		StringToSignBytes: spacedHex(stringToSign),
+	}
+	if canonicalRequest != "" {
+		err.CanonicalRequest = canonicalRequest
+		err.CanonicalRequestBytes = spacedHex(canonicalRequest)
+	}
+	panic(err)
+}
+
+// spacedHex returns the bytes of s in hexadecimal, separated by spaces.
+func spacedHex(s string) string {
+	h := make([]string, len(s))
+	for i := range h {
+		h[i] = hex.EncodeToString([]byte{s[i]})
+	}
+	return strings.Join(h, " ")
+}
+
+// checkRequestTime fails if t, the time the request was signed at, is
+// too far from now.
+func checkRequestTime(t time.Time) {
+	if d := time.Since(t); d > maxClockSkew || d < -maxClockSkew {
+		fatalf(403, "RequestTimeTooSkewed", "The difference between the request time and the current time is too large.")
+	}
+}
+
+// parseDate parses the value of a Date or x-amz-date header.
+func parseDate(s string) (time.Time, bool) {
+	for _, layout := range []string{http.TimeFormat, time.RFC1123, time.RFC1123Z, aws.ISO8601BasicFormat} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// stringToSignV2 returns the string signed with AWS signature version 2
+// for the request, which is dated date.
+// http://docs.aws.amazon.com/AmazonS3/latest/dev/RESTAuthentication.html
+func stringToSignV2(req *http.Request, date string) string {
+	var amz []string
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, "x-amz-") {
+			amz = append(amz, k+":"+strings.Join(v, ",")+"\n")
+		}
+	}
+	sort.Strings(amz)
+	var sub []string
+	for k, vs := range req.URL.Query() {
+		if !v2Subresources[k] {
+			continue
+		}
+		for _, v := range vs {
+			if v == "" {
+				sub = append(sub, k)
+			} else {
+				sub = append(sub, k+"="+v)
+			}
+		}
+	}
+	sort.Strings(sub)
+	resource := req.URL.EscapedPath()
+	if len(sub) > 0 {
+		resource += "?" + strings.Join(sub, "&")
+	}
+	return req.Method + "\n" +
+		req.Header.Get("Content-MD5") + "\n" +
+		req.Header.Get("Content-Type") + "\n" +
+		date + "\n" +
+		strings.Join(amz, "") + resource
+}
+
+// signV2 returns the AWS signature version 2 of stringToSign with the
+// secret key of auth.
+func signV2(auth *aws.Auth, stringToSign string) string {
+	h := hmac.New(sha1.New, []byte(auth.SecretKey))
+	h.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// checkV2Header checks the AWS signature version 2 given by the
+// Authorization header, whose value after the "AWS " prefix is h.
+func checkV2Header(a *action, auth *aws.Auth, h string) {
+	i := strings.LastIndex(h, ":")
+	if i < 0 {
+		fatalf(400, "InvalidArgument", "AWS authorization header is invalid.  Expected AwsAccessKeyId:signature")
+	}
+	accessKey, signature := h[:i], h[i+1:]
+	checkAccessKey(auth, accessKey)
+	date := a.req.Header.Get("x-amz-date")
+	if date == "" {
+		date = a.req.Header.Get("Date")
+	}
+	t, ok := parseDate(date)
+	if !ok {
+		fatalf(403, "AccessDenied", "AWS authentication requires a valid Date or x-amz-date header")
+	}
+	checkRequestTime(t)
+	if a.req.Header.Get("x-amz-date") != "" {
+		date = ""
+	}
+	sts := stringToSignV2(a.req, date)
+	checkSignature(accessKey, sts, "", signature, signV2(auth, sts))
+}
+
+// checkV2Query checks the AWS signature version 2 given by the query
+// parameters of a presigned URL.
+func checkV2Query(a *action, auth *aws.Auth) {
+	q := a.req.URL.Query()
+	accessKey, expires, signature := q.Get("AWSAccessKeyId"), q.Get("Expires"), q.Get("Signature")
+	if accessKey == "" || expires == "" || signature == "" {
+		fatalf(403, "AccessDenied", "Query-string authentication requires the Signature, Expires and AWSAccessKeyId parameters")
+	}
+	checkAccessKey(auth, accessKey)
+	secs, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		fatalf(403, "AccessDenied", "Invalid date (should be seconds since epoch): %s", expires)
+	}
+	if time.Now().After(time.Unix(secs, 0)) {
+		fatalf(403, "AccessDenied", "Request has expired")
+	}
+	sts := stringToSignV2(a.req, expires)
+	checkSignature(accessKey, sts, "", signature, signV2(auth, sts))
+}
+
+// v4Scope is the scope of an AWS signature version 4.
+type v4Scope struct {
+	accessKey string
+	date      string // as "YYYYMMDD".
+	region    string
+}
+
+// parseCredential parses the credential of an AWS signature version 4,
+// "access-key/date/region/s3/aws4_request".
+func parseCredential(credential string) (v4Scope, bool) {
+	f := strings.Split(credential, "/")
+	if len(f) != 5 || f[3] != "s3" || f[4] != "aws4_request" {
+		return v4Scope{}, false
+	}
+	return v4Scope{f[0], f[1], f[2]}, true
+}
+
+func (s v4Scope) String() string {
+	return s.date + "/" + s.region + "/s3/aws4_request"
+}
+
+// signingKey returns the key derived from the secret key of auth that
+// signatures of scope s are computed with.
+func (s v4Scope) signingKey(auth *aws.Auth) []byte {
+	key := hmacSHA256([]byte("AWS4"+auth.SecretKey), s.date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// uriEncode encodes s as done in AWS signature version 4, leaving
+// slashes as they are unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalRequestV4 returns the canonical request of AWS signature
+// version 4 for the request, given the headers signed and the hash of
+// the payload. The X-Amz-Signature query parameter is left out.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func canonicalRequestV4(req *http.Request, signedHeaders []string, payloadHash string) string {
+	var query []string
+	for k, vs := range req.URL.Query() {
+		if k == "X-Amz-Signature" {
+			continue
+		}
+		for _, v := range vs {
+			query = append(query, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	sort.Strings(query)
+	var headers []string
+	for _, name := range signedHeaders {
+		var value string
+		switch name {
+		case "host":
+			value = req.Host
+		case "content-length":
+			value = strconv.FormatInt(req.ContentLength, 10)
+		default:
+			var vs []string
+			for _, v := range req.Header[http.CanonicalHeaderKey(name)] {
+				vs = append(vs, strings.Join(strings.Fields(v), " "))
+			}
+			value = strings.Join(vs, ",")
+		}
+		headers = append(headers, name+":"+value+"\n")
+	}
+	return req.Method + "\n" +
+		uriEncode(req.URL.Path, false) + "\n" +
+		strings.Join(query, "&") + "\n" +
+		strings.Join(headers, "") + "\n" +
+		strings.Join(signedHeaders, ";") + "\n" +
+		payloadHash
+}
+
+// stringToSignV4 returns the string signed with AWS signature version 4
+// for a request of canonical request creq, signed at date.
+func stringToSignV4(date string, scope v4Scope, creq string) string {
+	return "AWS4-HMAC-SHA256\n" + date + "\n" + scope.String() + "\n" + sha256Hex([]byte(creq))
+}
+
+// checkV4Header checks the AWS signature version 4 given by the
+// Authorization header, whose value after the algorithm is h.
+func checkV4Header(a *action, auth *aws.Auth, h string) {
+	fields := make(map[string]string)
+	for _, f := range strings.Split(h, ",") {
+		f = strings.TrimSpace(f)
+		if i := strings.Index(f, "="); i >= 0 {
+			fields[f[:i]] = f[i+1:]
+		}
+	}
+	scope, ok := parseCredential(fields["Credential"])
+	if !ok || fields["SignedHeaders"] == "" || fields["Signature"] == "" {
+		fatalf(400, "AuthorizationHeaderMalformed", "The authorization header is malformed; the authorization header requires three components: Credential, SignedHeaders, and Signature.")
+	}
+	checkAccessKey(auth, scope.accessKey)
+	date := a.req.Header.Get("x-amz-date")
+	t, err := time.Parse(aws.ISO8601BasicFormat, date)
+	if err != nil {
+		fatalf(403, "AccessDenied", "AWS authentication requires a valid Date or x-amz-date header")
+	}
+	if t.Format(aws.ISO8601BasicFormatShort) != scope.date {
+		fatalf(400, "AuthorizationHeaderMalformed", "The authorization header is malformed; Invalid credential date. Date is not the same as X-Amz-Date.")
+	}
+	checkRequestTime(t)
+	payloadHash := a.req.Header.Get("x-amz-content-sha256")
+	if payloadHash == "" {
+		fatalf(400, "InvalidRequest", "Missing required header for this request: x-amz-content-sha256")
+	}
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	creq := canonicalRequestV4(a.req, signedHeaders, payloadHash)
+	sts := stringToSignV4(date, scope, creq)
+	key := scope.signingKey(auth)
+	signature := hex.EncodeToString(hmacSHA256(key, sts))
+	checkSignature(scope.accessKey, sts, creq, fields["Signature"], signature)
+
+	switch payloadHash {
+	case aws.UnsignedPayload:
+	case aws.StreamingPayload:
+		body := readAll(a)
+		checkChunks(body, date, scope, key, signature)
+	default:
+		if sha256Hex(readAll(a)) != payloadHash {
+			fatalf(400, "XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed.")
+		}
+	}
+}
+
+// readAll reads the body of the request, which may then be read again.
+func readAll(a *action) []byte {
+	data, err := ioutil.ReadAll(a.req.Body)
+	if err != nil {
+		fatalf(400, "TODO", "read error")
+	}
+	a.req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return data
+}
+
+// checkChunks checks the signatures of the chunks of body, sent with the
+// aws-chunked content encoding, each signed with the signature of the
+// previous one, starting from the signature of the request, seed.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html
+func checkChunks(body []byte, date string, scope v4Scope, key []byte, seed string) {
+	prev := seed
+	emptyHash := sha256Hex(nil)
+	for {
+		i := bytes.Index(body, []byte("\r\n"))
+		if i < 0 {
+			fatalf(400, "IncompleteBody", "The request body terminated unexpectedly")
+		}
+		header := string(body[:i])
+		j := strings.Index(header, ";chunk-signature=")
+		if j < 0 {
+			fatalf(400, "IncompleteBody", "The request body terminated unexpectedly")
+		}
+		n, err := strconv.ParseInt(header[:j], 16, 64)
+		if err != nil || n < 0 || int64(len(body)-i-2) < n+2 {
+			fatalf(400, "IncompleteBody", "The request body terminated unexpectedly")
+		}
+		data := body[i+2 : i+2+int(n)]
+		sts := "AWS4-HMAC-SHA256-PAYLOAD\n" + date + "\n" + scope.String() + "\n" + prev + "\n" + emptyHash + "\n" + sha256Hex(data)
+		signature := hex.EncodeToString(hmacSHA256(key, sts))
+		checkSignature(scope.accessKey, sts, "", header[j+len(";chunk-signature="):], signature)
+		if n == 0 {
+			return
+		}
+		prev = signature
+		body = body[i+2+int(n)+2:]
+	}
+}
+
+// checkV4Query checks the AWS signature version 4 given by the query
+// parameters of a presigned URL.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+func checkV4Query(a *action, auth *aws.Auth) {
+	q := a.req.URL.Query()
+	if q.Get("X-Amz-Algorithm") != "AWS4-HMAC-SHA256" {
+		fatalf(400, "AuthorizationQueryParametersError", "X-Amz-Algorithm only supports \"AWS4-HMAC-SHA256\"")
+	}
+	scope, ok := parseCredential(q.Get("X-Amz-Credential"))
+	date := q.Get("X-Amz-Date")
+	t, err := time.Parse(aws.ISO8601BasicFormat, date)
+	expires, err2 := strconv.Atoi(q.Get("X-Amz-Expires"))
+	if !ok || err != nil || err2 != nil || q.Get("X-Amz-SignedHeaders") == "" || q.Get("X-Amz-Signature") == "" {
+		fatalf(400, "AuthorizationQueryParametersError", "Query-string authentication version 4 requires the X-Amz-Algorithm, X-Amz-Credential, X-Amz-Signature, X-Amz-Date, X-Amz-SignedHeaders, and X-Amz-Expires parameters.")
+	}
+	if expires < 0 {
+		fatalf(400, "AuthorizationQueryParametersError", "X-Amz-Expires must be non-negative")
+	}
+	if expires > 7*24*60*60 {
+		fatalf(400, "AuthorizationQueryParametersError", "X-Amz-Expires must be less than a week (in seconds) that is 604800")
+	}
+	checkAccessKey(auth, scope.accessKey)
+	if t.Format(aws.ISO8601BasicFormatShort) != scope.date {
+		fatalf(400, "AuthorizationQueryParametersError", "Invalid credential date. Date is not the same as X-Amz-Date.")
+	}
+	if time.Now().After(t.Add(time.Duration(expires) * time.Second)) {
+		fatalf(403, "AccessDenied", "Request has expired")
+	}
+	signedHeaders := strings.Split(q.Get("X-Amz-SignedHeaders"), ";")
+	creq := canonicalRequestV4(a.req, signedHeaders, aws.UnsignedPayload)
+	sts := stringToSignV4(date, scope, creq)
+	signature := hex.EncodeToString(hmacSHA256(scope.signingKey(auth), sts))
+	checkSignature(scope.accessKey, sts, creq, q.Get("X-Amz-Signature"), signature)
+}
@@ -0,0 +1,65 @@
+// Command s3test runs the fake S3 server of the s3test package:
+//
+//	s3test [flags]
+//
+// Buckets are kept in memory, unless -dir is given, and last as long as
+// the server does. Requests are not authenticated unless -access-key is
+// given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/goamz/goamz/aws"
+	"github.com/goamz/goamz/s3/s3test"
+)
+
+var (
+	addr         = flag.String("addr", "localhost:4568", "address to listen on")
+	dir          = flag.String("dir", "", "directory to keep the buckets in")
+	restore      = flag.String("restore", "", "snapshot directory to load the buckets from")
+	accessKey    = flag.String("access-key", "", "access key requests must be signed with")
+	secretKey    = flag.String("secret-key", "", "secret key requests must be signed with")
+	conflict     = flag.Bool("409", false, "fail to create existing buckets, as regions other than us-east-1 do")
+	restoreDelay = flag.Duration("restore-delay", 0, "how long restoring objects from Glacier takes")
+	minPartSize  = flag.Int64("min-part-size", 0, "minimum size of the parts of multipart uploads, instead of 5MB")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: s3test [flags]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("s3test: ")
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config := &s3test.Config{
+		Addr:            *addr,
+		Dir:             *dir,
+		Send409Conflict: *conflict,
+		RestoreDelay:    *restoreDelay,
+		MinPartSize:     *minPartSize,
+	}
+	if *accessKey != "" {
+		config.Auth = &aws.Auth{AccessKey: *accessKey, SecretKey: *secretKey}
+	}
+	srv, err := s3test.NewServer(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *restore != "" {
+		if err := srv.Restore(*restore); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Println(srv.URL())
+	select {}
+}
@@ -0,0 +1,58 @@
+package s3test
+
+import (
+	"encoding/xml"
+
+	"github.com/goamz/goamz/s3"
+)
+
+var corsMethods = map[string]bool{
+	"GET":    true,
+	"PUT":    true,
+	"POST":   true,
+	"DELETE": true,
+	"HEAD":   true,
+}
+
+// corsResource is the CORS configuration of a bucket.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUTcors.html
+type corsResource struct {
+	bucketResource
+}
+
+func (r corsResource) put(a *action) interface{} {
+	b := r.existingBucket()
+	var c s3.CORSConfiguration
+	if err := xml.Unmarshal(readBody(a), &c); err != nil || len(c.Rules) == 0 || len(c.Rules) > 100 {
+		fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	for _, rule := range c.Rules {
+		if len(rule.AllowedOrigins) == 0 || len(rule.AllowedMethods) == 0 {
+			fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+		}
+		for _, m := range rule.AllowedMethods {
+			if !corsMethods[m] {
+				fatalf(400, "InvalidRequest", "Found unsupported HTTP method in CORS config. Unsupported method is %s", m)
+			}
+		}
+	}
+	b.cors = &c
+	return nil
+}
+
+func (r corsResource) get(a *action) interface{} {
+	b := r.existingBucket()
+	if b.cors == nil {
+		fatalf(404, "NoSuchCORSConfiguration", "The CORS configuration does not exist")
+	}
+	return b.cors
+}
+
+func (r corsResource) delete(a *action) interface{} {
+	r.existingBucket().cors = nil
+	return nil
+}
+
+func (r corsResource) post(a *action) interface{} {
+	return notAllowed()
+}
@@ -0,0 +1,476 @@
+package s3test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goamz/goamz/s3"
+)
+
+// The buckets of a server with a Config.Dir, and those of snapshots, are
+// kept in a directory holding one directory per bucket, laid out as:
+//
+//	BUCKET/bucket.json           the configuration of the bucket
+//	BUCKET/objects/KEY           the content of unversioned objects
+//	BUCKET/versions/KEY/VERSION  the content of versioned objects
+//	BUCKET/meta/KEY.json         the metadata of all the versions of KEY
+//
+// where KEY is the object key, escaped to a single file name.
+
+// bucketInfo is the configuration of a bucket, as kept on disk.
+type bucketInfo struct {
+	ACL          s3.ACL                        `json:",omitempty"`
+	ACP          *s3.AccessControlPolicy       `json:",omitempty"`
+	Policy       string                        `json:",omitempty"`
+	Versioning   string                        `json:",omitempty"`
+	Lifecycle    *s3.LifecycleConfiguration    `json:",omitempty"`
+	CORS         *s3.CORSConfiguration         `json:",omitempty"`
+	Notification *s3.NotificationConfiguration `json:",omitempty"`
+}
+
+// objectInfo is the metadata of a version of an object, as kept on disk.
+type objectInfo struct {
+	Version       string `json:",omitempty"`
+	DeleteMarker  bool   `json:",omitempty"`
+	LastModified  time.Time
+	MD5           string                  `json:",omitempty"`
+	Parts         int                     `json:",omitempty"`
+	Meta          http.Header             `json:",omitempty"`
+	ACP           *s3.AccessControlPolicy `json:",omitempty"`
+	Encryption    *s3.Encryption          `json:",omitempty"`
+	StorageClass  string                  `json:",omitempty"`
+	Tags          []s3.Tag                `json:",omitempty"`
+	RestoreDone   *time.Time              `json:",omitempty"`
+	RestoreExpiry *time.Time              `json:",omitempty"`
+}
+
+// fileName returns the name of the files of the object with the given key.
+func fileName(key string) string {
+	name := url.PathEscape(key)
+	if name == "." || name == ".." {
+		name = strings.Replace(name, ".", "%2E", -1)
+	}
+	return name
+}
+
+// diskStore keeps buckets in a directory, writing only what changed since
+// they were last saved.
+type diskStore struct {
+	dir   string
+	saved map[string]*savedBucket
+}
+
+// savedBucket is what was last saved of a bucket.
+type savedBucket struct {
+	b    *bucket
+	info []byte
+	hist map[string][]*object
+}
+
+func newDiskStore(dir string) *diskStore {
+	return &diskStore{
+		dir:   dir,
+		saved: make(map[string]*savedBucket),
+	}
+}
+
+// loaded records that buckets were just loaded from the store.
+func (st *diskStore) loaded(buckets map[string]*bucket) {
+	for name, b := range buckets {
+		sb := &savedBucket{
+			b:    b,
+			hist: make(map[string][]*object),
+		}
+		for key := range b.objects {
+			sb.hist[key] = append([]*object(nil), b.history(key)...)
+		}
+		for key := range b.versions {
+			sb.hist[key] = append([]*object(nil), b.history(key)...)
+		}
+		st.saved[name] = sb
+	}
+}
+
+// save writes the changes made to buckets since the last time they were
+// saved. The metadata of the named object of bucket b is written even if
+// its versions are the same, as it may have been changed in place.
+func (st *diskStore) save(buckets map[string]*bucket, b *bucket, name string) error {
+	for bname, sb := range st.saved {
+		if buckets[bname] != sb.b {
+			if err := os.RemoveAll(filepath.Join(st.dir, bname)); err != nil {
+				return err
+			}
+			delete(st.saved, bname)
+		}
+	}
+	for bname, bucket := range buckets {
+		sb := st.saved[bname]
+		if sb == nil {
+			sb = &savedBucket{
+				b:    bucket,
+				hist: make(map[string][]*object),
+			}
+			st.saved[bname] = sb
+		}
+		changed := ""
+		if bucket == b {
+			changed = name
+		}
+		if err := st.saveBucket(sb, changed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (st *diskStore) saveBucket(sb *savedBucket, changed string) error {
+	b := sb.b
+	dir := filepath.Join(st.dir, b.name)
+	info, err := json.MarshalIndent(&bucketInfo{
+		ACL:          b.acl,
+		ACP:          b.acp,
+		Policy:       string(b.policy),
+		Versioning:   b.versioning,
+		Lifecycle:    b.lifecycle,
+		CORS:         b.cors,
+		Notification: b.notification,
+	}, "", "\t")
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(info, sb.info) {
+		if err := writeFile(filepath.Join(dir, "bucket.json"), info); err != nil {
+			return err
+		}
+		sb.info = info
+	}
+	names := make(map[string]bool)
+	for name := range b.objects {
+		names[name] = true
+	}
+	for name := range b.versions {
+		names[name] = true
+	}
+	for name := range sb.hist {
+		names[name] = true
+	}
+	for name := range names {
+		hist := b.history(name)
+		if name != changed && sameObjects(hist, sb.hist[name]) {
+			continue
+		}
+		if err := st.saveObject(dir, name, hist, sb.hist[name]); err != nil {
+			return err
+		}
+		if len(hist) == 0 {
+			delete(sb.hist, name)
+		} else {
+			sb.hist[name] = append([]*object(nil), hist...)
+		}
+	}
+	return nil
+}
+
+// saveObject writes the versions hist of the named object, which replace
+// the versions old that were last saved.
+func (st *diskStore) saveObject(dir, name string, hist, old []*object) error {
+	// Remove the content of the versions that are gone first, as the
+	// "null" version may be replaced by one saved in the same file.
+	for _, obj := range old {
+		if obj.deleteMarker || containsObject(hist, obj) {
+			continue
+		}
+		if err := os.Remove(dataPath(dir, obj)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	for _, obj := range hist {
+		if obj.deleteMarker || containsObject(old, obj) {
+			continue
+		}
+		if err := writeFile(dataPath(dir, obj), obj.data); err != nil {
+			return err
+		}
+	}
+	metaPath := filepath.Join(dir, "meta", fileName(name)+".json")
+	if len(hist) == 0 {
+		// The directory of the versions is left behind if not empty.
+		os.Remove(filepath.Join(dir, "versions", fileName(name)))
+		if err := os.Remove(metaPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	infos := make([]objectInfo, len(hist))
+	for i, obj := range hist {
+		infos[i] = obj.info()
+	}
+	data, err := json.MarshalIndent(infos, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFile(metaPath, data)
+}
+
+// dataPath returns the path of the file holding the content of obj, in the
+// directory of its bucket.
+func dataPath(dir string, obj *object) string {
+	if obj.version == "" || obj.version == "null" {
+		return filepath.Join(dir, "objects", fileName(obj.name))
+	}
+	return filepath.Join(dir, "versions", fileName(obj.name), obj.version)
+}
+
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0666)
+}
+
+func sameObjects(a, b []*object) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsObject(objs []*object, obj *object) bool {
+	for _, o := range objs {
+		if o == obj {
+			return true
+		}
+	}
+	return false
+}
+
+func (obj *object) info() objectInfo {
+	info := objectInfo{
+		Version:      obj.version,
+		DeleteMarker: obj.deleteMarker,
+		LastModified: obj.mtime,
+		Parts:        obj.parts,
+		ACP:          obj.acp,
+		StorageClass: obj.storageClass,
+		Tags:         obj.tags,
+	}
+	if !obj.deleteMarker {
+		info.MD5 = hex.EncodeToString(obj.checksum)
+	}
+	if len(obj.meta) > 0 {
+		info.Meta = obj.meta
+	}
+	if obj.encryption != (s3.Encryption{}) {
+		info.Encryption = &obj.encryption
+	}
+	if !obj.restoreDone.IsZero() {
+		info.RestoreDone = &obj.restoreDone
+		info.RestoreExpiry = &obj.restoreExpiry
+	}
+	return info
+}
+
+// loadBuckets reads the buckets kept in dir, returning them with the last
+// object version they hold. A missing directory holds no buckets.
+func loadBuckets(dir string) (buckets map[string]*bucket, versionSeq int, err error) {
+	buckets = make(map[string]*bucket)
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return buckets, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		b, seq, err := loadBucket(filepath.Join(dir, e.Name()), e.Name())
+		if err != nil {
+			return nil, 0, err
+		}
+		buckets[b.name] = b
+		if seq > versionSeq {
+			versionSeq = seq
+		}
+	}
+	return buckets, versionSeq, nil
+}
+
+func loadBucket(dir, name string) (b *bucket, versionSeq int, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "bucket.json"))
+	if err != nil {
+		return nil, 0, err
+	}
+	var info bucketInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, 0, fmt.Errorf("cannot read configuration of bucket %q: %v", name, err)
+	}
+	b = &bucket{
+		name:         name,
+		acl:          info.ACL,
+		acp:          info.ACP,
+		versioning:   info.Versioning,
+		lifecycle:    info.Lifecycle,
+		cors:         info.CORS,
+		notification: info.Notification,
+		objects:      make(map[string]*object),
+		versions:     make(map[string][]*object),
+		uploads:      make(map[string]*upload),
+	}
+	if info.Policy != "" {
+		b.policy = []byte(info.Policy)
+	}
+	entries, err := ioutil.ReadDir(filepath.Join(dir, "meta"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, 0, err
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		key, err := url.PathUnescape(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			return nil, 0, fmt.Errorf("bad object file name %q in bucket %q", e.Name(), name)
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, "meta", e.Name()))
+		if err != nil {
+			return nil, 0, err
+		}
+		var infos []objectInfo
+		if err := json.Unmarshal(data, &infos); err != nil || len(infos) == 0 {
+			return nil, 0, fmt.Errorf("cannot read metadata of object %q in bucket %q: %v", key, name, err)
+		}
+		hist := make([]*object, len(infos))
+		for i, info := range infos {
+			obj, err := loadObject(dir, key, info)
+			if err != nil {
+				return nil, 0, err
+			}
+			hist[i] = obj
+			if seq, err := strconv.ParseInt(obj.version, 16, 0); err == nil && int(seq) > versionSeq {
+				versionSeq = int(seq)
+			}
+		}
+		if b.versioning == "" {
+			b.objects[key] = hist[0]
+		} else {
+			b.setHistory(key, hist)
+		}
+	}
+	return b, versionSeq, nil
+}
+
+func loadObject(dir, name string, info objectInfo) (*object, error) {
+	obj := &object{
+		name:         name,
+		mtime:        info.LastModified,
+		meta:         info.Meta,
+		parts:        info.Parts,
+		version:      info.Version,
+		deleteMarker: info.DeleteMarker,
+		acp:          info.ACP,
+		storageClass: info.StorageClass,
+		tags:         info.Tags,
+	}
+	if obj.meta == nil {
+		obj.meta = make(http.Header)
+	}
+	if info.Encryption != nil {
+		obj.encryption = *info.Encryption
+	}
+	if info.RestoreDone != nil {
+		obj.restoreDone = *info.RestoreDone
+	}
+	if info.RestoreExpiry != nil {
+		obj.restoreExpiry = *info.RestoreExpiry
+	}
+	if obj.deleteMarker {
+		return obj, nil
+	}
+	var err error
+	obj.checksum, err = hex.DecodeString(info.MD5)
+	if err != nil {
+		return nil, fmt.Errorf("bad MD5 of object %q in bucket %q", name, filepath.Base(dir))
+	}
+	obj.data, err = ioutil.ReadFile(dataPath(dir, obj))
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// subject returns the bucket and the name of the object of the resource.
+func (r objectResource) subject() (*bucket, string) {
+	return r.bucket, r.name
+}
+
+// save writes the buckets of the server to Config.Dir after the resource r
+// was changed.
+func (srv *Server) save(r resource) {
+	var b *bucket
+	var name string
+	if r, ok := r.(interface {
+		subject() (*bucket, string)
+	}); ok {
+		b, name = r.subject()
+	}
+	if err := srv.store.save(srv.buckets, b, name); err != nil {
+		fatalf(500, "InternalError", "cannot save buckets: %v", err)
+	}
+}
+
+// Snapshot writes the buckets of the server, with their objects, to dir,
+// which must not exist or be empty, in the layout of Config.Dir. The
+// snapshot can be loaded back with Restore, or by a new server with dir as
+// its Config.Dir. Unfinished multipart uploads are left out.
+func (srv *Server) Snapshot(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("snapshot directory %q is not empty", dir)
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return newDiskStore(dir).save(srv.buckets, nil, "")
+}
+
+// Restore replaces the buckets of the server with those of the snapshot
+// in dir, also writing them to Config.Dir if set. Unfinished multipart
+// uploads are discarded.
+func (srv *Server) Restore(dir string) error {
+	buckets, versionSeq, err := loadBuckets(dir)
+	if err != nil {
+		return err
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.buckets = buckets
+	if versionSeq > srv.versionSeq {
+		srv.versionSeq = versionSeq
+	}
+	if srv.store != nil {
+		return srv.store.save(srv.buckets, nil, "")
+	}
+	return nil
+}
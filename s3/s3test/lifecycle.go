@@ -0,0 +1,62 @@
+package s3test
+
+import (
+	"encoding/xml"
+
+	"github.com/goamz/goamz/s3"
+)
+
+// lifecycleResource is the lifecycle configuration of a bucket.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUTlifecycle.html
+type lifecycleResource struct {
+	bucketResource
+}
+
+func (r lifecycleResource) put(a *action) interface{} {
+	b := r.existingBucket()
+	var c s3.LifecycleConfiguration
+	if err := xml.Unmarshal(readBody(a), &c); err != nil {
+		fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	if len(c.Rules) == 0 || len(c.Rules) > 1000 {
+		fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	ids := make(map[string]bool)
+	for _, rule := range c.Rules {
+		if rule.Status != s3.LifecycleRuleEnabled && rule.Status != s3.LifecycleRuleDisabled {
+			fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+		}
+		if rule.ID != "" && ids[rule.ID] {
+			fatalf(400, "InvalidArgument", "Rule ID must be unique. Found same ID for more than one rule")
+		}
+		ids[rule.ID] = true
+		if rule.Expiration == nil && len(rule.Transitions) == 0 &&
+			rule.NoncurrentVersionExpiration == nil && rule.AbortIncompleteMultipartUpload == nil {
+			fatalf(400, "InvalidRequest", "At least one action needs to be specified in a rule")
+		}
+		for _, t := range rule.Transitions {
+			if t.StorageClass != s3.StorageClassStandardIA && t.StorageClass != s3.StorageClassGlacier {
+				fatalf(400, "InvalidStorageClass", "The storage class you specified is not valid")
+			}
+		}
+	}
+	b.lifecycle = &c
+	return nil
+}
+
+func (r lifecycleResource) get(a *action) interface{} {
+	b := r.existingBucket()
+	if b.lifecycle == nil {
+		fatalf(404, "NoSuchLifecycleConfiguration", "The lifecycle configuration does not exist")
+	}
+	return b.lifecycle
+}
+
+func (r lifecycleResource) delete(a *action) interface{} {
+	r.existingBucket().lifecycle = nil
+	return nil
+}
+
+func (r lifecycleResource) post(a *action) interface{} {
+	return notAllowed()
+}
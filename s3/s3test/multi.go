@@ -0,0 +1,441 @@
+package s3test
+
+import (
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goamz/goamz/s3"
+)
+
+// upload is an unfinished multipart upload.
+type upload struct {
+	id        string
+	key       string
+	initiated time.Time
+
+	// obj holds the metadata, access control policy, encryption,
+	// storage class and tags of the object the upload creates.
+	obj   *object
+	parts map[int]*part
+}
+
+type part struct {
+	data     []byte
+	checksum []byte
+	mtime    time.Time
+}
+
+func (p *part) etag() string {
+	return fmt.Sprintf(`"%x"`, p.checksum)
+}
+
+// orderedUploads holds a slice of uploads that can be sorted by key, and
+// by id for the same key.
+type orderedUploads []*upload
+
+func (s orderedUploads) Len() int      { return len(s) }
+func (s orderedUploads) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s orderedUploads) Less(i, j int) bool {
+	if s[i].key != s[j].key {
+		return s[i].key < s[j].key
+	}
+	return s[i].id < s[j].id
+}
+
+// intParam returns the value of the integer parameter name of the
+// request, or def if it is not given.
+func intParam(a *action, name string, def int) int {
+	s := a.req.Form.Get(name)
+	if s == "" {
+		return def
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 0 {
+		fatalf(400, "InvalidArgument", "Provided %s not an integer or within integer range", name)
+	}
+	return i
+}
+
+type initiateResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Bucket   string
+	Key      string
+	UploadId string
+}
+
+// uploadsResource initiates multipart uploads of an object.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadInitiate.html
+type uploadsResource struct {
+	objectResource
+}
+
+func (r uploadsResource) post(a *action) interface{} {
+	obj := &object{
+		name:         r.name,
+		meta:         make(http.Header),
+		acp:          cannedACL(s3.ACL(a.req.Header.Get("x-amz-acl"))),
+		encryption:   readEncryption(a),
+		storageClass: readStorageClass(a),
+		tags:         readTags(a),
+	}
+	for key, values := range a.req.Header {
+		key = http.CanonicalHeaderKey(key)
+		if metaHeaders[key] || strings.HasPrefix(key, "X-Amz-Meta-") {
+			obj.meta[key] = values
+		}
+	}
+	a.srv.uploadSeq++
+	u := &upload{
+		id:        fmt.Sprintf("%032x", a.srv.uploadSeq),
+		key:       r.name,
+		initiated: time.Now(),
+		obj:       obj,
+		parts:     make(map[int]*part),
+	}
+	r.bucket.uploads[u.id] = u
+	setEncryptionHeaders(a.w.Header(), obj.encryption)
+	return &initiateResult{
+		Bucket:   r.bucket.name,
+		Key:      r.name,
+		UploadId: u.id,
+	}
+}
+
+func (r uploadsResource) get(a *action) interface{} {
+	return notAllowed()
+}
+
+func (r uploadsResource) put(a *action) interface{} {
+	return notAllowed()
+}
+
+func (r uploadsResource) delete(a *action) interface{} {
+	return notAllowed()
+}
+
+// uploadResource is an unfinished multipart upload of an object, whose
+// parts are uploaded with PUT, listed with GET, and assembled into the
+// object with POST. DELETE aborts the upload.
+// http://docs.aws.amazon.com/AmazonS3/latest/dev/mpuoverview.html
+type uploadResource struct {
+	objectResource
+}
+
+// upload returns the upload the request is about.
+func (r uploadResource) upload(a *action) *upload {
+	u := r.bucket.uploads[a.req.Form.Get("uploadId")]
+	if u == nil || u.key != r.name {
+		fatalf(404, "NoSuchUpload", "The specified upload does not exist. The upload ID may be invalid, or the upload may have been aborted or completed.")
+	}
+	return u
+}
+
+type copyPartResult struct {
+	XMLName      xml.Name `xml:"CopyPartResult"`
+	ETag         string
+	LastModified string
+}
+
+// PUT uploads a part, or copies it from an object if the
+// x-amz-copy-source header is given.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadUploadPart.html
+// http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadUploadPartCopy.html
+func (r uploadResource) put(a *action) interface{} {
+	u := r.upload(a)
+	n, err := strconv.Atoi(a.req.Form.Get("partNumber"))
+	if err != nil || n < 1 || n > 10000 {
+		fatalf(400, "InvalidArgument", "Part number must be an integer between 1 and 10000, inclusive")
+	}
+	checkCustomerKey(a.req.Header, "x-amz-", u.obj)
+	p := &part{mtime: time.Now()}
+	src := a.req.Header.Get("x-amz-copy-source")
+	if src == "" {
+		p.data, p.checksum = readContent(a)
+	} else {
+		_, from := copySource(a, src)
+		p.data = from.data
+		if rng := a.req.Header.Get("x-amz-copy-source-range"); rng != "" {
+			var first, last int64
+			_, err := fmt.Sscanf(rng, "bytes=%d-%d", &first, &last)
+			if err != nil || first < 0 || first > last || last >= int64(len(from.data)) {
+				fatalf(400, "InvalidArgument", "Range specified is not valid for source object of size: %d", len(from.data))
+			}
+			p.data = from.data[first : last+1]
+		}
+		sum := md5.Sum(p.data)
+		p.checksum = sum[:]
+	}
+	u.parts[n] = p
+	setEncryptionHeaders(a.w.Header(), u.obj.encryption)
+	if src != "" {
+		return &copyPartResult{
+			ETag:         p.etag(),
+			LastModified: p.mtime.Format(timeFormat),
+		}
+	}
+	a.w.Header().Set("ETag", p.etag())
+	return nil
+}
+
+type listPart struct {
+	PartNumber   int
+	LastModified string
+	ETag         string
+	Size         int
+}
+
+type listPartsResult struct {
+	XMLName              xml.Name `xml:"ListPartsResult"`
+	Bucket               string
+	Key                  string
+	UploadId             string
+	StorageClass         string
+	PartNumberMarker     int
+	NextPartNumberMarker int
+	MaxParts             int
+	IsTruncated          bool
+	Part                 []listPart
+}
+
+// GET lists the parts uploaded.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadListParts.html
+func (r uploadResource) get(a *action) interface{} {
+	u := r.upload(a)
+	marker := intParam(a, "part-number-marker", 0)
+	maxParts := intParam(a, "max-parts", 1000)
+	if maxParts == 0 || maxParts > 1000 {
+		maxParts = 1000
+	}
+	var numbers []int
+	for n := range u.parts {
+		if n > marker {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+	resp := &listPartsResult{
+		Bucket:           r.bucket.name,
+		Key:              u.key,
+		UploadId:         u.id,
+		StorageClass:     u.obj.storageClass,
+		PartNumberMarker: marker,
+		MaxParts:         maxParts,
+	}
+	if len(numbers) > maxParts {
+		numbers = numbers[:maxParts]
+		resp.IsTruncated = true
+	}
+	for _, n := range numbers {
+		p := u.parts[n]
+		resp.Part = append(resp.Part, listPart{
+			PartNumber:   n,
+			LastModified: p.mtime.Format(timeFormat),
+			ETag:         p.etag(),
+			Size:         len(p.data),
+		})
+		resp.NextPartNumberMarker = n
+	}
+	return resp
+}
+
+type completeUpload struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Parts   []struct {
+		PartNumber int
+		ETag       string
+	} `xml:"Part"`
+}
+
+type completeResult struct {
+	XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+	Location string
+	Bucket   string
+	Key      string
+	ETag     string
+}
+
+// POST completes the upload, creating the object from the parts given,
+// whose ETag is the MD5 of their MD5s followed by their number.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadComplete.html
+func (r uploadResource) post(a *action) interface{} {
+	u := r.upload(a)
+	var req completeUpload
+	if err := xml.Unmarshal(readBody(a), &req); err != nil || len(req.Parts) == 0 {
+		fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	var parts []*part
+	for i, cp := range req.Parts {
+		if i > 0 && cp.PartNumber <= req.Parts[i-1].PartNumber {
+			fatalf(400, "InvalidPartOrder", "The list of parts was not in ascending order. The parts list must be specified in order by part number.")
+		}
+		p := u.parts[cp.PartNumber]
+		if p == nil || strings.Trim(cp.ETag, `"`) != strings.Trim(p.etag(), `"`) {
+			fatalf(400, "InvalidPart", "One or more of the specified parts could not be found. The part might not have been uploaded, or the specified entity tag might not have matched the part's entity tag.")
+		}
+		parts = append(parts, p)
+	}
+	minSize := a.srv.config.minPartSize()
+	for _, p := range parts[:len(parts)-1] {
+		if int64(len(p.data)) < minSize {
+			fatalf(400, "EntityTooSmall", "Your proposed upload is smaller than the minimum allowed object size.")
+		}
+	}
+
+	sum := md5.New()
+	var data []byte
+	for _, p := range parts {
+		data = append(data, p.data...)
+		sum.Write(p.checksum)
+	}
+	obj := &object{
+		name:         u.key,
+		meta:         make(http.Header),
+		data:         data,
+		checksum:     sum.Sum(nil),
+		parts:        len(parts),
+		mtime:        time.Now(),
+		acp:          u.obj.acp,
+		encryption:   u.obj.encryption,
+		storageClass: u.obj.storageClass,
+		tags:         u.obj.tags,
+	}
+	for key, values := range u.obj.meta {
+		obj.meta[key] = values
+	}
+	delete(r.bucket.uploads, u.id)
+	setEncryptionHeaders(a.w.Header(), obj.encryption)
+	r.store(a, obj)
+	return &completeResult{
+		Location: "http://" + a.req.Host + "/" + r.bucket.name + "/" + u.key,
+		Bucket:   r.bucket.name,
+		Key:      u.key,
+		ETag:     `"` + obj.etag() + `"`,
+	}
+}
+
+// DELETE aborts the upload, discarding its parts.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadAbort.html
+func (r uploadResource) delete(a *action) interface{} {
+	u := r.upload(a)
+	delete(r.bucket.uploads, u.id)
+	a.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type listUpload struct {
+	Key          string
+	UploadId     string
+	StorageClass string
+	Initiated    string
+}
+
+type commonPrefix struct {
+	Prefix string
+}
+
+type listUploadsResult struct {
+	XMLName            xml.Name `xml:"ListMultipartUploadsResult"`
+	Bucket             string
+	KeyMarker          string
+	UploadIdMarker     string
+	NextKeyMarker      string
+	NextUploadIdMarker string
+	Delimiter          string `xml:",omitempty"`
+	Prefix             string
+	MaxUploads         int
+	IsTruncated        bool
+	Upload             []listUpload
+	CommonPrefixes     []commonPrefix
+}
+
+// multipartUploadsResource lists the unfinished multipart uploads of a
+// bucket.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadListMPUpload.html
+type multipartUploadsResource struct {
+	bucketResource
+}
+
+func (r multipartUploadsResource) get(a *action) interface{} {
+	b := r.existingBucket()
+	prefix := a.req.Form.Get("prefix")
+	delimiter := a.req.Form.Get("delimiter")
+	keyMarker := a.req.Form.Get("key-marker")
+	idMarker := a.req.Form.Get("upload-id-marker")
+	maxUploads := intParam(a, "max-uploads", 1000)
+	if maxUploads == 0 || maxUploads > 1000 {
+		maxUploads = 1000
+	}
+
+	var uploads orderedUploads
+	for _, u := range b.uploads {
+		if strings.HasPrefix(u.key, prefix) {
+			uploads = append(uploads, u)
+		}
+	}
+	sort.Sort(uploads)
+
+	resp := &listUploadsResult{
+		Bucket:         b.name,
+		KeyMarker:      keyMarker,
+		UploadIdMarker: idMarker,
+		Delimiter:      delimiter,
+		Prefix:         prefix,
+		MaxUploads:     maxUploads,
+	}
+	for _, u := range uploads {
+		key := u.key
+		isPrefix := false
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				key = key[:len(prefix)+i+len(delimiter)]
+				n := len(resp.CommonPrefixes)
+				if n > 0 && resp.CommonPrefixes[n-1].Prefix == key {
+					continue
+				}
+				isPrefix = true
+			}
+		}
+		switch {
+		case key < keyMarker:
+			continue
+		case key == keyMarker && (isPrefix || idMarker == "" || u.id <= idMarker):
+			continue
+		}
+		if len(resp.Upload)+len(resp.CommonPrefixes) >= maxUploads {
+			resp.IsTruncated = true
+			break
+		}
+		resp.NextKeyMarker = key
+		if isPrefix {
+			resp.CommonPrefixes = append(resp.CommonPrefixes, commonPrefix{key})
+			resp.NextUploadIdMarker = ""
+			continue
+		}
+		resp.Upload = append(resp.Upload, listUpload{
+			Key:          u.key,
+			UploadId:     u.id,
+			StorageClass: u.obj.storageClass,
+			Initiated:    u.initiated.Format(timeFormat),
+		})
+		resp.NextUploadIdMarker = u.id
+	}
+	return resp
+}
+
+func (r multipartUploadsResource) put(a *action) interface{} {
+	return notAllowed()
+}
+
+func (r multipartUploadsResource) post(a *action) interface{} {
+	return notAllowed()
+}
+
+func (r multipartUploadsResource) delete(a *action) interface{} {
+	return notAllowed()
+}
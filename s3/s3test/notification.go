@@ -0,0 +1,76 @@
+package s3test
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/goamz/goamz/s3"
+)
+
+// notificationResource is the notification configuration of a bucket.
+// Notifications are recorded but never sent.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUTnotification.html
+type notificationResource struct {
+	bucketResource
+}
+
+func (r notificationResource) put(a *action) interface{} {
+	b := r.existingBucket()
+	var c s3.NotificationConfiguration
+	if err := xml.Unmarshal(readBody(a), &c); err != nil {
+		fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	for _, q := range c.Queues {
+		checkNotification(q.Queue, q.Events, q.Filter)
+	}
+	for _, t := range c.Topics {
+		checkNotification(t.Topic, t.Events, t.Filter)
+	}
+	for _, l := range c.Lambdas {
+		checkNotification(l.Function, l.Events, l.Filter)
+	}
+	b.notification = &c
+	return nil
+}
+
+// checkNotification validates a notification sent to the destination
+// arn for events, optionally filtered.
+func checkNotification(arn string, events []string, filter *s3.NotificationFilter) {
+	if !strings.HasPrefix(arn, "arn:") {
+		fatalf(400, "InvalidArgument", "The ARN is not well formed")
+	}
+	if len(events) == 0 {
+		fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	for _, e := range events {
+		if !strings.HasPrefix(e, "s3:") {
+			fatalf(400, "InvalidArgument", "The event is not supported for notifications")
+		}
+	}
+	if filter == nil {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, rule := range filter.Rules {
+		if rule.Name != "prefix" && rule.Name != "suffix" || seen[rule.Name] {
+			fatalf(400, "InvalidArgument", "filter rule name must be either prefix or suffix")
+		}
+		seen[rule.Name] = true
+	}
+}
+
+func (r notificationResource) get(a *action) interface{} {
+	b := r.existingBucket()
+	if b.notification == nil {
+		return &s3.NotificationConfiguration{}
+	}
+	return b.notification
+}
+
+func (r notificationResource) delete(a *action) interface{} {
+	return notAllowed()
+}
+
+func (r notificationResource) post(a *action) interface{} {
+	return notAllowed()
+}
@@ -0,0 +1,44 @@
+package s3test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+)
+
+// policyResource is the policy of a bucket.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUTpolicy.html
+type policyResource struct {
+	bucketResource
+}
+
+func (r policyResource) put(a *action) interface{} {
+	b := r.existingBucket()
+	data := readBody(a)
+	if !json.Valid(data) || !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		fatalf(400, "MalformedPolicy", "Policies must be valid JSON and the first byte must be '{'")
+	}
+	b.policy = data
+	return nil
+}
+
+func (r policyResource) get(a *action) interface{} {
+	b := r.existingBucket()
+	if b.policy == nil {
+		fatalf(404, "NoSuchBucketPolicy", "The bucket policy does not exist")
+	}
+	a.w.Header().Set("Content-Type", "application/json")
+	if _, err := a.w.Write(b.policy); err != nil {
+		log.Printf("error writing policy: %v", err)
+	}
+	return nil
+}
+
+func (r policyResource) delete(a *action) interface{} {
+	r.existingBucket().policy = nil
+	return nil
+}
+
+func (r policyResource) post(a *action) interface{} {
+	return notAllowed()
+}
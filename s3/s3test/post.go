@@ -0,0 +1,233 @@
+package s3test
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/goamz/goamz/aws"
+	"github.com/goamz/goamz/s3"
+)
+
+// postPolicy is the policy of a POST upload.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-HTTPPOSTConstructPolicy.html
+type postPolicy struct {
+	Expiration string
+	Conditions []interface{}
+}
+
+type postResponse struct {
+	XMLName  xml.Name `xml:"PostResponse"`
+	Location string
+	Bucket   string
+	Key      string
+	ETag     string
+}
+
+// postFields lists the form fields that need not be matched by a
+// condition of the policy of POST uploads.
+var postFields = map[string]bool{
+	"awsaccesskeyid":  true,
+	"signature":       true,
+	"policy":          true,
+	"file":            true,
+	"x-amz-signature": true,
+}
+
+// postObject creates an object from a POST upload, sent with the fields
+// of an HTML form, such as those given by Bucket.PostFormArgs.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPOST.html
+func (r bucketResource) postObject(a *action) interface{} {
+	b := r.existingBucket()
+	if err := a.req.ParseMultipartForm(32 << 20); err != nil {
+		fatalf(400, "MalformedPOSTRequest", "The body of your POST request is not well-formed multipart/form-data.")
+	}
+	// Field names are case insensitive.
+	fields := make(map[string]string)
+	for name, values := range a.req.MultipartForm.Value {
+		fields[strings.ToLower(name)] = values[0]
+	}
+	file, header, err := a.req.FormFile("file")
+	if err != nil {
+		fatalf(400, "InvalidArgument", "POST requires exactly one file upload per request.")
+	}
+	data, err := ioutil.ReadAll(file)
+	file.Close()
+	if err != nil {
+		fatalf(400, "TODO", "read error")
+	}
+	key := fields["key"]
+	if key == "" {
+		fatalf(400, "InvalidArgument", "Bucket POST must contain a field named 'key'.  If it is specified, please check the order of the fields.")
+	}
+	key = strings.Replace(key, "${filename}", header.Filename, -1)
+	fields["key"] = key
+	fields["bucket"] = b.name
+	if auth := a.srv.config.auth(); auth != nil {
+		checkPostSignature(auth, fields)
+		checkPostPolicy(fields, int64(len(data)))
+	}
+
+	sum := md5.Sum(data)
+	obj := &object{
+		name:         key,
+		meta:         make(http.Header),
+		checksum:     sum[:],
+		data:         data,
+		mtime:        time.Now(),
+		acp:          cannedACL(s3.ACL(fields["acl"])),
+		storageClass: s3.StorageClassStandard,
+	}
+	for name, value := range fields {
+		name = http.CanonicalHeaderKey(name)
+		if metaHeaders[name] || strings.HasPrefix(name, "X-Amz-Meta-") {
+			obj.meta.Set(name, value)
+		}
+	}
+	objectResource{name: key, bucket: b, object: b.objects[key]}.store(a, obj)
+
+	etag := `"` + obj.etag() + `"`
+	a.w.Header().Set("ETag", etag)
+	if redirect := fields["success_action_redirect"]; redirect != "" {
+		u, err := url.Parse(redirect)
+		if err == nil {
+			q := u.Query()
+			q.Set("bucket", b.name)
+			q.Set("key", key)
+			q.Set("etag", etag)
+			u.RawQuery = q.Encode()
+			a.w.Header().Set("Location", u.String())
+			a.w.WriteHeader(http.StatusSeeOther)
+			return nil
+		}
+	}
+	switch fields["success_action_status"] {
+	case "200":
+		return nil
+	case "201":
+		a.w.WriteHeader(http.StatusCreated)
+		return &postResponse{
+			Location: "http://" + a.req.Host + "/" + b.name + "/" + url.PathEscape(key),
+			Bucket:   b.name,
+			Key:      key,
+			ETag:     etag,
+		}
+	}
+	a.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// checkPostSignature fails unless the policy of a POST upload, given
+// with the other form fields, is signed with the credentials of auth,
+// with AWS signature version 2 or 4.
+func checkPostSignature(auth *aws.Auth, fields map[string]string) {
+	policy := fields["policy"]
+	if policy == "" {
+		fatalf(403, "AccessDenied", "Access Denied")
+	}
+	if fields["x-amz-algorithm"] != "" {
+		if fields["x-amz-algorithm"] != "AWS4-HMAC-SHA256" {
+			fatalf(400, "InvalidArgument", "Only AWS4-HMAC-SHA256 is supported for x-amz-algorithm")
+		}
+		scope, ok := parseCredential(fields["x-amz-credential"])
+		if !ok {
+			fatalf(400, "InvalidArgument", "Invalid Policy: Invalid 'x-amz-credential' field")
+		}
+		checkAccessKey(auth, scope.accessKey)
+		signature := hex.EncodeToString(hmacSHA256(scope.signingKey(auth), policy))
+		checkSignature(scope.accessKey, policy, "", fields["x-amz-signature"], signature)
+		return
+	}
+	checkAccessKey(auth, fields["awsaccesskeyid"])
+	checkSignature(fields["awsaccesskeyid"], policy, "", fields["signature"], signV2(auth, policy))
+}
+
+// checkPostPolicy fails unless the form fields of a POST upload, of a
+// file of size bytes, abide by the policy given with them.
+func checkPostPolicy(fields map[string]string, size int64) {
+	data, err := base64.StdEncoding.DecodeString(fields["policy"])
+	var policy postPolicy
+	if err == nil {
+		err = json.Unmarshal(data, &policy)
+	}
+	if err != nil {
+		fatalf(400, "InvalidPolicyDocument", "Invalid Policy: Invalid JSON.")
+	}
+	expiration, err := time.Parse(time.RFC3339, policy.Expiration)
+	if err != nil {
+		fatalf(400, "InvalidPolicyDocument", "Invalid Policy: Invalid 'expiration' value: '%s'", policy.Expiration)
+	}
+	if time.Now().After(expiration) {
+		fatalf(403, "AccessDenied", "Invalid according to Policy: Policy expired.")
+	}
+	matched := make(map[string]bool)
+	failed := func(cond interface{}) {
+		data, _ := json.Marshal(cond)
+		fatalf(403, "AccessDenied", "Invalid according to Policy: Policy Condition failed: %s", data)
+	}
+	for _, cond := range policy.Conditions {
+		switch cond := cond.(type) {
+		case map[string]interface{}:
+			// {"field": "value"}
+			for name, value := range cond {
+				name = strings.ToLower(name)
+				matched[name] = true
+				if s, ok := value.(string); !ok || fields[name] != s {
+					failed(cond)
+				}
+			}
+		case []interface{}:
+			if len(cond) != 3 {
+				fatalf(400, "InvalidPolicyDocument", "Invalid Policy: Invalid Condition: %v", cond)
+			}
+			op, _ := cond[0].(string)
+			if strings.ToLower(op) == "content-length-range" {
+				min, ok1 := cond[1].(float64)
+				max, ok2 := cond[2].(float64)
+				if !ok1 || !ok2 {
+					fatalf(400, "InvalidPolicyDocument", "Invalid Policy: Invalid content-length-range condition")
+				}
+				if float64(size) < min {
+					fatalf(400, "EntityTooSmall", "Your proposed upload is smaller than the minimum allowed size")
+				}
+				if float64(size) > max {
+					fatalf(400, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed size")
+				}
+				continue
+			}
+			field, ok1 := cond[1].(string)
+			value, ok2 := cond[2].(string)
+			if !ok1 || !ok2 || !strings.HasPrefix(field, "$") {
+				fatalf(400, "InvalidPolicyDocument", "Invalid Policy: Invalid Condition: %v", cond)
+			}
+			name := strings.ToLower(field[1:])
+			matched[name] = true
+			switch strings.ToLower(op) {
+			case "eq":
+				if fields[name] != value {
+					failed(cond)
+				}
+			case "starts-with":
+				if !strings.HasPrefix(fields[name], value) {
+					failed(cond)
+				}
+			default:
+				fatalf(400, "InvalidPolicyDocument", "Invalid Policy: Invalid Condition: %v", cond)
+			}
+		default:
+			fatalf(400, "InvalidPolicyDocument", "Invalid Policy: Invalid Condition: %v", cond)
+		}
+	}
+	for name := range fields {
+		if !matched[name] && !postFields[name] && !strings.HasPrefix(name, "x-ignore-") && name != "bucket" {
+			fatalf(403, "AccessDenied", "Invalid according to Policy: Extra input fields: %s", name)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/xml"
 	"fmt"
+	"github.com/goamz/goamz/aws"
 	"github.com/goamz/goamz/s3"
 	"io"
 	"io/ioutil"
@@ -32,6 +33,14 @@ type s3Error struct {
 	BucketName string
 	RequestId  string
 	HostId     string
+
+	// Details of authentication failures.
+	AWSAccessKeyId        string `xml:",omitempty"`
+	StringToSign          string `xml:",omitempty"`
+	SignatureProvided     string `xml:",omitempty"`
+	StringToSignBytes     string `xml:",omitempty"`
+	CanonicalRequest      string `xml:",omitempty"`
+	CanonicalRequestBytes string `xml:",omitempty"`
 }
 
 type action struct {
@@ -51,6 +60,34 @@ type Config struct {
 	// all other regions.
 	// http://docs.amazonwebservices.com/AmazonS3/latest/API/ErrorResponses.html
 	Send409Conflict bool
+
+	// RestoreDelay is how long restoring an object archived in Glacier
+	// takes. Restores complete at once by default.
+	RestoreDelay time.Duration
+
+	// MinPartSize, if positive, is the size all the parts of multipart
+	// uploads but the last one must be at least, instead of the 5MB S3
+	// requires.
+	MinPartSize int64
+
+	// Auth, if not nil, holds the credentials requests must be signed
+	// with, using AWS signature version 2 or 4, in headers or in the
+	// query string of presigned URLs, which are rejected once expired.
+	// POST uploads must be signed in turn, and abide by their policy.
+	// Anonymous requests may only read objects and buckets granted to
+	// all users. The region requests are signed for is not checked.
+	// By default, requests are not authenticated.
+	Auth *aws.Auth
+
+	// Dir, if not empty, is the directory the server keeps its buckets
+	// in, as laid out by Snapshot, so that they outlive it. NewServer
+	// loads the buckets it holds. Unfinished multipart uploads are kept
+	// in memory only.
+	Dir string
+
+	// Addr, if not empty, is the TCP address the server listens on,
+	// instead of a free port on localhost.
+	Addr string
 }
 
 func (c *Config) send409Conflict() bool {
@@ -60,8 +97,44 @@ func (c *Config) send409Conflict() bool {
 	return false
 }
 
+func (c *Config) restoreDelay() time.Duration {
+	if c != nil {
+		return c.RestoreDelay
+	}
+	return 0
+}
+
+func (c *Config) auth() *aws.Auth {
+	if c != nil {
+		return c.Auth
+	}
+	return nil
+}
+
+func (c *Config) dir() string {
+	if c != nil {
+		return c.Dir
+	}
+	return ""
+}
+
+func (c *Config) addr() string {
+	if c != nil && c.Addr != "" {
+		return c.Addr
+	}
+	return "localhost:0"
+}
+
+func (c *Config) minPartSize() int64 {
+	if c != nil && c.MinPartSize > 0 {
+		return c.MinPartSize
+	}
+	return 5 * 1024 * 1024
+}
+
 // Server is a fake S3 server for testing purposes.
-// All of the data for the server is kept in memory.
+// All of the data for the server is kept in memory,
+// and also on disk if Config.Dir is set.
 type Server struct {
 	url      string
 	reqId    int
@@ -69,13 +142,33 @@ type Server struct {
 	mu       sync.Mutex
 	buckets  map[string]*bucket
 	config   *Config
+
+	versionSeq int // Last object version handed out.
+	uploadSeq  int // Last multipart upload id handed out.
+
+	store *diskStore // nil unless Config.Dir is set.
 }
 
 type bucket struct {
-	name    string
-	acl     s3.ACL
-	ctime   time.Time
-	objects map[string]*object
+	name      string
+	acl       s3.ACL
+	ctime     time.Time
+	objects   map[string]*object // current versions only.
+	lifecycle *s3.LifecycleConfiguration
+	acp       *s3.AccessControlPolicy
+	policy    []byte
+	cors      *s3.CORSConfiguration
+
+	notification *s3.NotificationConfiguration
+
+	// versioning is the versioning status of the bucket, empty if it
+	// was never enabled. Once it is, versions holds all the versions of
+	// the objects stored since, newest first.
+	versioning string
+	versions   map[string][]*object
+
+	// uploads holds the unfinished multipart uploads by id.
+	uploads map[string]*upload
 }
 
 type object struct {
@@ -84,6 +177,23 @@ type object struct {
 	meta     http.Header // metadata to return with requests.
 	checksum []byte      // also held as Content-MD5 in meta.
 	data     []byte
+
+	// parts is the number of parts of objects stored by multipart
+	// uploads, whose checksum is the MD5 of the MD5s of their parts.
+	parts int
+
+	version      string // empty if stored in an unversioned bucket.
+	deleteMarker bool
+	acp          *s3.AccessControlPolicy
+	encryption   s3.Encryption
+	storageClass string
+	tags         []s3.Tag
+
+	// restoreDone is when the restoration of the object from Glacier
+	// completes, if it was ever asked for, and restoreExpiry when the
+	// restored copy expires.
+	restoreDone   time.Time
+	restoreExpiry time.Time
 }
 
 // A resource encapsulates the subject of an HTTP request.
@@ -97,16 +207,26 @@ type resource interface {
 }
 
 func NewServer(config *Config) (*Server, error) {
-	l, err := net.Listen("tcp", "localhost:0")
-	if err != nil {
-		return nil, fmt.Errorf("cannot listen on localhost: %v", err)
-	}
 	srv := &Server{
-		listener: l,
-		url:      "http://" + l.Addr().String(),
-		buckets:  make(map[string]*bucket),
-		config:   config,
+		buckets: make(map[string]*bucket),
+		config:  config,
+	}
+	if dir := config.dir(); dir != "" {
+		buckets, versionSeq, err := loadBuckets(dir)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load buckets from %q: %v", dir, err)
+		}
+		srv.buckets = buckets
+		srv.versionSeq = versionSeq
+		srv.store = newDiskStore(dir)
+		srv.store.loaded(buckets)
 	}
+	l, err := net.Listen("tcp", config.addr())
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on %s: %v", config.addr(), err)
+	}
+	srv.listener = l
+	srv.url = "http://" + l.Addr().String()
 	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		srv.serveHTTP(w, req)
 	}))
@@ -159,6 +279,32 @@ func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
 				err.BucketName = r.bucket.name
 			case bucketResource:
 				err.BucketName = r.name
+			case lifecycleResource:
+				err.BucketName = r.name
+			case versioningResource:
+				err.BucketName = r.name
+			case versionsResource:
+				err.BucketName = r.name
+			case aclResource:
+				err.BucketName = r.name
+			case policyResource:
+				err.BucketName = r.name
+			case corsResource:
+				err.BucketName = r.name
+			case notificationResource:
+				err.BucketName = r.name
+			case objectACLResource:
+				err.BucketName = r.bucket.name
+			case taggingResource:
+				err.BucketName = r.bucket.name
+			case restoreResource:
+				err.BucketName = r.bucket.name
+			case uploadsResource:
+				err.BucketName = r.bucket.name
+			case uploadResource:
+				err.BucketName = r.bucket.name
+			case multipartUploadsResource:
+				err.BucketName = r.name
 			}
 			err.RequestId = a.reqId
 			// TODO HostId
@@ -171,7 +317,11 @@ func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 	}()
 
+	anonymous := srv.authenticate(a)
 	r = srv.resourceForURL(req.URL)
+	if anonymous {
+		checkAnonymous(a, r)
+	}
 
 	var resp interface{}
 	switch req.Method {
@@ -186,6 +336,9 @@ func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
 	default:
 		fatalf(400, "MethodNotAllowed", "unknown http request method %q", req.Method)
 	}
+	if srv.store != nil && req.Method != "GET" && req.Method != "HEAD" {
+		srv.save(r)
+	}
 	if resp != nil && req.Method != "HEAD" {
 		xmlMarshal(w, resp)
 	}
@@ -203,24 +356,37 @@ func xmlMarshal(w io.Writer, x interface{}) {
 // In a fully implemented test server, each of these would have
 // its own resource type.
 var unimplementedBucketResourceNames = map[string]bool{
-	"acl":            true,
-	"lifecycle":      true,
-	"policy":         true,
 	"location":       true,
 	"logging":        true,
-	"notification":   true,
-	"versions":       true,
 	"requestPayment": true,
-	"versioning":     true,
 	"website":        true,
-	"uploads":        true,
+}
+
+// bucketSubresources maps the names of the bucket subresources that are
+// implemented to their resource types.
+var bucketSubresources = map[string]func(b bucketResource) resource{
+	"lifecycle":    func(b bucketResource) resource { return lifecycleResource{b} },
+	"versioning":   func(b bucketResource) resource { return versioningResource{b} },
+	"versions":     func(b bucketResource) resource { return versionsResource{b} },
+	"acl":          func(b bucketResource) resource { return aclResource{b} },
+	"policy":       func(b bucketResource) resource { return policyResource{b} },
+	"cors":         func(b bucketResource) resource { return corsResource{b} },
+	"notification": func(b bucketResource) resource { return notificationResource{b} },
+	"uploads":      func(b bucketResource) resource { return multipartUploadsResource{b} },
 }
 
 var unimplementedObjectResourceNames = map[string]bool{
-	"uploadId": true,
-	"acl":      true,
-	"torrent":  true,
-	"uploads":  true,
+	"torrent": true,
+}
+
+// objectSubresources maps the names of the object subresources that are
+// implemented to their resource types.
+var objectSubresources = map[string]func(objr objectResource) resource{
+	"acl":      func(objr objectResource) resource { return objectACLResource{objr} },
+	"tagging":  func(objr objectResource) resource { return taggingResource{objr} },
+	"restore":  func(objr objectResource) resource { return restoreResource{objr} },
+	"uploads":  func(objr objectResource) resource { return uploadsResource{objr} },
+	"uploadId": func(objr objectResource) resource { return uploadResource{objr} },
 }
 
 var pathRegexp = regexp.MustCompile("/(([^/]+)(/(.*))?)?")
@@ -243,6 +409,9 @@ func (srv *Server) resourceForURL(u *url.URL) (r resource) {
 	q := u.Query()
 	if objectName == "" {
 		for name := range q {
+			if sub := bucketSubresources[name]; sub != nil {
+				return sub(b)
+			}
 			if unimplementedBucketResourceNames[name] {
 				return nullResource{}
 			}
@@ -258,14 +427,17 @@ func (srv *Server) resourceForURL(u *url.URL) (r resource) {
 		version: q.Get("versionId"),
 		bucket:  b.bucket,
 	}
+	if obj := objr.bucket.objects[objr.name]; obj != nil {
+		objr.object = obj
+	}
 	for name := range q {
+		if sub := objectSubresources[name]; sub != nil {
+			return sub(objr)
+		}
 		if unimplementedObjectResourceNames[name] {
 			return nullResource{}
 		}
 	}
-	if obj := objr.bucket.objects[objr.name]; obj != nil {
-		objr.object = obj
-	}
 	return objr
 }
 
@@ -289,6 +461,14 @@ type bucketResource struct {
 	bucket *bucket // non-nil if the bucket already exists.
 }
 
+// existingBucket returns the bucket, failing if it does not exist.
+func (r bucketResource) existingBucket() *bucket {
+	if r.bucket == nil {
+		fatalf(404, "NoSuchBucket", "The specified bucket does not exist")
+	}
+	return r.bucket
+}
+
 // GET on a bucket lists the objects in the bucket.
 // http://docs.amazonwebservices.com/AmazonS3/latest/API/RESTBucketGET.html
 func (r bucketResource) get(a *action) interface{} {
@@ -381,13 +561,21 @@ func (s orderedObjects) Less(i, j int) bool {
 	return s[i].name < s[j].name
 }
 
+// etag returns the ETag of obj, without quotes.
+func (obj *object) etag() string {
+	if obj.parts > 0 {
+		return fmt.Sprintf("%x-%d", obj.checksum, obj.parts)
+	}
+	return hex.EncodeToString(obj.checksum)
+}
+
 func (obj *object) s3Key() s3.Key {
 	return s3.Key{
 		Key:          obj.name,
 		LastModified: obj.mtime.Format(timeFormat),
 		Size:         int64(len(obj.data)),
-		ETag:         fmt.Sprintf(`"%x"`, obj.checksum),
-		// TODO StorageClass
+		ETag:         `"` + obj.etag() + `"`,
+		StorageClass: obj.storageClass,
 		// TODO Owner
 	}
 }
@@ -398,7 +586,7 @@ func (r bucketResource) delete(a *action) interface{} {
 	if b == nil {
 		fatalf(404, "NoSuchBucket", "The specified bucket does not exist")
 	}
-	if len(b.objects) > 0 {
+	if len(b.objects) > 0 || len(b.versions) > 0 {
 		fatalf(400, "BucketNotEmpty", "The bucket you tried to delete is not empty")
 	}
 	delete(a.srv.buckets, b.name)
@@ -420,7 +608,9 @@ func (r bucketResource) put(a *action) interface{} {
 		r.bucket = &bucket{
 			name: r.name,
 			// TODO default acl
-			objects: make(map[string]*object),
+			objects:  make(map[string]*object),
+			versions: make(map[string][]*object),
+			uploads:  make(map[string]*upload),
 		}
 		a.srv.buckets[r.name] = r.bucket
 		created = true
@@ -429,10 +619,17 @@ func (r bucketResource) put(a *action) interface{} {
 		fatalf(409, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.")
 	}
 	r.bucket.acl = s3.ACL(a.req.Header.Get("x-amz-acl"))
+	r.bucket.acp = cannedACL(r.bucket.acl)
 	return nil
 }
 
-func (bucketResource) post(a *action) interface{} {
+func (r bucketResource) post(a *action) interface{} {
+	if _, ok := a.req.Form["delete"]; ok {
+		return r.deleteObjects(a)
+	}
+	if strings.HasPrefix(a.req.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.postObject(a)
+	}
 	fatalf(400, "Method", "bucket POST method not available")
 	return nil
 }
@@ -493,11 +690,17 @@ type objectResource struct {
 // GET on an object gets the contents of the object.
 // http://docs.amazonwebservices.com/AmazonS3/latest/API/RESTObjectGET.html
 func (objr objectResource) get(a *action) interface{} {
-	obj := objr.object
-	if obj == nil {
-		fatalf(404, "NoSuchKey", "The specified key does not exist.")
+	obj := objr.lookup(a)
+	checkCustomerKey(a.req.Header, "x-amz-", obj)
+	if a.req.Method != "HEAD" {
+		checkReadable(obj)
 	}
 	h := a.w.Header()
+	setEncryptionHeaders(h, obj.encryption)
+	setStorageHeaders(h, obj)
+	if objr.bucket.versioning != "" || obj.version != "" {
+		h.Set("x-amz-version-id", obj.versionId())
+	}
 	// add metadata
 	for name, d := range obj.meta {
 		h[name] = d
@@ -512,24 +715,36 @@ func (objr objectResource) get(a *action) interface{} {
 			h.Set(name, vals[0])
 		}
 	}
+	etag := obj.etag()
+	if m := a.req.Header.Get("If-Match"); m != "" && strings.Trim(m, `"`) != etag {
+		fatalf(412, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+	}
+	data := obj.data
+	status := http.StatusOK
 	if r := a.req.Header.Get("Range"); r != "" {
-		fatalf(400, "NotImplemented", "range unimplemented")
+		start, end, ok := parseRange(r, int64(len(data)))
+		if !ok {
+			fatalf(416, "InvalidRange", "The requested range is not satisfiable")
+		}
+		h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, len(data)))
+		data = data[start:end]
+		status = http.StatusPartialContent
 	}
 	// TODO Last-Modified-Since
 	// TODO If-Modified-Since
 	// TODO If-Unmodified-Since
-	// TODO If-Match
 	// TODO If-None-Match
 	// TODO Connection: close ??
 	// TODO x-amz-request-id
-	h.Set("Content-Length", fmt.Sprint(len(obj.data)))
-	h.Set("ETag", hex.EncodeToString(obj.checksum))
-	h.Set("Last-Modified", obj.mtime.Format(time.RFC1123))
+	h.Set("Content-Length", fmt.Sprint(len(data)))
+	h.Set("ETag", etag)
+	h.Set("Last-Modified", obj.mtime.UTC().Format(http.TimeFormat))
 	if a.req.Method == "HEAD" {
 		return nil
 	}
+	a.w.WriteHeader(status)
 	// TODO avoid holding the lock when writing data.
-	_, err := a.w.Write(obj.data)
+	_, err := a.w.Write(data)
 	if err != nil {
 		// we can't do much except just log the fact.
 		log.Printf("error writing data: %v", err)
@@ -537,6 +752,77 @@ func (objr objectResource) get(a *action) interface{} {
 	return nil
 }
 
+// parseRange returns the bounds of the single byte range r, the value of
+// a Range header, of an object of size bytes. Ranges that cannot be
+// parsed are ignored, as S3 does, by returning the whole object.
+func parseRange(r string, size int64) (start, end int64, ok bool) {
+	if !strings.HasPrefix(r, "bytes=") || strings.Contains(r, ",") {
+		return 0, size, true
+	}
+	spec := strings.TrimSpace(r[len("bytes="):])
+	i := strings.Index(spec, "-")
+	if i < 0 {
+		return 0, size, true
+	}
+	first, last := spec[:i], spec[i+1:]
+	if first == "" {
+		// The last n bytes.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil {
+			return 0, size, true
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size, true
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, size, true
+	}
+	end = size
+	if last != "" {
+		l, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || l < start {
+			return 0, size, true
+		}
+		if l+1 < end {
+			end = l + 1
+		}
+	}
+	if start >= size {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// lookup returns the object, or the version of it requested, failing if
+// there is none.
+func (objr objectResource) lookup(a *action) *object {
+	h := a.w.Header()
+	obj := objr.object
+	if objr.version != "" {
+		_, obj = objr.bucket.findVersion(objr.name, objr.version)
+		if obj == nil {
+			fatalf(404, "NoSuchVersion", "The specified version does not exist.")
+		}
+		if obj.deleteMarker {
+			h.Set("x-amz-delete-marker", "true")
+			fatalf(405, "MethodNotAllowed", "The specified method is not allowed against this resource.")
+		}
+	}
+	if obj == nil {
+		if hist := objr.bucket.history(objr.name); len(hist) > 0 && hist[0].deleteMarker {
+			h.Set("x-amz-delete-marker", "true")
+		}
+		fatalf(404, "NoSuchKey", "The specified key does not exist.")
+	}
+	return obj
+}
+
 var metaHeaders = map[string]bool{
 	"Content-MD5":         true,
 	"x-amz-acl":           true,
@@ -549,18 +835,60 @@ var metaHeaders = map[string]bool{
 func (objr objectResource) put(a *action) interface{} {
 	// TODO Cache-Control header
 	// TODO Expires header
-	// TODO x-amz-server-side-encryption
-	// TODO x-amz-storage-class
+	if src := a.req.Header.Get("x-amz-copy-source"); src != "" {
+		return objr.copy(a, src)
+	}
+
+	encryption := readEncryption(a)
+	storageClass := readStorageClass(a)
+	tags := readTags(a)
 
 	// TODO is this correct, or should we erase all previous metadata?
 	obj := objr.object
-	if obj == nil {
+	if obj == nil || objr.bucket.versioning != "" {
 		obj = &object{
 			name: objr.name,
 			meta: make(http.Header),
 		}
 	}
 
+	data, gotHash := readContent(a)
+	encoding := a.req.Header.Get("Content-Encoding")
+	chunked := strings.HasPrefix(encoding, "aws-chunked")
+	if chunked {
+		encoding = strings.TrimPrefix(strings.TrimPrefix(encoding, "aws-chunked"), ",")
+	}
+
+	// PUT request has been successful - save data and metadata
+	for key, values := range a.req.Header {
+		key = http.CanonicalHeaderKey(key)
+		if metaHeaders[key] || strings.HasPrefix(key, "X-Amz-Meta-") {
+			obj.meta[key] = values
+		}
+	}
+	if chunked && encoding != "" {
+		obj.meta.Set("Content-Encoding", encoding)
+	} else if chunked {
+		obj.meta.Del("Content-Encoding")
+	}
+	obj.data = data
+	obj.checksum = gotHash
+	obj.mtime = time.Now()
+	obj.acp = cannedACL(s3.ACL(a.req.Header.Get("x-amz-acl")))
+	obj.encryption = encryption
+	obj.storageClass = storageClass
+	obj.tags = tags
+	obj.restoreDone = time.Time{}
+	obj.restoreExpiry = time.Time{}
+	setEncryptionHeaders(a.w.Header(), encryption)
+	objr.store(a, obj)
+	return nil
+}
+
+// readContent returns the content sent by a request storing an object,
+// decoding it if it was sent with the aws-chunked content encoding, and
+// its MD5, checked against the Content-MD5 header if any.
+func readContent(a *action) (data, sum []byte) {
 	var expectHash []byte
 	if c := a.req.Header.Get("Content-MD5"); c != "" {
 		var err error
@@ -569,36 +897,175 @@ func (objr objectResource) put(a *action) interface{} {
 			fatalf(400, "InvalidDigest", "The Content-MD5 you specified was invalid")
 		}
 	}
-	sum := md5.New()
 	// TODO avoid holding lock while reading data.
-	data, err := ioutil.ReadAll(io.TeeReader(a.req.Body, sum))
+	data, err := ioutil.ReadAll(a.req.Body)
 	if err != nil {
 		fatalf(400, "TODO", "read error")
 	}
-	gotHash := sum.Sum(nil)
-	if expectHash != nil && bytes.Compare(gotHash, expectHash) != 0 {
-		fatalf(400, "BadDigest", "The Content-MD5 you specified did not match what we received")
-	}
 	if a.req.ContentLength >= 0 && int64(len(data)) != a.req.ContentLength {
 		fatalf(400, "IncompleteBody", "You did not provide the number of bytes specified by the Content-Length HTTP header")
 	}
+	if strings.HasPrefix(a.req.Header.Get("Content-Encoding"), "aws-chunked") {
+		data = decodeChunked(data, a.req.Header.Get("X-Amz-Decoded-Content-Length"))
+	}
+	gotHash := md5.Sum(data)
+	if expectHash != nil && bytes.Compare(gotHash[:], expectHash) != 0 {
+		fatalf(400, "BadDigest", "The Content-MD5 you specified did not match what we received")
+	}
+	return data, gotHash[:]
+}
 
-	// PUT request has been successful - save data and metadata
-	for key, values := range a.req.Header {
-		key = http.CanonicalHeaderKey(key)
-		if metaHeaders[key] || strings.HasPrefix(key, "X-Amz-Meta-") {
+// store makes obj the current version of the object.
+func (objr objectResource) store(a *action, obj *object) {
+	if objr.bucket.versioning == "" {
+		objr.bucket.objects[objr.name] = obj
+		return
+	}
+	a.srv.addVersion(objr.bucket, obj)
+	a.w.Header().Set("x-amz-version-id", obj.versionId())
+}
+
+// copy creates the object as a copy of the object src, given as
+// "bucket/key", optionally followed by "?versionId=" and a version.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectCOPY.html
+func (objr objectResource) copy(a *action, src string) interface{} {
+	b, from := copySource(a, src)
+	sum := md5.Sum(from.data)
+	obj := &object{
+		name:     objr.name,
+		meta:     make(http.Header),
+		checksum: sum[:],
+		data:     from.data,
+		mtime:    time.Now(),
+	}
+	if a.req.Header.Get("x-amz-metadata-directive") == "REPLACE" {
+		for key, values := range a.req.Header {
+			key = http.CanonicalHeaderKey(key)
+			if metaHeaders[key] || strings.HasPrefix(key, "X-Amz-Meta-") {
+				obj.meta[key] = values
+			}
+		}
+	} else {
+		for key, values := range from.meta {
 			obj.meta[key] = values
 		}
 	}
-	obj.data = data
-	obj.checksum = gotHash
-	obj.mtime = time.Now()
-	objr.bucket.objects[objr.name] = obj
-	return nil
+	obj.acp = cannedACL(s3.ACL(a.req.Header.Get("x-amz-acl")))
+	obj.encryption = readEncryption(a)
+	obj.storageClass = readStorageClass(a)
+	obj.tags = from.tags
+	if a.req.Header.Get("x-amz-tagging-directive") == "REPLACE" {
+		obj.tags = readTags(a)
+	}
+	setEncryptionHeaders(a.w.Header(), obj.encryption)
+	if b.versioning != "" || from.version != "" {
+		a.w.Header().Set("x-amz-copy-source-version-id", from.versionId())
+	}
+	objr.store(a, obj)
+	return &s3.CopyObjectResult{
+		ETag:         `"` + obj.etag() + `"`,
+		LastModified: obj.mtime.Format(timeFormat),
+	}
 }
 
+// copySource returns the object src, given as "bucket/key", optionally
+// followed by "?versionId=" and a version, to be copied, and its bucket.
+func copySource(a *action, src string) (*bucket, *object) {
+	src = strings.TrimPrefix(src, "/")
+	versionId := ""
+	if i := strings.Index(src, "?"); i >= 0 {
+		q, err := url.ParseQuery(src[i+1:])
+		if err != nil {
+			fatalf(400, "InvalidArgument", "Copy Source must mention the source bucket and key: sourcebucket/sourcekey")
+		}
+		src, versionId = src[:i], q.Get("versionId")
+	}
+	if s, err := url.QueryUnescape(src); err == nil {
+		src = s
+	}
+	i := strings.Index(src, "/")
+	if i < 0 {
+		fatalf(400, "InvalidArgument", "Copy Source must mention the source bucket and key: sourcebucket/sourcekey")
+	}
+	b := a.srv.buckets[src[:i]]
+	if b == nil {
+		fatalf(404, "NoSuchBucket", "The specified bucket does not exist")
+	}
+	from := b.objects[src[i+1:]]
+	if versionId != "" {
+		_, from = b.findVersion(src[i+1:], versionId)
+		if from == nil {
+			fatalf(404, "NoSuchVersion", "The specified version does not exist.")
+		}
+		if from.deleteMarker {
+			fatalf(400, "InvalidRequest", "The source of a copy request may not specifically refer to a delete marker by version id.")
+		}
+	}
+	if from == nil {
+		fatalf(404, "NoSuchKey", "The specified key does not exist.")
+	}
+	checkCustomerKey(a.req.Header, "x-amz-copy-source-", from)
+	checkReadable(from)
+	return b, from
+}
+
+// readBody returns the body of the request, decoding it if it was sent
+// with the aws-chunked content encoding.
+func readBody(a *action) []byte {
+	data, err := ioutil.ReadAll(a.req.Body)
+	if err != nil {
+		fatalf(400, "TODO", "read error")
+	}
+	if strings.HasPrefix(a.req.Header.Get("Content-Encoding"), "aws-chunked") {
+		data = decodeChunked(data, a.req.Header.Get("X-Amz-Decoded-Content-Length"))
+	}
+	return data
+}
+
+// decodeChunked returns the payload of a body sent with the aws-chunked
+// content encoding, checking its length against decodedLength.
+// Chunk signatures are not verified.
+func decodeChunked(body []byte, decodedLength string) []byte {
+	var data []byte
+	for {
+		i := bytes.Index(body, []byte("\r\n"))
+		if i < 0 {
+			fatalf(400, "IncompleteBody", "The request body terminated unexpectedly")
+		}
+		size := string(body[:i])
+		if j := strings.Index(size, ";"); j >= 0 {
+			size = size[:j]
+		}
+		n, err := strconv.ParseInt(size, 16, 64)
+		if err != nil || n < 0 || int64(len(body)-i-2) < n+2 {
+			fatalf(400, "IncompleteBody", "The request body terminated unexpectedly")
+		}
+		body = body[i+2:]
+		data = append(data, body[:n]...)
+		body = body[n+2:]
+		if n == 0 {
+			break
+		}
+	}
+	if decodedLength != "" && decodedLength != strconv.Itoa(len(data)) {
+		fatalf(400, "IncompleteBody", "You did not provide the number of bytes specified by the x-amz-decoded-content-length HTTP header")
+	}
+	return data
+}
+
+// DELETE on an object removes the object, or one of its versions.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectDELETE.html
 func (objr objectResource) delete(a *action) interface{} {
-	delete(objr.bucket.objects, objr.name)
+	deleted := a.srv.deleteObject(objr.bucket, objr.name, objr.version)
+	h := a.w.Header()
+	if deleted.DeleteMarker {
+		h.Set("x-amz-delete-marker", "true")
+	}
+	if deleted.DeleteMarkerVersionId != "" {
+		h.Set("x-amz-version-id", deleted.DeleteMarkerVersionId)
+	} else if deleted.VersionId != "" {
+		h.Set("x-amz-version-id", deleted.VersionId)
+	}
 	return nil
 }
 
@@ -0,0 +1,104 @@
+package s3test
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"github.com/goamz/goamz/s3"
+)
+
+// defaultKMSKeyId is the KMS key objects are encrypted with when SSE-KMS
+// is asked for without giving a key.
+const defaultKMSKeyId = "arn:aws:kms:us-east-1:123456789012:alias/aws/s3"
+
+// readEncryption returns the encryption a request storing an object asks
+// for. The data is kept in the clear: only the key of SSE-C objects is
+// checked when they are read.
+// http://docs.aws.amazon.com/AmazonS3/latest/dev/serv-side-encryption.html
+func readEncryption(a *action) s3.Encryption {
+	h := a.req.Header
+	e := s3.Encryption{
+		Algorithm: h.Get("x-amz-server-side-encryption"),
+		KMSKeyId:  h.Get("x-amz-server-side-encryption-aws-kms-key-id"),
+	}
+	switch e.Algorithm {
+	case "", s3.SSEAlgorithmAES256:
+		if e.KMSKeyId != "" || h.Get("x-amz-server-side-encryption-context") != "" {
+			fatalf(400, "InvalidArgument", "Server Side Encryption with AWS KMS managed key requires HTTP header x-amz-server-side-encryption : aws:kms")
+		}
+	case s3.SSEAlgorithmKMS:
+		if e.KMSKeyId == "" {
+			e.KMSKeyId = defaultKMSKeyId
+		}
+		if c := h.Get("x-amz-server-side-encryption-context"); c != "" {
+			data, err := base64.StdEncoding.DecodeString(c)
+			var context map[string]string
+			if err != nil || json.Unmarshal(data, &context) != nil {
+				fatalf(400, "InvalidArgument", "The header 'x-amz-server-side-encryption-context' shall be Base64-encoded UTF-8 string holding JSON which represents a string-string map")
+			}
+		}
+	default:
+		fatalf(400, "InvalidArgument", "The encryption method specified is not supported")
+	}
+	if keyMD5 := customerKeyMD5(h, "x-amz-"); keyMD5 != "" {
+		if e.Algorithm != "" {
+			fatalf(400, "InvalidArgument", "Server Side Encryption with Customer provided key is incompatible with the encryption method specified")
+		}
+		e.CustomerAlgorithm = s3.SSEAlgorithmAES256
+		e.CustomerKeyMD5 = keyMD5
+	}
+	return e
+}
+
+// customerKeyMD5 returns the MD5 digest of the customer provided key given
+// by the headers in h starting with prefix, or "" if there is none.
+func customerKeyMD5(h http.Header, prefix string) string {
+	alg := h.Get(prefix + "server-side-encryption-customer-algorithm")
+	key := h.Get(prefix + "server-side-encryption-customer-key")
+	keyMD5 := h.Get(prefix + "server-side-encryption-customer-key-MD5")
+	if alg == "" && key == "" && keyMD5 == "" {
+		return ""
+	}
+	if alg != s3.SSEAlgorithmAES256 {
+		fatalf(400, "InvalidEncryptionAlgorithmError", "The encryption request you specified is not valid. The valid value is AES256.")
+	}
+	data, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(data) != 32 {
+		fatalf(400, "InvalidArgument", "The secret key was invalid for the specified algorithm.")
+	}
+	sum := md5.Sum(data)
+	if base64.StdEncoding.EncodeToString(sum[:]) != keyMD5 {
+		fatalf(400, "InvalidArgument", "The calculated MD5 hash of the key did not match the hash that was provided.")
+	}
+	return keyMD5
+}
+
+// checkCustomerKey fails unless the headers in h starting with prefix give
+// the key obj was encrypted with, if it was encrypted with a customer
+// provided key, and none otherwise.
+func checkCustomerKey(h http.Header, prefix string, obj *object) {
+	keyMD5 := customerKeyMD5(h, prefix)
+	switch {
+	case obj.encryption.CustomerKeyMD5 == "" && keyMD5 != "":
+		fatalf(400, "InvalidRequest", "The encryption parameters are not applicable to this object.")
+	case obj.encryption.CustomerKeyMD5 != "" && keyMD5 == "":
+		fatalf(400, "InvalidRequest", "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.")
+	case keyMD5 != obj.encryption.CustomerKeyMD5:
+		fatalf(403, "AccessDenied", "Access Denied")
+	}
+}
+
+// setEncryptionHeaders sets the headers reporting the encryption e in h.
+func setEncryptionHeaders(h http.Header, e s3.Encryption) {
+	set := func(name, value string) {
+		if value != "" {
+			h.Set(name, value)
+		}
+	}
+	set("x-amz-server-side-encryption", e.Algorithm)
+	set("x-amz-server-side-encryption-aws-kms-key-id", e.KMSKeyId)
+	set("x-amz-server-side-encryption-customer-algorithm", e.CustomerAlgorithm)
+	set("x-amz-server-side-encryption-customer-key-MD5", e.CustomerKeyMD5)
+}
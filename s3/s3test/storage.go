@@ -0,0 +1,204 @@
+package s3test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goamz/goamz/s3"
+)
+
+var storageClasses = map[string]bool{
+	s3.StorageClassStandard:          true,
+	s3.StorageClassStandardIA:        true,
+	s3.StorageClassReducedRedundancy: true,
+	s3.StorageClassGlacier:           true,
+}
+
+// readStorageClass returns the storage class a request storing an object
+// asks for.
+func readStorageClass(a *action) string {
+	class := a.req.Header.Get("x-amz-storage-class")
+	if class == "" {
+		return s3.StorageClassStandard
+	}
+	if !storageClasses[class] {
+		fatalf(400, "InvalidStorageClass", "The storage class you specified is not valid")
+	}
+	return class
+}
+
+// checkTags fails if tags are not valid tags of an object.
+// http://docs.aws.amazon.com/AmazonS3/latest/dev/object-tagging.html
+func checkTags(tags []s3.Tag) {
+	if len(tags) > 10 {
+		fatalf(400, "BadRequest", "Object tags cannot be greater than 10")
+	}
+	seen := make(map[string]bool)
+	for _, t := range tags {
+		if t.Key == "" || len(t.Key) > 128 || len(t.Value) > 256 {
+			fatalf(400, "InvalidTag", "The TagKey or TagValue you have provided is invalid")
+		}
+		if seen[t.Key] {
+			fatalf(400, "InvalidTag", "Cannot provide multiple Tags with the same key")
+		}
+		seen[t.Key] = true
+	}
+}
+
+// readTags returns the tags given by the x-amz-tagging header of a request
+// storing an object.
+func readTags(a *action) []s3.Tag {
+	h := a.req.Header.Get("x-amz-tagging")
+	if h == "" {
+		return nil
+	}
+	var tags []s3.Tag
+	for _, kv := range strings.Split(h, "&") {
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			i = len(kv)
+			kv += "="
+		}
+		key, err1 := url.QueryUnescape(kv[:i])
+		value, err2 := url.QueryUnescape(kv[i+1:])
+		if err1 != nil || err2 != nil {
+			fatalf(400, "InvalidArgument", "The header 'x-amz-tagging' shall be encoded as UTF-8 then URLEncoded URL query parameters without tag name duplicates.")
+		}
+		tags = append(tags, s3.Tag{Key: key, Value: value})
+	}
+	checkTags(tags)
+	return tags
+}
+
+// setStorageHeaders sets the headers reporting the storage class, tags
+// and restoration of obj in h.
+func setStorageHeaders(h http.Header, obj *object) {
+	if obj.storageClass != s3.StorageClassStandard {
+		h.Set("x-amz-storage-class", obj.storageClass)
+	}
+	if len(obj.tags) > 0 {
+		h.Set("x-amz-tagging-count", strconv.Itoa(len(obj.tags)))
+	}
+	switch ongoing, restored := obj.restoreStatus(); {
+	case ongoing:
+		h.Set("x-amz-restore", `ongoing-request="true"`)
+	case restored:
+		h.Set("x-amz-restore", `ongoing-request="false", expiry-date="`+obj.restoreExpiry.UTC().Format(http.TimeFormat)+`"`)
+	}
+}
+
+// restoreStatus returns whether obj is being restored, or has a restored
+// copy available.
+func (obj *object) restoreStatus() (ongoing, restored bool) {
+	now := time.Now()
+	switch {
+	case obj.restoreDone.IsZero():
+		return false, false
+	case now.Before(obj.restoreDone):
+		return true, false
+	}
+	return false, now.Before(obj.restoreExpiry)
+}
+
+// checkReadable fails if the content of obj cannot be read because it is
+// archived in Glacier and has no restored copy available.
+func checkReadable(obj *object) {
+	if obj.storageClass != s3.StorageClassGlacier {
+		return
+	}
+	if _, restored := obj.restoreStatus(); !restored {
+		fatalf(403, "InvalidObjectState", "The operation is not valid for the object's storage class")
+	}
+}
+
+type tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	TagSet  []s3.Tag `xml:"TagSet>Tag"`
+}
+
+// taggingResource is the tag set of an object.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPUTtagging.html
+type taggingResource struct {
+	objectResource
+}
+
+func (r taggingResource) put(a *action) interface{} {
+	obj := r.lookup(a)
+	var t tagging
+	if err := xml.Unmarshal(readBody(a), &t); err != nil {
+		fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	checkTags(t.TagSet)
+	obj.tags = t.TagSet
+	return nil
+}
+
+func (r taggingResource) get(a *action) interface{} {
+	return &tagging{TagSet: r.lookup(a).tags}
+}
+
+func (r taggingResource) delete(a *action) interface{} {
+	r.lookup(a).tags = nil
+	a.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (r taggingResource) post(a *action) interface{} {
+	return notAllowed()
+}
+
+type restoreRequest struct {
+	Days int
+	Tier string `xml:"GlacierJobParameters>Tier"`
+}
+
+// restoreResource restores objects archived in Glacier. Restores take
+// Config.RestoreDelay to complete.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPOSTrestore.html
+type restoreResource struct {
+	objectResource
+}
+
+func (r restoreResource) post(a *action) interface{} {
+	obj := r.lookup(a)
+	if obj.storageClass != s3.StorageClassGlacier {
+		fatalf(403, "InvalidObjectState", "Restore is not allowed for the object's current storage class")
+	}
+	var req restoreRequest
+	if err := xml.Unmarshal(readBody(a), &req); err != nil || req.Days < 1 {
+		fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	switch req.Tier {
+	case "", s3.RestoreTierExpedited, s3.RestoreTierStandard, s3.RestoreTierBulk:
+	default:
+		fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	days := time.Duration(req.Days) * 24 * time.Hour
+	switch ongoing, restored := obj.restoreStatus(); {
+	case ongoing:
+		fatalf(409, "RestoreAlreadyInProgress", "Object restore is already in progress")
+	case restored:
+		obj.restoreExpiry = time.Now().Add(days)
+	default:
+		obj.restoreDone = time.Now().Add(a.srv.config.restoreDelay())
+		obj.restoreExpiry = obj.restoreDone.Add(days)
+		a.w.WriteHeader(http.StatusAccepted)
+	}
+	return nil
+}
+
+func (r restoreResource) get(a *action) interface{} {
+	return notAllowed()
+}
+
+func (r restoreResource) put(a *action) interface{} {
+	return notAllowed()
+}
+
+func (r restoreResource) delete(a *action) interface{} {
+	return notAllowed()
+}
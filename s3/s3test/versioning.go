@@ -0,0 +1,280 @@
+package s3test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goamz/goamz/s3"
+)
+
+// versioningResource is the versioning state of a bucket.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUTVersioningStatus.html
+type versioningResource struct {
+	bucketResource
+}
+
+func (r versioningResource) put(a *action) interface{} {
+	b := r.existingBucket()
+	var c s3.VersioningConfiguration
+	if err := xml.Unmarshal(readBody(a), &c); err != nil {
+		fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	if c.Status != s3.VersioningEnabled && c.Status != s3.VersioningSuspended {
+		fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	b.versioning = c.Status
+	return nil
+}
+
+func (r versioningResource) get(a *action) interface{} {
+	return &s3.VersioningConfiguration{Status: r.existingBucket().versioning}
+}
+
+func (r versioningResource) delete(a *action) interface{} {
+	return notAllowed()
+}
+
+func (r versioningResource) post(a *action) interface{} {
+	return notAllowed()
+}
+
+// versionId returns the version of obj as S3 reports it. Objects stored
+// before versioning was enabled have the "null" version.
+func (obj *object) versionId() string {
+	if obj.version == "" {
+		return "null"
+	}
+	return obj.version
+}
+
+// history returns the versions of the named object, including delete
+// markers, from the newest to the oldest.
+func (b *bucket) history(name string) []*object {
+	if hist, ok := b.versions[name]; ok {
+		return hist
+	}
+	if obj := b.objects[name]; obj != nil {
+		return []*object{obj}
+	}
+	return nil
+}
+
+// setHistory replaces the versions of the named object, making the newest
+// one current unless it is a delete marker.
+func (b *bucket) setHistory(name string, hist []*object) {
+	if len(hist) == 0 {
+		delete(b.versions, name)
+		delete(b.objects, name)
+		return
+	}
+	b.versions[name] = hist
+	if hist[0].deleteMarker {
+		delete(b.objects, name)
+	} else {
+		b.objects[name] = hist[0]
+	}
+}
+
+// findVersion returns the index in the history of the named object of the
+// given version, and the version itself, or -1 and nil if there is none.
+func (b *bucket) findVersion(name, versionId string) (int, *object) {
+	for i, obj := range b.history(name) {
+		if obj.versionId() == versionId {
+			return i, obj
+		}
+	}
+	return -1, nil
+}
+
+// addVersion stores obj as the newest version of its object, giving it a
+// new version, or the "null" version if versioning is suspended, which
+// replaces the previous "null" version.
+func (srv *Server) addVersion(b *bucket, obj *object) {
+	if b.versioning == s3.VersioningEnabled {
+		srv.versionSeq++
+		obj.version = fmt.Sprintf("%016x", srv.versionSeq)
+	} else {
+		obj.version = "null"
+	}
+	hist := []*object{obj}
+	for _, old := range b.history(obj.name) {
+		if old.versionId() != obj.version {
+			hist = append(hist, old)
+		}
+	}
+	// Versions are ordered by time in listings, so keep them apart
+	// even when they are stored within the same millisecond.
+	if len(hist) > 1 && !obj.mtime.After(hist[1].mtime.Add(time.Millisecond)) {
+		obj.mtime = hist[1].mtime.Add(time.Millisecond)
+	}
+	b.setHistory(obj.name, hist)
+}
+
+// deleteObject removes the named object from b, as the DELETE object and
+// Multi-Object Delete requests do. Without a version, it leaves a delete
+// marker in place of the object if b is versioned.
+func (srv *Server) deleteObject(b *bucket, name, versionId string) s3.DeletedObject {
+	deleted := s3.DeletedObject{Key: name, VersionId: versionId}
+	if versionId != "" {
+		if i, obj := b.findVersion(name, versionId); obj != nil {
+			hist := append([]*object(nil), b.history(name)[:i]...)
+			b.setHistory(name, append(hist, b.history(name)[i+1:]...))
+			deleted.DeleteMarker = obj.deleteMarker
+		}
+		return deleted
+	}
+	if b.versioning == "" {
+		delete(b.objects, name)
+		return deleted
+	}
+	marker := &object{
+		name:         name,
+		mtime:        time.Now(),
+		deleteMarker: true,
+	}
+	srv.addVersion(b, marker)
+	deleted.DeleteMarker = true
+	deleted.DeleteMarkerVersionId = marker.version
+	return deleted
+}
+
+// POST on a bucket with the delete parameter removes several objects.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/multiobjectdeleteapi.html
+func (r bucketResource) deleteObjects(a *action) interface{} {
+	b := r.existingBucket()
+	var objects s3.Delete
+	if err := xml.Unmarshal(readBody(a), &objects); err != nil {
+		fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	if len(objects.Objects) == 0 || len(objects.Objects) > 1000 {
+		fatalf(400, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	result := &s3.DeleteResult{}
+	for _, obj := range objects.Objects {
+		deleted := a.srv.deleteObject(b, obj.Key, obj.VersionId)
+		if !objects.Quiet {
+			result.Deleted = append(result.Deleted, deleted)
+		}
+	}
+	return result
+}
+
+// versionsResource lists the versions of the objects in a bucket.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketGETVersion.html
+type versionsResource struct {
+	bucketResource
+}
+
+func (r versionsResource) get(a *action) interface{} {
+	b := r.existingBucket()
+	prefix := a.req.Form.Get("prefix")
+	delimiter := a.req.Form.Get("delimiter")
+	keyMarker := a.req.Form.Get("key-marker")
+	versionIdMarker := a.req.Form.Get("version-id-marker")
+	maxKeys := 1000
+	if s := a.req.Form.Get("max-keys"); s != "" {
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 0 {
+			fatalf(400, "InvalidArgument", "invalid value for max-keys: %q", s)
+		}
+		if i > 0 {
+			maxKeys = i
+		}
+	}
+
+	var names []string
+	for name := range b.objects {
+		if _, ok := b.versions[name]; !ok && strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	for name := range b.versions {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	resp := &s3.VersionsResp{
+		Name:            b.name,
+		Prefix:          prefix,
+		Delimiter:       delimiter,
+		KeyMarker:       keyMarker,
+		VersionIdMarker: versionIdMarker,
+		MaxKeys:         maxKeys,
+	}
+	n := 0
+	full := func() bool {
+		if n < maxKeys {
+			n++
+			return false
+		}
+		resp.IsTruncated = true
+		return true
+	}
+Names:
+	for _, name := range names {
+		if delimiter != "" {
+			if i := strings.Index(name[len(prefix):], delimiter); i >= 0 {
+				common := name[:len(prefix)+i+len(delimiter)]
+				last := len(resp.CommonPrefixes) - 1
+				if common <= keyMarker || last >= 0 && resp.CommonPrefixes[last] == common {
+					continue
+				}
+				if full() {
+					break
+				}
+				resp.CommonPrefixes = append(resp.CommonPrefixes, common)
+				resp.NextKeyMarker, resp.NextVersionIdMarker = common, ""
+				continue
+			}
+		}
+		if name < keyMarker || name == keyMarker && versionIdMarker == "" {
+			continue
+		}
+		skip := name == keyMarker
+		for i, obj := range b.history(name) {
+			if skip {
+				skip = obj.versionId() != versionIdMarker
+				continue
+			}
+			if full() {
+				break Names
+			}
+			v := s3.Version{
+				Key:          name,
+				VersionId:    obj.versionId(),
+				IsLatest:     i == 0,
+				LastModified: obj.mtime.Format(timeFormat),
+			}
+			if obj.deleteMarker {
+				resp.DeleteMarkers = append(resp.DeleteMarkers, v)
+			} else {
+				v.ETag = `"` + obj.etag() + `"`
+				v.Size = int64(len(obj.data))
+				resp.Versions = append(resp.Versions, v)
+			}
+			resp.NextKeyMarker, resp.NextVersionIdMarker = name, v.VersionId
+		}
+	}
+	if !resp.IsTruncated {
+		resp.NextKeyMarker, resp.NextVersionIdMarker = "", ""
+	}
+	return resp
+}
+
+func (r versionsResource) put(a *action) interface{} {
+	return notAllowed()
+}
+
+func (r versionsResource) delete(a *action) interface{} {
+	return notAllowed()
+}
+
+func (r versionsResource) post(a *action) interface{} {
+	return notAllowed()
+}
@@ -4,10 +4,15 @@ import (
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
+	"fmt"
 	"github.com/goamz/goamz/aws"
 	"log"
+	"net/http"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var b64 = base64.StdEncoding
@@ -17,12 +22,16 @@ var b64 = base64.StdEncoding
 
 var s3ParamsToSign = map[string]bool{
 	"acl":                          true,
+	"cors":                         true,
+	"lifecycle":                    true,
 	"location":                     true,
 	"logging":                      true,
 	"notification":                 true,
 	"partNumber":                   true,
 	"policy":                       true,
 	"requestPayment":               true,
+	"restore":                      true,
+	"tagging":                      true,
 	"torrent":                      true,
 	"uploadId":                     true,
 	"uploads":                      true,
@@ -112,3 +121,91 @@ func sign(auth aws.Auth, method, canonicalPath string, params, headers map[strin
 		log.Printf("Signature: %q", signature)
 	}
 }
+
+// ----------------------------------------------------------------------------
+// S3 signing with AWS Signature Version 4
+// (http://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html)
+
+// The hash of an empty payload.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// The size of the chunks payloads of known length are sent in.
+var streamingChunkSize = 64 * 1024
+
+// signV4 signs req, whose URL is u, with AWS Signature Version 4.
+//
+// A payload whose length is known from the Content-Length header is
+// signed chunk by chunk as it is sent, through req.encode. Other payloads
+// are not signed. If req has an Expires parameter, the signature is put
+// in the query string of the request like with sign.
+func signV4(auth aws.Auth, region aws.Region, req *request, u *url.URL) error {
+	// Drop what signing req before left behind.
+	for _, k := range []string{"Authorization", "X-Amz-Date", "X-Amz-Content-Sha256", "X-Amz-Security-Token"} {
+		delete(req.headers, k)
+	}
+	if v, ok := req.headers["X-Amz-Decoded-Content-Length"]; ok {
+		req.headers["Content-Length"] = v
+		delete(req.headers, "X-Amz-Decoded-Content-Length")
+		if e := strings.TrimPrefix(req.headers.Get("Content-Encoding"), "aws-chunked"); e == "" {
+			delete(req.headers, "Content-Encoding")
+		} else {
+			req.headers.Set("Content-Encoding", strings.TrimPrefix(e, ","))
+		}
+	}
+	req.encode = nil
+
+	hreq, err := http.NewRequest(req.method, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	// Content-Length is sent from the length of the body, so it is left
+	// out of the signed headers unless the payload is streamed.
+	length := int64(-1)
+	for k, v := range req.headers {
+		if k == "Content-Length" {
+			length, _ = strconv.ParseInt(v[0], 10, 64)
+			continue
+		}
+		hreq.Header[k] = v
+	}
+
+	signer := aws.NewV4Signer(auth, "s3", region)
+	if v, ok := req.params["Expires"]; ok {
+		expires, err := strconv.ParseInt(v[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("bad Expires parameter %q", v[0])
+		}
+		delete(req.params, "Expires")
+		hreq.URL.RawQuery = req.params.Encode()
+		d := time.Unix(expires, 0).Sub(time.Now())
+		if d < 0 {
+			// Already expired, which X-Amz-Expires cannot be.
+			d = 0
+		}
+		signer.Presign(hreq, d)
+		req.params = hreq.URL.Query()
+		return nil
+	}
+
+	if auth.Token() != "" {
+		hreq.Header.Set("X-Amz-Security-Token", auth.Token())
+	}
+	switch {
+	case req.payload == nil:
+		hreq.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+		signer.Sign(hreq)
+	case length >= 0:
+		req.encode = signer.SignStreaming(hreq, length, streamingChunkSize)
+	default:
+		hreq.Header.Set("X-Amz-Content-Sha256", aws.UnsignedPayload)
+		signer.Sign(hreq)
+	}
+	if length >= 0 && req.encode == nil {
+		hreq.Header.Set("Content-Length", strconv.FormatInt(length, 10))
+	}
+	req.headers = hreq.Header
+	if debug {
+		log.Printf("Signed headers: %v", req.headers)
+	}
+	return nil
+}
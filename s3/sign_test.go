@@ -1,6 +1,13 @@
 package s3_test
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+
 	"github.com/goamz/goamz/aws"
 	"github.com/goamz/goamz/s3"
 	. "gopkg.in/check.v1"
@@ -130,3 +137,158 @@ func (s *S) TestSignExampleUnicodeKeys(c *C) {
 	expected := "AWS 0PN5J17HBGZHT7JJ3X82:dxhSBHoI6eVSPcXJqEghlUzZMnY="
 	c.Assert(headers["Authorization"], DeepEquals, []string{expected})
 }
+
+func (s *S) TestSignLifecycle(c *C) {
+	method := "GET"
+	path := "/johnsmith/"
+	params := map[string][]string{
+		"lifecycle": {""},
+	}
+	headers := map[string][]string{
+		"Host": {"johnsmith.s3.amazonaws.com"},
+		"Date": {"Tue, 27 Mar 2007 19:44:46 +0000"},
+	}
+	s3.Sign(testAuth, method, path, params, headers)
+	expected := "AWS 0PN5J17HBGZHT7JJ3X82:kNxT4ebVlNQzPAGopwOSjTeOJN8="
+	c.Assert(headers["Authorization"], DeepEquals, []string{expected})
+}
+
+func (s *S) TestSignCORS(c *C) {
+	method := "GET"
+	path := "/johnsmith/"
+	params := map[string][]string{
+		"cors": {""},
+	}
+	headers := map[string][]string{
+		"Host": {"johnsmith.s3.amazonaws.com"},
+		"Date": {"Tue, 27 Mar 2007 19:44:46 +0000"},
+	}
+	s3.Sign(testAuth, method, path, params, headers)
+	expected := "AWS 0PN5J17HBGZHT7JJ3X82:e8DNYMv9Avx/AMNtXHthfu/q7vU="
+	c.Assert(headers["Authorization"], DeepEquals, []string{expected})
+}
+
+func (s *S) TestSignTagging(c *C) {
+	method := "GET"
+	path := "/johnsmith/photos/puppy.jpg"
+	params := map[string][]string{
+		"tagging": {""},
+	}
+	headers := map[string][]string{
+		"Host": {"johnsmith.s3.amazonaws.com"},
+		"Date": {"Tue, 27 Mar 2007 19:44:46 +0000"},
+	}
+	s3.Sign(testAuth, method, path, params, headers)
+	expected := "AWS 0PN5J17HBGZHT7JJ3X82:6rqPatECfHRBD2YhHD+F4huVMBc="
+	c.Assert(headers["Authorization"], DeepEquals, []string{expected})
+}
+
+func (s *S) TestSignRestore(c *C) {
+	method := "POST"
+	path := "/johnsmith/photos/puppy.jpg"
+	params := map[string][]string{
+		"restore": {""},
+	}
+	headers := map[string][]string{
+		"Host": {"johnsmith.s3.amazonaws.com"},
+		"Date": {"Tue, 27 Mar 2007 19:44:46 +0000"},
+	}
+	s3.Sign(testAuth, method, path, params, headers)
+	expected := "AWS 0PN5J17HBGZHT7JJ3X82:Zqp5I5wjZsDxYXE8POZEqubhJU4="
+	c.Assert(headers["Authorization"], DeepEquals, []string{expected})
+}
+
+// Signature Version 4 docs: http://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html
+
+func (s *S) v4Bucket(name string) *s3.Bucket {
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	region := aws.Region{Name: "faux-region-1", S3Endpoint: testServer.URL, S3Signer: aws.V4Signature}
+	return s3.New(auth, region).Bucket(name)
+}
+
+func (s *S) TestSignV4Get(c *C) {
+	testServer.Response(200, nil, "content")
+
+	b := s.v4Bucket("bucket")
+	data, err := b.Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "content")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("Authorization"), Matches, "AWS4-HMAC-SHA256 Credential=abc/[0-9]{8}/faux-region-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=[0-9a-f]{64}")
+	c.Assert(req.Header.Get("X-Amz-Content-Sha256"), Equals, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	c.Assert(req.Header.Get("X-Amz-Date"), Not(Equals), "")
+	c.Assert(req.Header.Get("Date"), Equals, "")
+}
+
+func (s *S) TestSignV4PutReader(c *C) {
+	defer s3.SetStreamingChunkSize(4)()
+	testServer.Response(200, nil, "")
+
+	b := s.v4Bucket("bucket")
+	err := b.PutReader("name", strings.NewReader("content"), 7, "content-type", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "PUT")
+	c.Assert(req.Header.Get("Content-Encoding"), Equals, "aws-chunked")
+	c.Assert(req.Header.Get("X-Amz-Decoded-Content-Length"), Equals, "7")
+	c.Assert(req.Header.Get("X-Amz-Content-Sha256"), Equals, aws.StreamingPayload)
+	c.Assert(req.Header.Get("Authorization"), Matches, ".*SignedHeaders=content-encoding;content-length;content-type;host;x-amz-acl;.*")
+	body, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	c.Assert(req.ContentLength, Equals, int64(len(body)))
+	chunks := bytes.Split(body, []byte("\r\n"))
+	c.Assert(chunks, HasLen, 7)
+	c.Assert(string(chunks[0]), Matches, "4;chunk-signature=[0-9a-f]{64}")
+	c.Assert(string(chunks[1]), Equals, "cont")
+	c.Assert(string(chunks[2]), Matches, "3;chunk-signature=[0-9a-f]{64}")
+	c.Assert(string(chunks[3]), Equals, "ent")
+	c.Assert(string(chunks[4]), Matches, "0;chunk-signature=[0-9a-f]{64}")
+}
+
+func (s *S) TestSignV4PutExpiredToken(c *C) {
+	testServer.Response(400, nil, ExpiredTokenErrorDump)
+	testServer.Response(200, nil, "")
+
+	p := aws.NewRefreshingProvider(&tokenProvider{})
+	region := aws.Region{Name: "faux-region-1", S3Endpoint: testServer.URL, S3Signer: aws.V4Signature}
+	b := s3.NewWithProvider(p, region).Bucket("bucket")
+	err := b.PutReader("name", strings.NewReader("content"), 7, "content-type", s3.Private, s3.Options{})
+	c.Assert(err, IsNil)
+
+	reqs := testServer.WaitRequests(2)
+	for i, req := range reqs {
+		c.Assert(req.Header.Get("Authorization"), Matches, fmt.Sprintf("AWS4-HMAC-SHA256 Credential=key%d/.*", i+1))
+		c.Assert(req.Header["X-Amz-Security-Token"], DeepEquals, []string{fmt.Sprintf("token%d", i+1)})
+		c.Assert(req.Header.Get("Content-Encoding"), Equals, "aws-chunked")
+		c.Assert(req.Header["X-Amz-Decoded-Content-Length"], DeepEquals, []string{"7"})
+		body, err := ioutil.ReadAll(req.Body)
+		c.Assert(err, IsNil)
+		c.Assert(string(body), Matches, "(?s)7;chunk-signature=[0-9a-f]{64}\r\ncontent\r\n.*")
+	}
+}
+
+func (s *S) TestSignV4SignedURL(c *C) {
+	b := s.v4Bucket("bucket")
+	u, err := url.Parse(b.SignedURL("name", time.Now().Add(time.Hour)))
+	c.Assert(err, IsNil)
+	c.Assert(u.Path, Equals, "/bucket/name")
+	q := u.Query()
+	c.Assert(q.Get("X-Amz-Algorithm"), Equals, "AWS4-HMAC-SHA256")
+	c.Assert(q.Get("X-Amz-Credential"), Matches, "abc/[0-9]{8}/faux-region-1/s3/aws4_request")
+	c.Assert(q.Get("X-Amz-SignedHeaders"), Equals, "host")
+	c.Assert(q.Get("X-Amz-Expires"), Matches, "3[56][0-9]{2}")
+	c.Assert(q.Get("X-Amz-Signature"), Matches, "[0-9a-f]{64}")
+	c.Assert(q.Get("Expires"), Equals, "")
+
+	u, err = url.Parse(b.UploadSignedURL("name", "PUT", "text/plain", time.Now().Add(time.Hour)))
+	c.Assert(err, IsNil)
+	c.Assert(u.Query().Get("X-Amz-SignedHeaders"), Equals, "content-type;host")
+}
+
+func (s *S) TestSignV4SignedURLExpired(c *C) {
+	u, err := url.Parse(s.v4Bucket("bucket").SignedURL("name", time.Now().Add(-time.Hour)))
+	c.Assert(err, IsNil)
+	c.Assert(u.Query().Get("X-Amz-Expires"), Equals, "0")
+}
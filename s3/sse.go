@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+)
+
+// The server-side encryption algorithms S3 supports.
+const (
+	SSEAlgorithmAES256 = "AES256"
+	SSEAlgorithmKMS    = "aws:kms"
+)
+
+// Encryption describes how S3 encrypted an object, as reported by the
+// headers of the responses to requests storing or retrieving it.
+type Encryption struct {
+	// Algorithm is the algorithm of S3 managed encryption, SSEAlgorithmAES256
+	// or SSEAlgorithmKMS, and KMSKeyId the KMS key used by the latter.
+	Algorithm string
+	KMSKeyId  string
+
+	// CustomerAlgorithm is SSEAlgorithmAES256 if the object is encrypted
+	// with a key provided by the customer, and CustomerKeyMD5 the base64
+	// encoded MD5 digest of that key.
+	CustomerAlgorithm string
+	CustomerKeyMD5    string
+}
+
+// ResponseEncryption returns the encryption reported by the headers h of
+// a response, such as one returned by GetResponse or Head.
+func ResponseEncryption(h http.Header) Encryption {
+	return Encryption{
+		Algorithm:         h.Get("x-amz-server-side-encryption"),
+		KMSKeyId:          h.Get("x-amz-server-side-encryption-aws-kms-key-id"),
+		CustomerAlgorithm: h.Get("x-amz-server-side-encryption-customer-algorithm"),
+		CustomerKeyMD5:    h.Get("x-amz-server-side-encryption-customer-key-MD5"),
+	}
+}
+
+// SSECustomerKeyHeaders returns the headers needed to read an object
+// encrypted with the 256-bit customer provided key, for use with
+// GetResponseWithHeaders and Head.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/dev/ServerSideEncryptionCustomerKeys.html
+// for details.
+func SSECustomerKeyHeaders(key []byte) map[string][]string {
+	headers := make(map[string][]string)
+	addCustomerKeyHeaders(headers, "x-amz-", key)
+	return headers
+}
+
+// addCustomerKeyHeaders adds to headers those giving the customer provided
+// key, with names starting with prefix.
+func addCustomerKeyHeaders(headers map[string][]string, prefix string, key []byte) {
+	sum := md5.Sum(key)
+	headers[prefix+"server-side-encryption-customer-algorithm"] = []string{SSEAlgorithmAES256}
+	headers[prefix+"server-side-encryption-customer-key"] = []string{base64.StdEncoding.EncodeToString(key)}
+	headers[prefix+"server-side-encryption-customer-key-MD5"] = []string{base64.StdEncoding.EncodeToString(sum[:])}
+}
+
+// addKMSHeaders adds to headers those asking for encryption with the KMS
+// key keyId, or the default KMS key of the account if empty, and the
+// encryption context given.
+func addKMSHeaders(headers map[string][]string, keyId string, context map[string]string) {
+	headers["x-amz-server-side-encryption"] = []string{SSEAlgorithmKMS}
+	if keyId != "" {
+		headers["x-amz-server-side-encryption-aws-kms-key-id"] = []string{keyId}
+	}
+	if len(context) != 0 {
+		// Marshalling a map of strings cannot fail.
+		data, _ := json.Marshal(context)
+		headers["x-amz-server-side-encryption-context"] = []string{base64.StdEncoding.EncodeToString(data)}
+	}
+}
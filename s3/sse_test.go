@@ -0,0 +1,135 @@
+package s3_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/goamz/goamz/s3"
+	. "gopkg.in/check.v1"
+)
+
+var customerKey = []byte("0123456789abcdef0123456789abcdef")
+
+func customerKeyMD5() string {
+	sum := md5.Sum(customerKey)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func (s *S) TestPutObjectSSE(c *C) {
+	testServer.Response(200, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.Put("name", []byte("content"), "text/plain", s3.Private, s3.Options{SSE: true})
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("x-amz-server-side-encryption"), Equals, "AES256")
+	c.Assert(req.Header.Get("x-amz-server-side-encryption-aws-kms-key-id"), Equals, "")
+}
+
+func (s *S) TestPutObjectSSEKMS(c *C) {
+	testServer.Response(200, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.Put("name", []byte("content"), "text/plain", s3.Private, s3.Options{
+		SSEKMSKeyId:   "arn:aws:kms:us-east-1:123456789012:key/key-id",
+		SSEKMSContext: map[string]string{"purpose": "test"},
+	})
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("x-amz-server-side-encryption"), Equals, "aws:kms")
+	c.Assert(req.Header.Get("x-amz-server-side-encryption-aws-kms-key-id"), Equals, "arn:aws:kms:us-east-1:123456789012:key/key-id")
+	context, err := base64.StdEncoding.DecodeString(req.Header.Get("x-amz-server-side-encryption-context"))
+	c.Assert(err, IsNil)
+	c.Assert(string(context), Equals, `{"purpose":"test"}`)
+}
+
+func (s *S) TestPutObjectSSEKMSDefaultKey(c *C) {
+	testServer.Response(200, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.Put("name", []byte("content"), "text/plain", s3.Private, s3.Options{SSEKMS: true})
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("x-amz-server-side-encryption"), Equals, "aws:kms")
+	c.Assert(req.Header["X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"], IsNil)
+	c.Assert(req.Header["X-Amz-Server-Side-Encryption-Context"], IsNil)
+}
+
+func (s *S) TestPutReaderSSECustomerKey(c *C) {
+	testServer.Response(200, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.PutReader("name", bytes.NewReader([]byte("content")), 7, "text/plain", s3.Private, s3.Options{SSECustomerKey: customerKey})
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("x-amz-server-side-encryption"), Equals, "")
+	c.Assert(req.Header.Get("x-amz-server-side-encryption-customer-algorithm"), Equals, "AES256")
+	c.Assert(req.Header.Get("x-amz-server-side-encryption-customer-key"), Equals, base64.StdEncoding.EncodeToString(customerKey))
+	c.Assert(req.Header.Get("x-amz-server-side-encryption-customer-key-MD5"), Equals, customerKeyMD5())
+}
+
+func (s *S) TestGetResponseSSECustomerKey(c *C) {
+	testServer.Response(200, map[string]string{
+		"x-amz-server-side-encryption-customer-algorithm": "AES256",
+		"x-amz-server-side-encryption-customer-key-MD5":   customerKeyMD5(),
+	}, "content")
+
+	b := s.s3.Bucket("bucket")
+	resp, err := b.GetResponseWithHeaders("name", s3.SSECustomerKeyHeaders(customerKey))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(s3.ResponseEncryption(resp.Header), Equals, s3.Encryption{
+		CustomerAlgorithm: "AES256",
+		CustomerKeyMD5:    customerKeyMD5(),
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.Header.Get("x-amz-server-side-encryption-customer-key"), Equals, base64.StdEncoding.EncodeToString(customerKey))
+	c.Assert(req.Header.Get("x-amz-server-side-encryption-customer-key-MD5"), Equals, customerKeyMD5())
+}
+
+func (s *S) TestPutCopySSE(c *C) {
+	testServer.Response(200, map[string]string{
+		"x-amz-server-side-encryption":                "aws:kms",
+		"x-amz-server-side-encryption-aws-kms-key-id": "key-id",
+	}, `<CopyObjectResult><ETag>"9b2cf535f27731c974343645a3985328"</ETag><LastModified>2009-10-28T22:32:00.000Z</LastModified></CopyObjectResult>`)
+
+	b := s.s3.Bucket("bucket")
+	options := s3.CopyOptions{CopySourceSSECustomerKey: customerKey}
+	options.SSEKMSKeyId = "key-id"
+	res, err := b.PutCopy("name", s3.Private, options, "source-bucket/source")
+	c.Assert(err, IsNil)
+	c.Assert(res.Encryption, Equals, s3.Encryption{Algorithm: "aws:kms", KMSKeyId: "key-id"})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("x-amz-server-side-encryption"), Equals, "aws:kms")
+	c.Assert(req.Header.Get("x-amz-server-side-encryption-customer-key"), Equals, "")
+	c.Assert(req.Header.Get("x-amz-copy-source-server-side-encryption-customer-algorithm"), Equals, "AES256")
+	c.Assert(req.Header.Get("x-amz-copy-source-server-side-encryption-customer-key"), Equals, base64.StdEncoding.EncodeToString(customerKey))
+	c.Assert(req.Header.Get("x-amz-copy-source-server-side-encryption-customer-key-MD5"), Equals, customerKeyMD5())
+}
+
+func (s *S) TestPutPartSSECustomerKey(c *C) {
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(200, map[string]string{"ETag": `"26f90efd10d614f100252ff56d88dad8"`}, "")
+
+	b := s.s3.Bucket("sample")
+	multi, err := b.InitMultiOptions("multi", "text/plain", s3.Private, s3.Options{SSECustomerKey: customerKey})
+	c.Assert(err, IsNil)
+	c.Assert(multi.SSECustomerKey, DeepEquals, customerKey)
+
+	_, err = multi.PutPart(1, bytes.NewReader([]byte("<part 1>")))
+	c.Assert(err, IsNil)
+
+	for _, req := range []*http.Request{testServer.WaitRequest(), testServer.WaitRequest()} {
+		c.Assert(req.Header.Get("x-amz-server-side-encryption-customer-algorithm"), Equals, "AES256")
+		c.Assert(req.Header.Get("x-amz-server-side-encryption-customer-key-MD5"), Equals, customerKeyMD5())
+	}
+}
@@ -0,0 +1,199 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The storage classes of objects.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/dev/storage-class-intro.html
+// for how they differ.
+const (
+	StorageClassStandard          = "STANDARD"
+	StorageClassStandardIA        = "STANDARD_IA"
+	StorageClassReducedRedundancy = "REDUCED_REDUNDANCY"
+	StorageClassGlacier           = "GLACIER"
+)
+
+// Tag is a tag of an object.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+type tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	TagSet  []Tag    `xml:"TagSet>Tag"`
+}
+
+// encodeTags returns tags encoded as in the x-amz-tagging header.
+func encodeTags(tags []Tag) string {
+	var buf bytes.Buffer
+	for i, t := range tags {
+		if i > 0 {
+			buf.WriteByte('&')
+		}
+		buf.WriteString(url.QueryEscape(t.Key))
+		buf.WriteByte('=')
+		buf.WriteString(url.QueryEscape(t.Value))
+	}
+	return buf.String()
+}
+
+// GetTagging returns the tags of the object at path.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectGETtagging.html
+// for details.
+func (b *Bucket) GetTagging(path string) ([]Tag, error) {
+	var t tagging
+	if err := b.getSubresource(path, "tagging", &t); err != nil {
+		return nil, err
+	}
+	return t.TagSet, nil
+}
+
+// PutTagging replaces the tags of the object at path. S3 allows at most 10
+// tags per object.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPUTtagging.html
+// for details.
+func (b *Bucket) PutTagging(path string, tags []Tag) error {
+	return b.putSubresourceXML(path, "tagging", &tagging{TagSet: tags})
+}
+
+// DeleteTagging removes all the tags of the object at path.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectDELETEtagging.html
+// for details.
+func (b *Bucket) DeleteTagging(path string) error {
+	return b.delSubresource(path, "tagging")
+}
+
+// The retrieval tiers of restore requests, from the fastest and most
+// expensive to the slowest and cheapest.
+const (
+	RestoreTierExpedited = "Expedited"
+	RestoreTierStandard  = "Standard"
+	RestoreTierBulk      = "Bulk"
+)
+
+type restoreRequest struct {
+	XMLName              xml.Name `xml:"RestoreRequest"`
+	Days                 int
+	GlacierJobParameters *glacierJobParameters `xml:",omitempty"`
+}
+
+type glacierJobParameters struct {
+	Tier string
+}
+
+// RestoreObject starts restoring a temporary copy of the object at path,
+// stored in the GLACIER storage class, which is kept for the given number
+// of days once restored. The copy is retrieved using tier, or
+// RestoreTierStandard if empty. Restoring an object already restored
+// only changes for how long it is kept.
+//
+// Restoring takes from minutes to hours: use RestoreStatus to find when
+// the copy is available. If the object is already being restored, the
+// error returned has the code RestoreAlreadyInProgress.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPOSTrestore.html
+// for details.
+func (b *Bucket) RestoreObject(path string, days int, tier string) error {
+	req := &restoreRequest{Days: days}
+	if tier != "" {
+		req.GlacierJobParameters = &glacierJobParameters{tier}
+	}
+	doc, err := xml.Marshal(req)
+	if err != nil {
+		return err
+	}
+	data := makeXmlBuffer(doc).Bytes()
+	sum := md5.Sum(data)
+	for attempt := b.S3.attempts(); attempt.Next(); {
+		r := &request{
+			method: "POST",
+			bucket: b.Name,
+			path:   path,
+			params: url.Values{"restore": {""}},
+			headers: map[string][]string{
+				"Content-Length": {strconv.Itoa(len(data))},
+				"Content-MD5":    {base64.StdEncoding.EncodeToString(sum[:])},
+				"Content-Type":   {"text/xml"},
+			},
+			payload: bytes.NewReader(data),
+		}
+		err := b.S3.query(r, nil)
+		if shouldRetry(err) && attempt.HasNext() {
+			continue
+		}
+		return err
+	}
+	panic("unreachable")
+}
+
+// RestoreStatus is the status of the restoration of an object.
+type RestoreStatus struct {
+	// Ongoing is whether the object is being restored. Once it is
+	// restored, the copy is kept until Expiry.
+	Ongoing bool
+	Expiry  time.Time
+}
+
+// ParseRestore returns the restore status given by the x-amz-restore
+// header in h, as returned by Head or GetResponse, or nil if the object
+// was never restored, or its restored copy expired.
+func ParseRestore(h http.Header) (*RestoreStatus, error) {
+	v := h.Get("x-amz-restore")
+	if v == "" {
+		return nil, nil
+	}
+	// The header is of the form
+	// ongoing-request="false", expiry-date="Fri, 23 Dec 2012 00:00:00 GMT"
+	status := &RestoreStatus{}
+	for v = strings.TrimSpace(v); v != ""; v = strings.TrimLeft(v, ", ") {
+		i := strings.Index(v, `="`)
+		if i < 0 {
+			return nil, fmt.Errorf("bad x-amz-restore header %q", h.Get("x-amz-restore"))
+		}
+		name := v[:i]
+		v = v[i+2:]
+		j := strings.Index(v, `"`)
+		if j < 0 {
+			return nil, fmt.Errorf("bad x-amz-restore header %q", h.Get("x-amz-restore"))
+		}
+		value := v[:j]
+		v = v[j+1:]
+		switch name {
+		case "ongoing-request":
+			status.Ongoing = value == "true"
+		case "expiry-date":
+			t, err := http.ParseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("bad x-amz-restore header %q: %v", h.Get("x-amz-restore"), err)
+			}
+			status.Expiry = t
+		}
+	}
+	return status, nil
+}
+
+// RestoreStatus returns the restore status of the object at path, or nil
+// if the object was never restored, or its restored copy expired.
+func (b *Bucket) RestoreStatus(path string) (*RestoreStatus, error) {
+	resp, err := b.Head(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return ParseRestore(resp.Header)
+}
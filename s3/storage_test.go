@@ -0,0 +1,160 @@
+package s3_test
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/goamz/goamz/s3"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestPutStorageClassAndTags(c *C) {
+	testServer.Response(200, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.Put("name", []byte("content"), "text/plain", s3.Private, s3.Options{
+		StorageClass: s3.StorageClassStandardIA,
+		Tags:         []s3.Tag{{"project", "goamz"}, {"owner", "a b&c"}},
+	})
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("x-amz-storage-class"), Equals, "STANDARD_IA")
+	c.Assert(req.Header.Get("x-amz-tagging"), Equals, "project=goamz&owner=a+b%26c")
+}
+
+func (s *S) TestPutCopyTaggingDirective(c *C) {
+	testServer.Response(200, nil, `<CopyObjectResult><ETag>"9b2cf535f27731c974343645a3985328"</ETag><LastModified>2009-10-28T22:32:00.000Z</LastModified></CopyObjectResult>`)
+
+	b := s.s3.Bucket("bucket")
+	options := s3.CopyOptions{TaggingDirective: "REPLACE"}
+	options.Tags = []s3.Tag{{"k", "v"}}
+	options.StorageClass = s3.StorageClassGlacier
+	_, err := b.PutCopy("name", s3.Private, options, "bucket/source")
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("x-amz-tagging-directive"), Equals, "REPLACE")
+	c.Assert(req.Header.Get("x-amz-tagging"), Equals, "k=v")
+	c.Assert(req.Header.Get("x-amz-storage-class"), Equals, "GLACIER")
+}
+
+func (s *S) TestGetTagging(c *C) {
+	testServer.Response(200, nil, GetTaggingDump)
+
+	b := s.s3.Bucket("bucket")
+	tags, err := b.GetTagging("name")
+	c.Assert(err, IsNil)
+	c.Assert(tags, DeepEquals, []s3.Tag{{"project", "goamz"}, {"owner", "me"}})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.URL.Path, Equals, "/bucket/name")
+	c.Assert(req.Form["tagging"], DeepEquals, []string{""})
+}
+
+func (s *S) TestPutTagging(c *C) {
+	testServer.Response(200, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.PutTagging("name", []s3.Tag{{"project", "goamz"}})
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "PUT")
+	c.Assert(req.URL.Path, Equals, "/bucket/name")
+	c.Assert(req.Form["tagging"], DeepEquals, []string{""})
+	c.Assert(req.Header.Get("Content-MD5"), Not(Equals), "")
+	c.Assert(readAll(req.Body), Equals, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<Tagging><TagSet><Tag><Key>project</Key><Value>goamz</Value></Tag></TagSet></Tagging>`)
+}
+
+func (s *S) TestDeleteTagging(c *C) {
+	testServer.Response(204, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.DeleteTagging("name")
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "DELETE")
+	c.Assert(req.URL.Path, Equals, "/bucket/name")
+	c.Assert(req.Form["tagging"], DeepEquals, []string{""})
+}
+
+func (s *S) TestRestoreObject(c *C) {
+	testServer.Response(202, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.RestoreObject("name", 7, s3.RestoreTierBulk)
+	c.Assert(err, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "POST")
+	c.Assert(req.URL.Path, Equals, "/bucket/name")
+	c.Assert(req.Form["restore"], DeepEquals, []string{""})
+	c.Assert(req.Header.Get("Content-MD5"), Not(Equals), "")
+	c.Assert(readAll(req.Body), Equals, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<RestoreRequest><Days>7</Days><GlacierJobParameters><Tier>Bulk</Tier></GlacierJobParameters></RestoreRequest>`)
+}
+
+func (s *S) TestRestoreObjectRetry(c *C) {
+	testServer.Response(500, nil, InternalErrorDump)
+	testServer.Response(202, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.RestoreObject("name", 1, "")
+	c.Assert(err, IsNil)
+
+	body := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<RestoreRequest><Days>1</Days></RestoreRequest>`
+	reqs := testServer.WaitRequests(2)
+	c.Assert(readAll(reqs[0].Body), Equals, body)
+	c.Assert(readAll(reqs[1].Body), Equals, body)
+}
+
+func (s *S) TestRestoreObjectInProgress(c *C) {
+	s.DisableRetries()
+	testServer.Response(409, nil, `<Error><Code>RestoreAlreadyInProgress</Code><Message>Object restore is already in progress</Message></Error>`)
+
+	b := s.s3.Bucket("bucket")
+	err := b.RestoreObject("name", 1, "")
+	c.Assert(err, FitsTypeOf, new(s3.Error))
+	c.Assert(err.(*s3.Error).Code, Equals, "RestoreAlreadyInProgress")
+
+	req := testServer.WaitRequest()
+	c.Assert(readAll(req.Body), Equals, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<RestoreRequest><Days>1</Days></RestoreRequest>`)
+}
+
+func (s *S) TestRestoreStatus(c *C) {
+	testServer.Response(200, map[string]string{"x-amz-restore": `ongoing-request="true"`}, "")
+	testServer.Response(200, map[string]string{
+		"x-amz-restore": `ongoing-request="false", expiry-date="Fri, 23 Dec 2012 00:00:00 GMT"`,
+	}, "")
+	testServer.Response(200, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	status, err := b.RestoreStatus("name")
+	c.Assert(err, IsNil)
+	c.Assert(status, DeepEquals, &s3.RestoreStatus{Ongoing: true})
+
+	status, err = b.RestoreStatus("name")
+	c.Assert(err, IsNil)
+	c.Assert(status.Ongoing, Equals, false)
+	c.Assert(status.Expiry.Equal(time.Date(2012, 12, 23, 0, 0, 0, 0, time.UTC)), Equals, true)
+
+	status, err = b.RestoreStatus("name")
+	c.Assert(err, IsNil)
+	c.Assert(status, IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "HEAD")
+}
+
+func (s *S) TestParseRestoreBad(c *C) {
+	for _, v := range []string{`ongoing-request`, `ongoing-request="false`, `expiry-date="yesterday"`} {
+		_, err := s3.ParseRestore(http.Header{"X-Amz-Restore": {v}})
+		c.Assert(err, ErrorMatches, "bad x-amz-restore header .*")
+	}
+}
@@ -0,0 +1,382 @@
+package s3
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The defaults used by uploaders created by NewUploader.
+const (
+	DefaultUploadPartSize    = 5 * 1024 * 1024
+	DefaultUploadConcurrency = 5
+	DefaultPartRetries       = 3
+	DefaultPartRetryDelay    = time.Second
+)
+
+// An Uploader sends objects to S3 using multipart uploads, reading the
+// content from any io.Reader and sending several parts at once.
+//
+// The content is read into buffers of PartSize bytes, so at most
+// (Concurrency+1)*PartSize bytes are held in memory at any time.
+type Uploader struct {
+	Bucket *Bucket
+
+	// PartSize is the size of the parts sent. S3 requires all parts but
+	// the last one to be at least 5MB in size.
+	PartSize int64
+
+	// Concurrency is the number of parts sent at once.
+	Concurrency int
+
+	// PartRetries is the number of times a part that could not be
+	// sent is sent again, on top of the retries done for every S3
+	// request, before the upload fails. Parts rejected by S3 as bad
+	// requests are not retried.
+	PartRetries int
+
+	// PartRetryDelay is how long to wait before sending a part again.
+	// It doubles after each retry of the same part.
+	PartRetryDelay time.Duration
+
+	// Progress, if set, is called after each part is sent, with the
+	// part and the number of bytes sent so far. Calls are never
+	// concurrent, but parts may complete out of order.
+	Progress func(part Part, sent int64)
+}
+
+// NewUploader returns an uploader sending objects to b using the default
+// settings.
+func NewUploader(b *Bucket) *Uploader {
+	return &Uploader{
+		Bucket:         b,
+		PartSize:       DefaultUploadPartSize,
+		Concurrency:    DefaultUploadConcurrency,
+		PartRetries:    DefaultPartRetries,
+		PartRetryDelay: DefaultPartRetryDelay,
+	}
+}
+
+// Upload stores all of r at path using a new multipart upload, which is
+// completed once all the parts have been sent. If sending any part fails,
+// the multipart upload is aborted and the error returned.
+func (u *Uploader) Upload(path string, r io.Reader, contType string, perm ACL) error {
+	m, err := u.Bucket.InitMulti(path, contType, perm)
+	if err != nil {
+		return err
+	}
+	parts, err := u.PutAll(m, r)
+	if err == nil {
+		err = m.Complete(parts)
+	}
+	if err != nil {
+		m.Abort()
+		return err
+	}
+	return nil
+}
+
+// PutAll sends all of r as the parts of m, starting from part 1, and
+// returns them ordered by part number. Once all the parts have been
+// sent, the parts S3 holds for m are checked against them.
+//
+// Unlike Upload, PutAll neither completes nor aborts m.
+func (u *Uploader) PutAll(m *Multi, r io.Reader) ([]Part, error) {
+	partSize := u.partSize()
+	s := u.newPartSender(m)
+	// Buffers are allocated on first use and handed back once their
+	// part has been sent.
+	free := make(chan []byte, s.concurrency+1)
+	for i := 0; i < cap(free); i++ {
+		free <- nil
+	}
+Read:
+	for n := 1; ; n++ {
+		var buf []byte
+		select {
+		case buf = <-free:
+		case <-s.failed:
+			break Read
+		}
+		if buf == nil {
+			buf = make([]byte, partSize)
+		}
+		size, err := io.ReadFull(r, buf[:cap(buf)])
+		if err == io.EOF && n > 1 {
+			break
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			s.fail(err)
+			break
+		}
+		// An empty reader is sent as a single empty part.
+		data := buf[:size]
+		if !s.send(n, bytes.NewReader(data), func() { free <- data }) {
+			break
+		}
+		if err != nil {
+			break
+		}
+	}
+	return s.wait()
+}
+
+// Resume stores all of r at path, carrying on with the most recent
+// unfinished multipart upload of path if there is one, and starting a new
+// one otherwise. The upload is completed once all the parts are held by
+// S3. If Resume fails, the multipart upload is left in place so that a
+// later call can resume it.
+//
+// See ResumeMulti for how parts sent before are reused.
+func (u *Uploader) Resume(path string, r ReaderAtSeeker, contType string, perm ACL) error {
+	multis, _, err := u.Bucket.ListMulti(path, "")
+	if err != nil && !hasCode(err, "NoSuchUpload") {
+		return err
+	}
+	var m *Multi
+	for _, multi := range multis {
+		// Uploads of the same key are listed oldest first.
+		if multi.Key == path {
+			m = multi
+		}
+	}
+	if m == nil {
+		m, err = u.Bucket.InitMulti(path, contType, perm)
+		if err != nil {
+			return err
+		}
+	}
+	parts, err := u.ResumeMulti(m, r)
+	if err != nil {
+		return err
+	}
+	return m.Complete(parts)
+}
+
+// ResumeMulti is like PutAll, but reuses the parts m already holds. Each
+// part found is checked against the content of r it stands for, and is
+// sent again if either its size or its ETag, the MD5 of its content,
+// differs. Parts must be laid out with the same PartSize as when they
+// were first sent for any of them to be reused.
+//
+// Reused parts are reported to Progress as they are checked.
+func (u *Uploader) ResumeMulti(m *Multi, r ReaderAtSeeker) ([]Part, error) {
+	old, err := m.ListParts()
+	if err != nil && !hasCode(err, "NoSuchUpload") {
+		return nil, err
+	}
+	held := make(map[int]Part, len(old))
+	for _, p := range old {
+		held[p.N] = p
+	}
+	totalSize, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	partSize := u.partSize()
+	s := u.newPartSender(m)
+	// An empty reader is sent as a single empty part.
+	for n, offset := 1, int64(0); offset < totalSize || n == 1; n, offset = n+1, offset+partSize {
+		size := partSize
+		if offset+size > totalSize {
+			size = totalSize - offset
+		}
+		section := io.NewSectionReader(r, offset, size)
+		if p, ok := held[n]; ok && p.Size == size {
+			_, md5hex, _, err := seekerInfo(section)
+			if err != nil {
+				s.fail(err)
+				break
+			}
+			if p.ETag == `"`+md5hex+`"` {
+				s.add(p)
+				continue
+			}
+		}
+		if !s.send(n, section, nil) {
+			break
+		}
+	}
+	return s.wait()
+}
+
+func (u *Uploader) partSize() int64 {
+	if u.PartSize <= 0 {
+		return DefaultUploadPartSize
+	}
+	return u.PartSize
+}
+
+// A partSender sends the parts of a multipart upload on a pool of
+// workers, and gathers them.
+type partSender struct {
+	u           *Uploader
+	m           *Multi
+	concurrency int
+	jobs        chan partJob
+	wg          sync.WaitGroup
+
+	mu     sync.Mutex
+	parts  partSlice
+	sent   int64
+	failed chan struct{}
+	err    error
+}
+
+type partJob struct {
+	put  func() (Part, error) // Sends the part once.
+	done func()
+}
+
+func (u *Uploader) newPartSender(m *Multi) *partSender {
+	s := &partSender{
+		u:           u,
+		m:           m,
+		concurrency: u.Concurrency,
+		jobs:        make(chan partJob),
+		failed:      make(chan struct{}),
+	}
+	if s.concurrency < 1 {
+		s.concurrency = 1
+	}
+	for i := 0; i < s.concurrency; i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+	return s
+}
+
+func (s *partSender) work() {
+	defer s.wg.Done()
+	for j := range s.jobs {
+		select {
+		case <-s.failed:
+		default:
+			part, err := s.u.retryPart(s.m, j.put, s.failed)
+			if err != nil {
+				s.fail(err)
+			} else {
+				s.add(part)
+			}
+		}
+		if j.done != nil {
+			j.done()
+		}
+	}
+}
+
+// send queues part n, read from r, to be sent. done, if not nil, is called
+// once r is no longer needed. send reports false if the upload has
+// already failed.
+func (s *partSender) send(n int, r io.ReadSeeker, done func()) bool {
+	put := func() (Part, error) { return s.m.PutPart(n, r) }
+	return s.queue(partJob{put, done})
+}
+
+// sendCopy queues part n, copied from size bytes of source at offset, to
+// be copied. It reports false if the upload has already failed.
+func (s *partSender) sendCopy(n int, source string, offset, size int64) bool {
+	put := func() (Part, error) { return s.m.PutPartCopy(n, source, offset, size) }
+	return s.queue(partJob{put, nil})
+}
+
+func (s *partSender) queue(j partJob) bool {
+	select {
+	case s.jobs <- j:
+		return true
+	case <-s.failed:
+		if j.done != nil {
+			j.done()
+		}
+		return false
+	}
+}
+
+// add records part as held by S3 and reports progress.
+func (s *partSender) add(part Part) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.parts = append(s.parts, part)
+	s.sent += part.Size
+	if s.u.Progress != nil && s.err == nil {
+		s.u.Progress(part, s.sent)
+	}
+}
+
+func (s *partSender) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+		close(s.failed)
+	}
+}
+
+// wait waits for the queued parts to be sent and returns all the parts,
+// ordered by part number, once checked against those S3 holds.
+func (s *partSender) wait() ([]Part, error) {
+	close(s.jobs)
+	s.wg.Wait()
+	if s.err != nil {
+		return nil, s.err
+	}
+	sort.Sort(s.parts)
+	if err := s.u.checkParts(s.m, s.parts); err != nil {
+		return nil, err
+	}
+	return s.parts, nil
+}
+
+// retryPart sends a part of m with put, retrying as configured. It gives
+// up early once failed is closed.
+func (u *Uploader) retryPart(m *Multi, put func() (Part, error), failed <-chan struct{}) (Part, error) {
+	delay := u.PartRetryDelay
+	for retry := 0; ; retry++ {
+		part, err := put()
+		if err == nil || retry >= u.PartRetries || !retryTransfer(m.Bucket.S3, err) {
+			return part, err
+		}
+		select {
+		case <-time.After(delay):
+		case <-failed:
+			return part, err
+		}
+		delay *= 2
+	}
+}
+
+// retryTransfer reports whether a part of an upload or download through
+// s3 that failed with err may succeed if transferred again.
+func retryTransfer(s3 *S3, err error) bool {
+	if ctx := s3.ctx; ctx != nil && ctx.Err() != nil {
+		return false
+	}
+	if e, ok := err.(*Error); ok {
+		// Server errors and throttling are worth retrying, but
+		// requests S3 rejected will keep being rejected.
+		return e.StatusCode >= 500 || e.StatusCode == 429 || e.Code == "RequestTimeout"
+	}
+	return true
+}
+
+// checkParts verifies that S3 holds parts for m, as they were sent.
+func (u *Uploader) checkParts(m *Multi, parts []Part) error {
+	listed, err := m.ListParts()
+	if err != nil {
+		return err
+	}
+	found := make(map[int]Part, len(listed))
+	for _, p := range listed {
+		found[p.N] = p
+	}
+	for _, p := range parts {
+		if l, ok := found[p.N]; !ok || l.ETag != p.ETag || l.Size != p.Size {
+			return fmt.Errorf("part %d of multipart upload %s is missing or was overwritten", p.N, m.UploadId)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package s3_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goamz/goamz/s3"
+	. "gopkg.in/check.v1"
+)
+
+// listPartsDump returns a ListParts response holding parts.
+func listPartsDump(parts ...s3.Part) string {
+	var buf strings.Builder
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?><ListPartsResult><IsTruncated>false</IsTruncated>`)
+	for _, p := range parts {
+		fmt.Fprintf(&buf, "<Part><PartNumber>%d</PartNumber><ETag>%s</ETag><Size>%d</Size></Part>", p.N, p.ETag, p.Size)
+	}
+	buf.WriteString("</ListPartsResult>")
+	return buf.String()
+}
+
+func (s *S) newUploader() *s3.Uploader {
+	u := s3.NewUploader(s.s3.Bucket("sample"))
+	u.PartSize = 5
+	u.Concurrency = 1
+	u.PartRetryDelay = time.Millisecond
+	return u
+}
+
+func (s *S) TestUploaderUpload(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(200, map[string]string{"ETag": `"etag1"`}, "")
+	testServer.Response(200, map[string]string{"ETag": `"etag2"`}, "")
+	testServer.Response(200, map[string]string{"ETag": `"etag3"`}, "")
+	testServer.Response(200, nil, listPartsDump(
+		s3.Part{N: 1, ETag: `"etag1"`, Size: 5},
+		s3.Part{N: 2, ETag: `"etag2"`, Size: 5},
+		s3.Part{N: 3, ETag: `"etag3"`, Size: 4},
+	))
+	testServer.Response(200, nil, "")
+
+	var sent []int64
+	u := s.newUploader()
+	u.Progress = func(part s3.Part, n int64) {
+		sent = append(sent, n)
+	}
+	// Hide the Seeker of the reader.
+	r := struct{ io.Reader }{strings.NewReader("part1part2last")}
+	err := u.Upload("multi", r, "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+	c.Assert(sent, DeepEquals, []int64{5, 10, 14})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "POST")
+	c.Assert(req.Form["uploads"], DeepEquals, []string{""})
+
+	for i, body := range []string{"part1", "part2", "last"} {
+		req = testServer.WaitRequest()
+		c.Assert(req.Method, Equals, "PUT")
+		c.Assert(req.Form["partNumber"], DeepEquals, []string{fmt.Sprint(i + 1)})
+		c.Assert(readAll(req.Body), Equals, body)
+	}
+
+	req = testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.Form.Get("uploadId"), Matches, "JNbR_.*")
+
+	req = testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "POST")
+	c.Assert(readAll(req.Body), Matches, `.*<PartNumber>3</PartNumber><ETag>&#34;etag3&#34;</ETag>.*`)
+}
+
+func (s *S) TestUploaderEmpty(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(200, map[string]string{"ETag": `"etag1"`}, "")
+	testServer.Response(200, nil, listPartsDump(s3.Part{N: 1, ETag: `"etag1"`}))
+
+	b := s.s3.Bucket("sample")
+	multi, err := b.InitMulti("multi", "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+
+	parts, err := s.newUploader().PutAll(multi, strings.NewReader(""))
+	c.Assert(err, IsNil)
+	c.Assert(parts, DeepEquals, []s3.Part{{N: 1, ETag: `"etag1"`, Size: 0}})
+
+	testServer.WaitRequest()
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["partNumber"], DeepEquals, []string{"1"})
+	c.Assert(readAll(req.Body), Equals, "")
+}
+
+func (s *S) TestUploaderConcurrent(c *C) {
+	s.DisableRetries()
+	const n = 8
+	etag := map[string]string{"ETag": `"etag"`}
+	testServer.Response(200, nil, InitMultiResultDump)
+	var listed []s3.Part
+	for i := 1; i <= n; i++ {
+		testServer.Response(200, etag, "")
+		listed = append(listed, s3.Part{N: i, ETag: `"etag"`, Size: 5})
+	}
+	testServer.Response(200, nil, listPartsDump(listed...))
+	testServer.Response(200, nil, "")
+
+	var mu sync.Mutex
+	var last int64
+	u := s.newUploader()
+	u.Concurrency = 3
+	u.Progress = func(part s3.Part, sent int64) {
+		mu.Lock()
+		last = sent
+		mu.Unlock()
+	}
+	err := u.Upload("multi", strings.NewReader(strings.Repeat("12345", n)), "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+	c.Assert(last, Equals, int64(5*n))
+
+	reqs := testServer.WaitRequests(n + 3)
+	seen := make(map[string]bool)
+	for _, req := range reqs[1 : n+1] {
+		c.Assert(req.Method, Equals, "PUT")
+		c.Assert(readAll(req.Body), Equals, "12345")
+		seen[req.Form.Get("partNumber")] = true
+	}
+	c.Assert(seen, HasLen, n)
+}
+
+func (s *S) TestUploaderRetriesPart(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(500, nil, InternalErrorDump)
+	testServer.Response(200, map[string]string{"ETag": `"etag1"`}, "")
+	testServer.Response(200, nil, listPartsDump(s3.Part{N: 1, ETag: `"etag1"`, Size: 4}))
+	testServer.Response(200, nil, "")
+
+	err := s.newUploader().Upload("multi", strings.NewReader("part"), "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+
+	reqs := testServer.WaitRequests(5)
+	c.Assert(reqs[1].Method, Equals, "PUT")
+	c.Assert(reqs[2].Method, Equals, "PUT")
+	c.Assert(readAll(reqs[2].Body), Equals, "part")
+	c.Assert(reqs[4].Method, Equals, "POST")
+}
+
+func (s *S) TestUploaderAbortsOnFatalError(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(403, nil, AccessDeniedErrorDump)
+	testServer.Response(204, nil, "")
+
+	err := s.newUploader().Upload("multi", strings.NewReader("part1part2"), "text/plain", s3.Private)
+	c.Assert(err, ErrorMatches, "Access Denied")
+
+	reqs := testServer.WaitRequests(3)
+	c.Assert(reqs[1].Method, Equals, "PUT")
+	c.Assert(reqs[2].Method, Equals, "DELETE")
+	c.Assert(reqs[2].Form.Get("uploadId"), Matches, "JNbR_.*")
+}
+
+func (s *S) TestUploaderPartOverwritten(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(200, map[string]string{"ETag": `"etag1"`}, "")
+	testServer.Response(200, nil, listPartsDump(s3.Part{N: 1, ETag: `"other"`, Size: 4}))
+	testServer.Response(204, nil, "")
+
+	err := s.newUploader().Upload("multi", strings.NewReader("part"), "text/plain", s3.Private)
+	c.Assert(err, ErrorMatches, "part 1 of multipart upload JNbR_.* is missing or was overwritten")
+
+	reqs := testServer.WaitRequests(4)
+	c.Assert(reqs[3].Method, Equals, "DELETE")
+}
+
+var resumeListMultiDump = `
+<?xml version="1.0" encoding="UTF-8"?>
+<ListMultipartUploadsResult>
+  <IsTruncated>false</IsTruncated>
+  <Upload><Key>multi</Key><UploadId>older</UploadId></Upload>
+  <Upload><Key>multi</Key><UploadId>newer</UploadId></Upload>
+  <Upload><Key>multi/other</Key><UploadId>other</UploadId></Upload>
+</ListMultipartUploadsResult>
+`
+
+func (s *S) TestUploaderResume(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, nil, resumeListMultiDump)
+	testServer.Response(200, nil, listPartsDump(
+		s3.Part{N: 1, ETag: `"ffc88b4ca90a355f8ddba6b2c3b2af5c"`, Size: 5}, // MD5 of part1
+		s3.Part{N: 2, ETag: `"stale"`, Size: 5},
+	))
+	testServer.Response(200, map[string]string{"ETag": `"etag2"`}, "")
+	testServer.Response(200, map[string]string{"ETag": `"etag3"`}, "")
+	testServer.Response(200, nil, listPartsDump(
+		s3.Part{N: 1, ETag: `"ffc88b4ca90a355f8ddba6b2c3b2af5c"`, Size: 5},
+		s3.Part{N: 2, ETag: `"etag2"`, Size: 5},
+		s3.Part{N: 3, ETag: `"etag3"`, Size: 4},
+	))
+	testServer.Response(200, nil, "")
+
+	var sent []int64
+	u := s.newUploader()
+	u.Progress = func(part s3.Part, n int64) {
+		sent = append(sent, n)
+	}
+	err := u.Resume("multi", strings.NewReader("part1part2last"), "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+	c.Assert(sent, DeepEquals, []int64{5, 10, 14})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.Form["uploads"], DeepEquals, []string{""})
+	c.Assert(req.Form["prefix"], DeepEquals, []string{"multi"})
+
+	req = testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.Form["uploadId"], DeepEquals, []string{"newer"})
+
+	for _, part := range []struct{ n, body string }{{"2", "part2"}, {"3", "last"}} {
+		req = testServer.WaitRequest()
+		c.Assert(req.Method, Equals, "PUT")
+		c.Assert(req.Form["uploadId"], DeepEquals, []string{"newer"})
+		c.Assert(req.Form["partNumber"], DeepEquals, []string{part.n})
+		c.Assert(readAll(req.Body), Equals, part.body)
+	}
+
+	testServer.WaitRequest()
+	req = testServer.WaitRequest()
+	c.Assert(req.Method, Equals, "POST")
+	c.Assert(req.Form["uploadId"], DeepEquals, []string{"newer"})
+	c.Assert(readAll(req.Body), Matches, `.*<PartNumber>1</PartNumber><ETag>&#34;ffc88b4ca90a355f8ddba6b2c3b2af5c&#34;</ETag>.*`)
+}
+
+func (s *S) TestUploaderResumeNoUpload(c *C) {
+	s.DisableRetries()
+	testServer.Response(200, nil, ListMultiResultDump)
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(404, nil, NoSuchUploadErrorDump)
+	testServer.Response(200, map[string]string{"ETag": `"etag1"`}, "")
+	testServer.Response(200, nil, listPartsDump(s3.Part{N: 1, ETag: `"etag1"`, Size: 4}))
+	testServer.Response(200, nil, "")
+
+	err := s.newUploader().Resume("multi", strings.NewReader("part"), "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+
+	reqs := testServer.WaitRequests(6)
+	c.Assert(reqs[1].Method, Equals, "POST")
+	c.Assert(reqs[1].Form["uploads"], DeepEquals, []string{""})
+	c.Assert(reqs[3].Method, Equals, "PUT")
+	c.Assert(reqs[5].Method, Equals, "POST")
+}
@@ -0,0 +1,110 @@
+package s3
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// The versioning statuses of buckets. A bucket that never had versioning
+// enabled reports no status at all.
+const (
+	VersioningEnabled   = "Enabled"
+	VersioningSuspended = "Suspended"
+)
+
+// VersioningConfiguration holds the versioning state of a bucket.
+//
+// Once versioning is enabled, every object stored gets a new version
+// rather than replacing the previous one, and deleting an object only
+// hides its versions behind a delete marker. Suspending versioning stores
+// new objects with the "null" version, replacing any previous one.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/dev/Versioning.html
+// for an overview.
+type VersioningConfiguration struct {
+	XMLName   xml.Name `xml:"VersioningConfiguration"`
+	Status    string   `xml:",omitempty"` // VersioningEnabled or VersioningSuspended
+	MfaDelete string   `xml:",omitempty"`
+}
+
+// PutBucketVersioning changes the versioning state of b.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUTVersioningStatus.html
+// for details.
+func (b *Bucket) PutBucketVersioning(c *VersioningConfiguration) error {
+	return b.putBucketSubresourceXML("versioning", c)
+}
+
+// GetBucketVersioning returns the versioning state of b. Its Status is
+// empty if versioning was never enabled on b.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketGETversioningStatus.html
+// for details.
+func (b *Bucket) GetBucketVersioning() (*VersioningConfiguration, error) {
+	c := &VersioningConfiguration{}
+	if err := b.getBucketSubresource("versioning", c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func versionParams(versionId string) url.Values {
+	return url.Values{"versionId": {versionId}}
+}
+
+// GetVersion retrieves the given version of an object, as Get does for
+// its current version.
+func (b *Bucket) GetVersion(path, versionId string) (data []byte, err error) {
+	rc, err := b.GetVersionReader(path, versionId)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// GetVersionReader retrieves the given version of an object, returning
+// the body of the HTTP response.
+// It is the caller's responsibility to call Close on rc when
+// finished reading.
+func (b *Bucket) GetVersionReader(path, versionId string) (rc io.ReadCloser, err error) {
+	resp, err := b.GetVersionResponse(path, versionId, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// GetVersionResponse retrieves the given version of an object, sending
+// the custom headers given, and returns the HTTP response.
+// It is the caller's responsibility to call Close on the response body
+// when finished reading.
+func (b *Bucket) GetVersionResponse(path, versionId string, headers map[string][]string) (*http.Response, error) {
+	return b.getResponse(path, versionParams(versionId), headers)
+}
+
+// HeadVersion HEADs the given version of an object, as Head does for its
+// current version. A delete marker is reported as an error with the
+// status code 405.
+func (b *Bucket) HeadVersion(path, versionId string, headers map[string][]string) (*http.Response, error) {
+	return b.head(path, versionParams(versionId), headers)
+}
+
+// DelVersion permanently removes the given version of an object. Removing
+// the current version makes the previous one current; removing a delete
+// marker makes the object visible again.
+//
+// See http://docs.aws.amazon.com/AmazonS3/latest/dev/DeletingObjectVersions.html
+// for details.
+func (b *Bucket) DelVersion(path, versionId string) error {
+	return b.del(path, versionParams(versionId))
+}
+
+// PutCopyVersion copies the given version of the object source, given as
+// "bucket/key", to path in b, as PutCopy does for its current version.
+func (b *Bucket) PutCopyVersion(path string, perm ACL, options CopyOptions, source, sourceVersionId string) (*CopyObjectResult, error) {
+	return b.PutCopy(path, perm, options, source+"?versionId="+url.QueryEscape(sourceVersionId))
+}
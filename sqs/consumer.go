@@ -0,0 +1,247 @@
+package sqs
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The defaults used by consumers created by NewConsumer.
+const (
+	DefaultConsumerWorkers = 10
+	DefaultWaitTime        = 20 * time.Second
+	DefaultDeleteDelay     = time.Second
+	DefaultRetryDelay      = time.Second
+	DefaultMaxRetryDelay   = time.Minute
+)
+
+// maxBatch is the number of messages SQS receives or deletes at most in
+// a single request.
+const maxBatch = 10
+
+// A Consumer receives the messages of a queue using long polling and
+// hands them to a handler, several at once. The messages handled are
+// deleted from the queue in batches, while those the handler fails on are
+// left in the queue, to be received again once their visibility timeout
+// expires.
+type Consumer struct {
+	Queue *Queue
+
+	// Handler is called with each message received, from as many
+	// goroutines as there are Workers. The message is deleted if it
+	// returns nil.
+	Handler func(m *Message) error
+
+	// Workers is the number of messages handled at once. Messages are
+	// only received when a worker is free to handle them.
+	Workers int
+
+	// WaitTime is how long requests for messages wait for one to arrive
+	// when the queue is empty, at most 20 seconds.
+	WaitTime time.Duration
+
+	// VisibilityTimeout, if not zero, replaces the visibility timeout
+	// of the queue for the messages received. It is rounded down to
+	// seconds.
+	VisibilityTimeout time.Duration
+
+	// DeleteDelay is how long handled messages may wait to be deleted
+	// together with others, up to 10 at a time.
+	DeleteDelay time.Duration
+
+	// RetryDelay is how long to wait before receiving messages again
+	// after failing to. It doubles after each consecutive failure, up
+	// to MaxRetryDelay.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+
+	// Heartbeat, if set, keeps the messages invisible while they are
+	// being handled, for handlers that may take longer than the
+	// visibility timeout.
+	Heartbeat *Heartbeat
+
+	// ErrorLog, if set, is called with the errors returned by Handler
+	// and those deleting messages, along with the message concerned,
+	// and with the errors receiving messages, along with a nil message.
+	// Calls may be concurrent.
+	ErrorLog func(m *Message, err error)
+}
+
+// NewConsumer returns a consumer handing the messages of q to handler
+// using the default settings.
+func NewConsumer(q *Queue, handler func(m *Message) error) *Consumer {
+	return &Consumer{
+		Queue:         q,
+		Handler:       handler,
+		Workers:       DefaultConsumerWorkers,
+		WaitTime:      DefaultWaitTime,
+		DeleteDelay:   DefaultDeleteDelay,
+		RetryDelay:    DefaultRetryDelay,
+		MaxRetryDelay: DefaultMaxRetryDelay,
+	}
+}
+
+// Run receives and handles messages until ctx is done. It then stops
+// receiving messages, waits for the handler to be done with those already
+// received, deletes those it handled, and returns ctx.Err().
+func (c *Consumer) Run(ctx context.Context) error {
+	workers := c.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	// A worker is busy while its slot is taken.
+	slots := make(chan bool, workers)
+	handled := make(chan *Message)
+	deleted := make(chan bool)
+	go c.deleteHandled(handled, deleted)
+
+	q := c.Queue.WithContext(ctx)
+	delay := c.RetryDelay
+	var wg sync.WaitGroup
+Receive:
+	for {
+		select {
+		case slots <- true:
+		case <-ctx.Done():
+			break Receive
+		}
+		n := 1
+	Slots:
+		for n < maxBatch {
+			select {
+			case slots <- true:
+				n++
+			default:
+				break Slots
+			}
+		}
+		msgs, err := c.receive(q, n)
+		for i := len(msgs); i < n; i++ {
+			<-slots
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break Receive
+			}
+			c.logError(nil, err)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				break Receive
+			}
+			if delay *= 2; delay > c.MaxRetryDelay {
+				delay = c.MaxRetryDelay
+			}
+			continue
+		}
+		delay = c.RetryDelay
+		for i := range msgs {
+			m := &msgs[i]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				if c.Heartbeat != nil {
+					c.Heartbeat.Add(m)
+				}
+				err := c.Handler(m)
+				if c.Heartbeat != nil {
+					c.Heartbeat.Remove(m)
+				}
+				if err != nil {
+					c.logError(m, err)
+					return
+				}
+				handled <- m
+			}()
+		}
+	}
+	wg.Wait()
+	close(handled)
+	<-deleted
+	return ctx.Err()
+}
+
+// receive asks q for up to n messages.
+func (c *Consumer) receive(q *Queue, n int) ([]Message, error) {
+	wait := c.WaitTime
+	if wait > DefaultWaitTime {
+		wait = DefaultWaitTime
+	}
+	params := map[string]string{
+		"MaxNumberOfMessages": strconv.Itoa(n),
+		"WaitTimeSeconds":     strconv.Itoa(int(wait / time.Second)),
+	}
+	if c.VisibilityTimeout != 0 {
+		params["VisibilityTimeout"] = strconv.Itoa(int(c.VisibilityTimeout / time.Second))
+	}
+	resp, err := q.ReceiveMessageWithParameters(params)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Messages, nil
+}
+
+// deleteHandled deletes the messages sent on handled in batches, until
+// handled is closed, and then closes deleted.
+func (c *Consumer) deleteHandled(handled <-chan *Message, deleted chan<- bool) {
+	var batch []*Message
+	var flush <-chan time.Time
+	for {
+		select {
+		case m, ok := <-handled:
+			if !ok {
+				c.deleteBatch(batch)
+				close(deleted)
+				return
+			}
+			batch = append(batch, m)
+			if len(batch) < maxBatch {
+				if flush == nil {
+					flush = time.After(c.DeleteDelay)
+				}
+				continue
+			}
+		case <-flush:
+		}
+		c.deleteBatch(batch)
+		batch = nil
+		flush = nil
+	}
+}
+
+func (c *Consumer) deleteBatch(batch []*Message) {
+	if len(batch) == 0 {
+		return
+	}
+	// The same message may be received twice, so the entries are
+	// identified by their index rather than the message id.
+	entries := make([]Message, len(batch))
+	for i, m := range batch {
+		entries[i] = Message{
+			MessageId:     strconv.Itoa(i),
+			ReceiptHandle: m.ReceiptHandle,
+		}
+	}
+	resp, err := c.Queue.DeleteMessageBatch(entries)
+	if err != nil {
+		for _, m := range batch {
+			c.logError(m, err)
+		}
+		return
+	}
+	for _, f := range resp.Failed {
+		i, err := strconv.Atoi(f.Id)
+		if err != nil || i < 0 || i >= len(batch) {
+			continue
+		}
+		c.logError(batch[i], &Error{Code: f.Code, Message: f.Message})
+	}
+}
+
+func (c *Consumer) logError(m *Message, err error) {
+	if c.ErrorLog != nil {
+		c.ErrorLog(m, err)
+	}
+}
@@ -0,0 +1,344 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goamz/goamz/aws"
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&ConsumerSuite{})
+
+type ConsumerSuite struct {
+	fake *fakeQueue
+	srv  *httptest.Server
+	q    *Queue
+}
+
+func (s *ConsumerSuite) SetUpTest(c *C) {
+	s.fake = &fakeQueue{inFlight: make(map[string]string)}
+	s.srv = httptest.NewServer(s.fake)
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	s.q = &Queue{New(auth, aws.Region{SQSEndpoint: s.srv.URL}), s.srv.URL + "/123456789012/testQueue"}
+}
+
+func (s *ConsumerSuite) TearDownTest(c *C) {
+	s.srv.Close()
+}
+
+// fakeQueue is an SQS queue, serving ReceiveMessage, DeleteMessageBatch,
+// ChangeMessageVisibility and ChangeMessageVisibilityBatch requests, whose
+// messages are never received twice.
+type fakeQueue struct {
+	mu       sync.Mutex
+	messages []string          // bodies of the messages not yet received.
+	inFlight map[string]string // bodies of the messages received, by receipt handle.
+	seq      int
+	deleted  []string // bodies of the messages deleted.
+	batches  []int    // sizes of the delete batches.
+	changes  []string // visibility timeout changes, as "body:timeout".
+
+	// failReceive is the number of ReceiveMessage requests to fail.
+	failReceive int
+}
+
+func (f *fakeQueue) send(bodies ...string) {
+	f.mu.Lock()
+	f.messages = append(f.messages, bodies...)
+	f.mu.Unlock()
+}
+
+func (f *fakeQueue) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	req.ParseForm()
+	switch action := req.Form.Get("Action"); action {
+	case "ReceiveMessage":
+		f.receive(w, req)
+	case "DeleteMessageBatch":
+		f.deleteBatch(w, req)
+	case "ChangeMessageVisibility":
+		f.changeVisibility(w, req)
+	case "ChangeMessageVisibilityBatch":
+		f.changeVisibilityBatch(w, req)
+	default:
+		http.Error(w, "unexpected action "+action, 400)
+	}
+}
+
+func (f *fakeQueue) receive(w http.ResponseWriter, req *http.Request) {
+	max, _ := strconv.Atoi(req.Form.Get("MaxNumberOfMessages"))
+	wait, _ := strconv.Atoi(req.Form.Get("WaitTimeSeconds"))
+	deadline := time.Now().Add(time.Duration(wait) * time.Second)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failReceive > 0 {
+		f.failReceive--
+		w.WriteHeader(500)
+		fmt.Fprint(w, `<ErrorResponse><Error><Code>InternalError</Code><Message>We encountered an internal error.</Message></Error></ErrorResponse>`)
+		return
+	}
+	for len(f.messages) == 0 && time.Now().Before(deadline) {
+		f.mu.Unlock()
+		select {
+		case <-time.After(5 * time.Millisecond):
+		case <-req.Context().Done():
+		}
+		f.mu.Lock()
+		if req.Context().Err() != nil {
+			return
+		}
+	}
+	fmt.Fprint(w, "<ReceiveMessageResponse><ReceiveMessageResult>")
+	for i := 0; i < max && len(f.messages) > 0; i++ {
+		f.seq++
+		handle := fmt.Sprintf("handle-%d", f.seq)
+		f.inFlight[handle] = f.messages[0]
+		fmt.Fprintf(w, "<Message><MessageId>id-%d</MessageId><ReceiptHandle>%s</ReceiptHandle><Body>%s</Body></Message>", f.seq, handle, f.messages[0])
+		f.messages = f.messages[1:]
+	}
+	fmt.Fprint(w, "</ReceiveMessageResult></ReceiveMessageResponse>")
+}
+
+func (f *fakeQueue) deleteBatch(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fmt.Fprint(w, "<DeleteMessageBatchResponse><DeleteMessageBatchResult>")
+	n := 0
+	for ; req.Form.Get(fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.Id", n+1)) != ""; n++ {
+		prefix := fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.", n+1)
+		id := req.Form.Get(prefix + "Id")
+		handle := req.Form.Get(prefix + "ReceiptHandle")
+		body, ok := f.inFlight[handle]
+		if !ok || strings.HasPrefix(body, "undeletable") {
+			fmt.Fprintf(w, "<BatchResultErrorEntry><Id>%s</Id><SenderFault>true</SenderFault><Code>ReceiptHandleIsInvalid</Code><Message>The input receipt handle is invalid.</Message></BatchResultErrorEntry>", id)
+			continue
+		}
+		delete(f.inFlight, handle)
+		f.deleted = append(f.deleted, body)
+		fmt.Fprintf(w, "<DeleteMessageBatchResultEntry><Id>%s</Id></DeleteMessageBatchResultEntry>", id)
+	}
+	f.batches = append(f.batches, n)
+	fmt.Fprint(w, "</DeleteMessageBatchResult></DeleteMessageBatchResponse>")
+}
+
+// received returns a message with the given body, as if it was just
+// received.
+func (f *fakeQueue) received(body string) *Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	m := &Message{
+		MessageId:     fmt.Sprintf("id-%d", f.seq),
+		ReceiptHandle: fmt.Sprintf("handle-%d", f.seq),
+		Body:          body,
+	}
+	f.inFlight[m.ReceiptHandle] = body
+	return m
+}
+
+func (f *fakeQueue) changeVisibility(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	handle := req.Form.Get("ReceiptHandle")
+	body, ok := f.inFlight[handle]
+	if !ok {
+		code, msg := handleError(handle)
+		w.WriteHeader(400)
+		fmt.Fprintf(w, `<ErrorResponse><Error><Code>%s</Code><Message>%s</Message></Error></ErrorResponse>`, code, msg)
+		return
+	}
+	f.changes = append(f.changes, body+":"+req.Form.Get("VisibilityTimeout"))
+	fmt.Fprint(w, "<ChangeMessageVisibilityResponse></ChangeMessageVisibilityResponse>")
+}
+
+// handleError returns the error code and message of a visibility timeout
+// change for a receipt handle not in flight, which has expired if it
+// starts with "expired".
+func handleError(handle string) (code, msg string) {
+	if strings.HasPrefix(handle, "expired") {
+		return "InvalidParameterValue", "Value " + handle + " for parameter ReceiptHandle is invalid. Reason: The receipt handle has expired."
+	}
+	return "ReceiptHandleIsInvalid", "The input receipt handle is invalid."
+}
+
+func (f *fakeQueue) changeVisibilityBatch(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fmt.Fprint(w, "<ChangeMessageVisibilityBatchResponse><ChangeMessageVisibilityBatchResult>")
+	for n := 1; req.Form.Get(fmt.Sprintf("ChangeMessageVisibilityBatchRequestEntry.%d.Id", n)) != ""; n++ {
+		prefix := fmt.Sprintf("ChangeMessageVisibilityBatchRequestEntry.%d.", n)
+		id := req.Form.Get(prefix + "Id")
+		handle := req.Form.Get(prefix + "ReceiptHandle")
+		body, ok := f.inFlight[handle]
+		if !ok {
+			code, msg := handleError(handle)
+			fmt.Fprintf(w, "<BatchResultErrorEntry><Id>%s</Id><SenderFault>true</SenderFault><Code>%s</Code><Message>%s</Message></BatchResultErrorEntry>", id, code, msg)
+			continue
+		}
+		f.changes = append(f.changes, body+":"+req.Form.Get(prefix+"VisibilityTimeout"))
+		fmt.Fprintf(w, "<ChangeMessageVisibilityBatchResultEntry><Id>%s</Id></ChangeMessageVisibilityBatchResultEntry>", id)
+	}
+	fmt.Fprint(w, "</ChangeMessageVisibilityBatchResult></ChangeMessageVisibilityBatchResponse>")
+}
+
+// changed returns the visibility timeout changes made so far.
+func (f *fakeQueue) changed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.changes...)
+}
+
+// newConsumer returns a consumer of s.q, with short delays.
+func (s *ConsumerSuite) newConsumer(handler func(m *Message) error) *Consumer {
+	cons := NewConsumer(s.q, handler)
+	cons.WaitTime = time.Second
+	cons.DeleteDelay = 10 * time.Millisecond
+	cons.RetryDelay = 10 * time.Millisecond
+	return cons
+}
+
+func (s *ConsumerSuite) TestConsumer(c *C) {
+	var bodies []string
+	for i := 0; i < 25; i++ {
+		bodies = append(bodies, fmt.Sprintf("message-%d", i))
+	}
+	s.fake.send(bodies...)
+	s.fake.send("failing", "undeletable")
+
+	var mu sync.Mutex
+	handled := make(map[string]bool)
+	busy, maxBusy := 0, 0
+	done := make(chan bool)
+	ctx, cancel := context.WithCancel(context.Background())
+	cons := s.newConsumer(func(m *Message) error {
+		mu.Lock()
+		busy++
+		if busy > maxBusy {
+			maxBusy = busy
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		busy--
+		handled[m.Body] = true
+		if len(handled) == 27 {
+			close(done)
+		}
+		if m.Body == "failing" {
+			return errors.New("cannot handle it")
+		}
+		return nil
+	})
+	cons.Workers = 4
+	var errs []string
+	cons.ErrorLog = func(m *Message, err error) {
+		mu.Lock()
+		errs = append(errs, m.Body+": "+err.Error())
+		mu.Unlock()
+	}
+
+	result := make(chan error)
+	go func() {
+		result <- cons.Run(ctx)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		c.Fatalf("messages not handled")
+	}
+	cancel()
+	c.Assert(<-result, Equals, context.Canceled)
+
+	c.Assert(maxBusy <= 4, Equals, true)
+	sort.Strings(errs)
+	c.Assert(errs, DeepEquals, []string{
+		"failing: cannot handle it",
+		"undeletable: The input receipt handle is invalid. (ReceiptHandleIsInvalid)",
+	})
+	deleted := append([]string(nil), s.fake.deleted...)
+	sort.Strings(deleted)
+	sort.Strings(bodies)
+	c.Assert(deleted, DeepEquals, bodies)
+	for _, n := range s.fake.batches {
+		c.Assert(n > 0 && n <= 10, Equals, true)
+	}
+	c.Assert(len(s.fake.batches) < len(bodies), Equals, true)
+}
+
+func (s *ConsumerSuite) TestConsumerRetry(c *C) {
+	s.fake.failReceive = 3
+	s.fake.send("message")
+
+	done := make(chan bool)
+	ctx, cancel := context.WithCancel(context.Background())
+	cons := s.newConsumer(func(m *Message) error {
+		close(done)
+		return nil
+	})
+	var errs []error
+	cons.ErrorLog = func(m *Message, err error) {
+		c.Check(m, IsNil)
+		errs = append(errs, err)
+	}
+	result := make(chan error)
+	start := time.Now()
+	go func() {
+		result <- cons.Run(ctx)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		c.Fatalf("message not handled")
+	}
+	// Receiving is retried after 10, 20 and 40ms.
+	c.Assert(time.Since(start) >= 70*time.Millisecond, Equals, true)
+	cancel()
+	c.Assert(<-result, Equals, context.Canceled)
+	c.Assert(errs, HasLen, 3)
+	c.Assert(errs[0], FitsTypeOf, &Error{})
+	c.Assert(errs[0].(*Error).Code, Equals, "InternalError")
+	c.Assert(s.fake.deleted, DeepEquals, []string{"message"})
+}
+
+func (s *ConsumerSuite) TestConsumerDrain(c *C) {
+	s.fake.send("message")
+
+	started := make(chan bool)
+	release := make(chan bool)
+	ctx, cancel := context.WithCancel(context.Background())
+	cons := s.newConsumer(func(m *Message) error {
+		close(started)
+		<-release
+		return nil
+	})
+	// Handled messages would only be deleted after stopping.
+	cons.DeleteDelay = time.Hour
+	result := make(chan error)
+	go func() {
+		result <- cons.Run(ctx)
+	}()
+	<-started
+	cancel()
+	select {
+	case <-result:
+		c.Fatalf("Run returned before the message was handled")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case err := <-result:
+		c.Assert(err, Equals, context.Canceled)
+	case <-time.After(5 * time.Second):
+		c.Fatalf("Run did not return")
+	}
+	c.Assert(s.fake.deleted, DeepEquals, []string{"message"})
+}
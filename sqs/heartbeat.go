@@ -0,0 +1,235 @@
+package sqs
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The defaults used by heartbeats created by NewHeartbeat.
+const (
+	DefaultHeartbeatTimeout  = 30 * time.Second
+	DefaultHeartbeatInterval = 10 * time.Second
+	DefaultHeartbeatMax      = 12 * time.Hour
+)
+
+// A Heartbeat keeps messages from becoming visible again while they are
+// being handled, by extending their visibility timeout periodically, so
+// that handling them may take longer than the visibility timeout of
+// their queue without them being received and handled twice.
+//
+// Messages are kept invisible from the time they are added to the
+// heartbeat until they are removed from it, MaxTime has passed, or
+// their receipt handle is found to be invalid, as it is once they have
+// been deleted, have become visible again or have been received too long
+// ago.
+type Heartbeat struct {
+	Queue *Queue
+
+	// VisibilityTimeout is the visibility timeout the messages are
+	// given each time it is extended, counting from then.
+	VisibilityTimeout time.Duration
+
+	// Interval is how often the visibility timeout of the messages is
+	// extended after the first time, when they are added. It should be
+	// well below VisibilityTimeout.
+	Interval time.Duration
+
+	// MaxTime is how long after being added the messages stop being
+	// kept invisible, so that messages whose handling is stuck are
+	// eventually handled again. SQS lets messages be invisible for at
+	// most 12 hours after they are received.
+	MaxTime time.Duration
+
+	// ErrorLog, if set, is called with the errors extending the
+	// visibility timeout of messages, along with the message concerned.
+	// Calls may be concurrent.
+	ErrorLog func(m *Message, err error)
+
+	mu      sync.Mutex
+	added   map[*Message]*heartbeatEntry
+	wake    chan bool // signals run that messages were added.
+	running bool
+}
+
+// heartbeatEntry holds the state of a message added to a heartbeat.
+type heartbeatEntry struct {
+	added time.Time // when the message was added.
+	next  time.Time // when its visibility timeout is next extended.
+}
+
+// NewHeartbeat returns a heartbeat for the messages of q using the default
+// settings.
+func NewHeartbeat(q *Queue) *Heartbeat {
+	return &Heartbeat{
+		Queue:             q,
+		VisibilityTimeout: DefaultHeartbeatTimeout,
+		Interval:          DefaultHeartbeatInterval,
+		MaxTime:           DefaultHeartbeatMax,
+	}
+}
+
+// Add starts keeping m invisible. Its visibility timeout is extended
+// right away, as how much of it is left is not known, and then every
+// Interval.
+func (h *Heartbeat) Add(m *Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.added == nil {
+		h.added = make(map[*Message]*heartbeatEntry)
+		h.wake = make(chan bool, 1)
+	}
+	now := time.Now()
+	h.added[m] = &heartbeatEntry{added: now, next: now}
+	if !h.running {
+		h.running = true
+		go h.run()
+	}
+	select {
+	case h.wake <- true:
+	default:
+	}
+}
+
+// Remove stops keeping m invisible, as is done once it is handled. Its
+// visibility timeout is left as last extended. An extension already under
+// way when Remove is called may still be made, but its errors are not
+// reported.
+func (h *Heartbeat) Remove(m *Message) {
+	h.mu.Lock()
+	delete(h.added, m)
+	h.mu.Unlock()
+}
+
+// run extends the visibility timeout of the messages when it is due, for
+// as long as there are any.
+func (h *Heartbeat) run() {
+	for {
+		h.mu.Lock()
+		if len(h.added) == 0 {
+			h.running = false
+			h.mu.Unlock()
+			return
+		}
+		// The messages due are grouped by the timeout they are given,
+		// which is shorter for those nearing MaxTime.
+		now := time.Now()
+		wait := h.Interval
+		groups := make(map[int][]*Message)
+		for m, e := range h.added {
+			if e.next.After(now) {
+				if d := e.next.Sub(now); d < wait {
+					wait = d
+				}
+				continue
+			}
+			e.next = now.Add(h.Interval)
+			timeout := h.VisibilityTimeout
+			if left := e.added.Add(h.MaxTime).Sub(now); left < timeout {
+				timeout = left
+			}
+			seconds := int(timeout / time.Second)
+			if seconds < 1 {
+				delete(h.added, m)
+				continue
+			}
+			groups[seconds] = append(groups[seconds], m)
+		}
+		h.mu.Unlock()
+
+		for seconds, msgs := range groups {
+			for len(msgs) > 0 {
+				n := len(msgs)
+				if n > maxBatch {
+					n = maxBatch
+				}
+				h.extend(msgs[:n], seconds)
+				msgs = msgs[n:]
+			}
+		}
+		select {
+		case <-time.After(wait):
+		case <-h.wake:
+		}
+	}
+}
+
+// live returns those of msgs that have not been removed.
+func (h *Heartbeat) live(msgs []*Message) []*Message {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var live []*Message
+	for _, m := range msgs {
+		if _, ok := h.added[m]; ok {
+			live = append(live, m)
+		}
+	}
+	return live
+}
+
+// extend sets the visibility timeout of those of msgs not removed since
+// they were found due, removing the messages whose receipt handle is no
+// longer valid.
+func (h *Heartbeat) extend(msgs []*Message, seconds int) {
+	msgs = h.live(msgs)
+	if len(msgs) == 0 {
+		return
+	}
+	if len(msgs) == 1 {
+		_, err := h.Queue.ChangeMessageVisibility(msgs[0], seconds)
+		if err != nil {
+			h.fail(msgs[0], err)
+		}
+		return
+	}
+	// The entries are identified by their index, as in deleteBatch.
+	entries := make([]Message, len(msgs))
+	for i, m := range msgs {
+		entries[i] = Message{
+			MessageId:     strconv.Itoa(i),
+			ReceiptHandle: m.ReceiptHandle,
+		}
+	}
+	resp, err := h.Queue.ChangeMessageVisibilityBatch(entries, seconds)
+	if err != nil {
+		for _, m := range msgs {
+			h.fail(m, err)
+		}
+		return
+	}
+	for _, f := range resp.Failed {
+		i, err := strconv.Atoi(f.Id)
+		if err != nil || i < 0 || i >= len(msgs) {
+			continue
+		}
+		h.fail(msgs[i], &Error{Code: f.Code, Message: f.Message})
+	}
+}
+
+// fail reports that the visibility timeout of m could not be extended,
+// and stops trying if its receipt handle is no longer valid. Nothing is
+// reported if m was removed meanwhile, as it may since have been deleted.
+func (h *Heartbeat) fail(m *Message, err error) {
+	h.mu.Lock()
+	_, added := h.added[m]
+	if e, ok := err.(*Error); ok && added {
+		switch e.Code {
+		case "ReceiptHandleIsInvalid", "AWS.SimpleQueueService.MessageNotInflight", "MessageNotInflight":
+			delete(h.added, m)
+		case "InvalidParameterValue":
+			// Expired receipt handles are reported as invalid values
+			// of the ReceiptHandle parameter.
+			if strings.Contains(e.Message, "ReceiptHandle") {
+				delete(h.added, m)
+			}
+		}
+	}
+	h.mu.Unlock()
+	if !added {
+		return
+	}
+	if h.ErrorLog != nil {
+		h.ErrorLog(m, err)
+	}
+}
@@ -0,0 +1,164 @@
+package sqs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *ConsumerSuite) TestHeartbeat(c *C) {
+	h := NewHeartbeat(s.q)
+	h.Interval = 20 * time.Millisecond
+	m1 := s.fake.received("a")
+	m2 := s.fake.received("b")
+	h.Add(m1)
+	h.Add(m2)
+	time.Sleep(70 * time.Millisecond)
+	h.Remove(m1)
+	h.Remove(m2)
+	changes := s.fake.changed()
+	c.Assert(len(changes) >= 4, Equals, true)
+	count := map[string]int{}
+	for _, change := range changes {
+		count[change]++
+	}
+	c.Assert(count["a:30"], Equals, count["b:30"])
+	c.Assert(count["a:30"]+count["b:30"], Equals, len(changes))
+
+	// Nothing is extended once the messages are removed.
+	time.Sleep(50 * time.Millisecond)
+	c.Assert(s.fake.changed(), HasLen, len(changes))
+}
+
+func (s *ConsumerSuite) TestHeartbeatExtendsOnAdd(c *C) {
+	h := NewHeartbeat(s.q)
+	h.Interval = time.Hour
+	m := s.fake.received("a")
+	h.Add(m)
+	defer h.Remove(m)
+	for i := 0; i < 100 && len(s.fake.changed()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(s.fake.changed(), DeepEquals, []string{"a:30"})
+}
+
+func (s *ConsumerSuite) TestHeartbeatRemoved(c *C) {
+	h := NewHeartbeat(s.q)
+	h.ErrorLog = func(m *Message, err error) {
+		c.Errorf("error reported for removed message: %v", err)
+	}
+	m := s.fake.received("a")
+	invalid := &Message{ReceiptHandle: "unknown"}
+
+	// The messages are not added, as if removed after being found due:
+	// they are not extended, and errors extending them are not reported.
+	h.extend([]*Message{m}, 30)
+	h.extend([]*Message{m, invalid}, 30)
+	h.fail(invalid, &Error{Code: "ReceiptHandleIsInvalid"})
+	c.Assert(s.fake.changed(), HasLen, 0)
+}
+
+func (s *ConsumerSuite) TestHeartbeatInvalidReceiptHandle(c *C) {
+	h := NewHeartbeat(s.q)
+	h.Interval = 20 * time.Millisecond
+	var mu sync.Mutex
+	var errs []error
+	h.ErrorLog = func(m *Message, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		c.Check(m.ReceiptHandle, Equals, "unknown")
+		errs = append(errs, err)
+	}
+	valid := s.fake.received("valid")
+	h.Add(valid)
+	h.Add(&Message{ReceiptHandle: "unknown"})
+	time.Sleep(70 * time.Millisecond)
+	h.Remove(valid)
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(errs, HasLen, 1)
+	c.Assert(errs[0], FitsTypeOf, &Error{})
+	c.Assert(errs[0].(*Error).Code, Equals, "ReceiptHandleIsInvalid")
+	c.Assert(len(s.fake.changed()) >= 2, Equals, true)
+}
+
+func (s *ConsumerSuite) TestHeartbeatExpiredReceiptHandle(c *C) {
+	h := NewHeartbeat(s.q)
+	h.Interval = 20 * time.Millisecond
+	var mu sync.Mutex
+	var errs []error
+	h.ErrorLog = func(m *Message, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		c.Check(m.ReceiptHandle, Matches, "expired.*")
+		errs = append(errs, err)
+	}
+	// The first message is extended alone, and the second one in a
+	// batch along with a valid one.
+	m1 := &Message{ReceiptHandle: "expired-1"}
+	h.Add(m1)
+	time.Sleep(70 * time.Millisecond)
+	h.Remove(m1)
+	valid := s.fake.received("valid")
+	h.Add(valid)
+	h.Add(&Message{ReceiptHandle: "expired-2"})
+	time.Sleep(70 * time.Millisecond)
+	h.Remove(valid)
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(errs, HasLen, 2)
+	for _, err := range errs {
+		c.Assert(err, FitsTypeOf, &Error{})
+		c.Assert(err.(*Error).Code, Equals, "InvalidParameterValue")
+	}
+}
+
+func (s *ConsumerSuite) TestHeartbeatMaxTime(c *C) {
+	h := NewHeartbeat(s.q)
+	h.Interval = 100 * time.Millisecond
+	h.MaxTime = 1500 * time.Millisecond
+	m := s.fake.received("a")
+	h.Add(m)
+	defer h.Remove(m)
+	time.Sleep(800 * time.Millisecond)
+	changes := s.fake.changed()
+	// The visibility timeout, extended right away and then every
+	// Interval, never extends past MaxTime, and is no longer extended
+	// once less than a second is left.
+	c.Assert(len(changes) >= 3 && len(changes) <= 5, Equals, true)
+	for _, change := range changes {
+		c.Assert(change, Equals, "a:1")
+	}
+	time.Sleep(200 * time.Millisecond)
+	c.Assert(s.fake.changed(), HasLen, len(changes))
+}
+
+func (s *ConsumerSuite) TestConsumerHeartbeat(c *C) {
+	s.fake.send("message")
+
+	done := make(chan bool)
+	ctx, cancel := context.WithCancel(context.Background())
+	cons := s.newConsumer(func(m *Message) error {
+		time.Sleep(60 * time.Millisecond)
+		close(done)
+		return nil
+	})
+	cons.Heartbeat = NewHeartbeat(s.q)
+	cons.Heartbeat.Interval = 20 * time.Millisecond
+	cons.Heartbeat.ErrorLog = func(m *Message, err error) {
+		c.Errorf("cannot extend visibility timeout: %v", err)
+	}
+	result := make(chan error)
+	go func() {
+		result <- cons.Run(ctx)
+	}()
+	<-done
+	cancel()
+	c.Assert(<-result, Equals, context.Canceled)
+	c.Assert(len(s.fake.changed()) >= 1, Equals, true)
+	c.Assert(s.fake.deleted, DeepEquals, []string{"message"})
+}
@@ -125,6 +125,25 @@ var TestChangeMessageVisibilityXmlOK = `
 </ChangeMessageVisibilityResponse>
 `
 
+var TestChangeMessageVisibilityBatchXmlOK = `
+<ChangeMessageVisibilityBatchResponse>
+    <ChangeMessageVisibilityBatchResult>
+        <ChangeMessageVisibilityBatchResultEntry>
+            <Id>msg1</Id>
+        </ChangeMessageVisibilityBatchResultEntry>
+        <BatchResultErrorEntry>
+            <Id>msg2</Id>
+            <SenderFault>true</SenderFault>
+            <Code>ReceiptHandleIsInvalid</Code>
+            <Message>The input receipt handle is invalid.</Message>
+        </BatchResultErrorEntry>
+    </ChangeMessageVisibilityBatchResult>
+    <ResponseMetadata>
+        <RequestId>ca9668f7-ab1b-4f7a-8859-f15747ab17a7</RequestId>
+    </ResponseMetadata>
+</ChangeMessageVisibilityBatchResponse>
+`
+
 var TestDeleteMessageBatchXmlOK = `
 <DeleteMessageBatchResponse>
     <DeleteMessageBatchResult>
@@ -10,6 +10,7 @@
 package sqs
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -34,7 +35,9 @@ const debug = false
 type SQS struct {
 	aws.Auth
 	aws.Region
-	private byte // Reserve the right of using private data.
+	provider aws.CredentialsProvider
+	ctx      context.Context
+	private  byte // Reserve the right of using private data.
 }
 
 // NewFrom Create A new SQS Client given an access and secret Key
@@ -73,7 +76,35 @@ func NewFrom(accessKey, secretKey, region string) (*SQS, error) {
 
 // NewFrom Create A new SQS Client from an exisisting aws.Auth
 func New(auth aws.Auth, region aws.Region) *SQS {
-	return &SQS{auth, region, 0}
+	return &SQS{Auth: auth, Region: region}
+}
+
+// NewWithProvider creates a new SQS whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) *SQS {
+	c := New(aws.Auth{}, region)
+	c.provider = provider
+	return c
+}
+
+// credentials returns the credentials to sign the next request with.
+func (s *SQS) credentials() (aws.Auth, error) {
+	if s.provider == nil {
+		return s.Auth.Current(), nil
+	}
+	return s.provider.Credentials()
+}
+
+// WithContext returns a copy of s whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (s *SQS) WithContext(ctx context.Context) *SQS {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *s
+	c.ctx = ctx
+	return &c
 }
 
 // Queue Reference to a Queue
@@ -82,6 +113,13 @@ type Queue struct {
 	Url string
 }
 
+// WithContext returns a copy of q whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight, including a long-polling ReceiveMessage.
+func (q *Queue) WithContext(ctx context.Context) *Queue {
+	return &Queue{q.SQS.WithContext(ctx), q.Url}
+}
+
 type CreateQueueResponse struct {
 	QueueUrl         string `xml:"CreateQueueResult>QueueUrl"`
 	ResponseMetadata ResponseMetadata
@@ -364,6 +402,36 @@ func (q *Queue) ChangeMessageVisibility(M *Message, VisibilityTimeout int) (resp
 	return
 }
 
+type ChangeMessageVisibilityBatchResponse struct {
+	ChangeMessageVisibilityBatchResult []struct {
+		Id string
+	} `xml:"ChangeMessageVisibilityBatchResult>ChangeMessageVisibilityBatchResultEntry"`
+
+	// Failed holds the entries of the batch whose visibility timeout
+	// could not be changed.
+	Failed []BatchResultErrorEntry `xml:"ChangeMessageVisibilityBatchResult>BatchResultErrorEntry"`
+
+	ResponseMetadata ResponseMetadata
+}
+
+// ChangeMessageVisibilityBatch changes the visibility timeout of up to 10
+// messages at once, as ChangeMessageVisibility does. The entries of the
+// batch are identified by the MessageId of the messages.
+func (q *Queue) ChangeMessageVisibilityBatch(msgList []Message, VisibilityTimeout int) (resp *ChangeMessageVisibilityBatchResponse, err error) {
+	resp = &ChangeMessageVisibilityBatchResponse{}
+	params := makeParams("ChangeMessageVisibilityBatch")
+
+	for idx := range msgList {
+		prefix := fmt.Sprintf("ChangeMessageVisibilityBatchRequestEntry.%d.", idx+1)
+		params[prefix+"Id"] = msgList[idx].MessageId
+		params[prefix+"ReceiptHandle"] = msgList[idx].ReceiptHandle
+		params[prefix+"VisibilityTimeout"] = strconv.Itoa(VisibilityTimeout)
+	}
+
+	err = q.SQS.query(q.Url, params, resp)
+	return
+}
+
 func (q *Queue) GetQueueAttributes(A string) (resp *GetQueueAttributesResponse, err error) {
 	resp = &GetQueueAttributesResponse{}
 	params := makeParams("GetQueueAttributes")
@@ -436,9 +504,21 @@ type DeleteMessageBatchResponse struct {
 		Code        string
 		Message     string
 	} `xml:"DeleteMessageBatchResult>DeleteMessageBatchResultEntry"`
+
+	// Failed holds the entries of the batch that could not be deleted.
+	Failed []BatchResultErrorEntry `xml:"DeleteMessageBatchResult>BatchResultErrorEntry"`
+
 	ResponseMetadata ResponseMetadata
 }
 
+// BatchResultErrorEntry tells why an entry of a batch request failed.
+type BatchResultErrorEntry struct {
+	Id          string
+	SenderFault bool
+	Code        string
+	Message     string
+}
+
 /* DeleteMessageBatch */
 func (q *Queue) DeleteMessageBatch(msgList []Message) (resp *DeleteMessageBatchResponse, err error) {
 	resp = &DeleteMessageBatchResponse{}
@@ -473,7 +553,17 @@ func (q *Queue) DeleteMessageBatch(msgList []Message) (resp *DeleteMessageBatchR
 	return
 }
 
-func (s *SQS) query(queueUrl string, params map[string]string, resp interface{}) (err error) {
+// query makes the request described by params, retrying it once with
+// fresh credentials if the ones it was signed with had expired.
+func (s *SQS) query(queueUrl string, params map[string]string, resp interface{}) error {
+	err := s.queryOnce(queueUrl, params, resp)
+	if e, ok := err.(*Error); ok && aws.ExpireCredentials(e.Code, s.provider, s.Auth) {
+		err = s.queryOnce(queueUrl, params, resp)
+	}
+	return err
+}
+
+func (s *SQS) queryOnce(queueUrl string, params map[string]string, resp interface{}) (err error) {
 	params["Version"] = API_VERSION
 	params["Timestamp"] = time.Now().In(time.UTC).Format(time.RFC3339)
 	var url_ *url.URL
@@ -498,8 +588,13 @@ func (s *SQS) query(queueUrl string, params map[string]string, resp interface{})
 		return err
 	}
 
-	if s.Auth.Token() != "" {
-		params["SecurityToken"] = s.Auth.Token()
+	auth, err := s.credentials()
+	if err != nil {
+		return err
+	}
+	delete(params, "SecurityToken")
+	if auth.Token() != "" {
+		params["SecurityToken"] = auth.Token()
 	}
 
 	var r *http.Response
@@ -513,14 +608,25 @@ func (s *SQS) query(queueUrl string, params map[string]string, resp interface{})
 		if err != nil {
 			return err
 		}
-		signer := aws.NewV4Signer(s.Auth, "sqs", s.Region)
+		signer := aws.NewV4Signer(auth, "sqs", s.Region)
 		signer.Sign(req)
+		if s.ctx != nil {
+			req = req.WithContext(s.ctx)
+		}
 		client := http.Client{}
 		r, err = client.Do(req)
 	} else {
-		sign(s.Auth, "GET", path, params, url_.Host)
+		sign(auth, "GET", path, params, url_.Host)
 		url_.RawQuery = multimap(params).Encode()
-		r, err = http.Get(url_.String())
+		var req *http.Request
+		req, err = http.NewRequest("GET", url_.String(), nil)
+		if err != nil {
+			return err
+		}
+		if s.ctx != nil {
+			req = req.WithContext(s.ctx)
+		}
+		r, err = http.DefaultClient.Do(req)
 	}
 
 	if debug {
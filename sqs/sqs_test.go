@@ -375,6 +375,38 @@ func (s *S) TestChangeMessageVisibility(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *S) TestChangeMessageVisibilityBatch(c *C) {
+	testServer.PrepareResponse(200, nil, TestChangeMessageVisibilityBatchXmlOK)
+
+	q := &Queue{s.sqs, testServer.URL + "/123456789012/testQueue/"}
+
+	msgList := []Message{
+		{MessageId: "msg1", ReceiptHandle: "handle1"},
+		{MessageId: "msg2", ReceiptHandle: "handle2"},
+	}
+	resp, err := q.ChangeMessageVisibilityBatch(msgList, 45)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.URL.Path, Equals, "/123456789012/testQueue/")
+	c.Assert(req.Form["Action"], DeepEquals, []string{"ChangeMessageVisibilityBatch"})
+	c.Assert(req.Form["ChangeMessageVisibilityBatchRequestEntry.1.Id"], DeepEquals, []string{"msg1"})
+	c.Assert(req.Form["ChangeMessageVisibilityBatchRequestEntry.1.ReceiptHandle"], DeepEquals, []string{"handle1"})
+	c.Assert(req.Form["ChangeMessageVisibilityBatchRequestEntry.2.ReceiptHandle"], DeepEquals, []string{"handle2"})
+	c.Assert(req.Form["ChangeMessageVisibilityBatchRequestEntry.2.VisibilityTimeout"], DeepEquals, []string{"45"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.ChangeMessageVisibilityBatchResult, HasLen, 1)
+	c.Assert(resp.ChangeMessageVisibilityBatchResult[0].Id, Equals, "msg1")
+	c.Assert(resp.Failed, DeepEquals, []BatchResultErrorEntry{{
+		Id:          "msg2",
+		SenderFault: true,
+		Code:        "ReceiptHandleIsInvalid",
+		Message:     "The input receipt handle is invalid.",
+	}})
+	c.Assert(resp.ResponseMetadata.RequestId, Equals, "ca9668f7-ab1b-4f7a-8859-f15747ab17a7")
+}
+
 func (s *S) TestGetQueueAttributes(c *C) {
 	testServer.PrepareResponse(200, nil, TestGetQueueAttributesXmlOK)
 
@@ -7,6 +7,7 @@
 package sts
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"log"
@@ -24,7 +25,9 @@ import (
 type STS struct {
 	aws.Auth
 	aws.Region
-	private byte // Reserve the right of using private data.
+	provider aws.CredentialsProvider
+	ctx      context.Context
+	private  byte // Reserve the right of using private data.
 }
 
 // New creates a new STS Client.
@@ -32,9 +35,37 @@ type STS struct {
 func New(auth aws.Auth, region aws.Region) *STS {
 	// Make sure we can run the package tests
 	if region.Name == "" {
-		return &STS{auth, region, 0}
+		return &STS{Auth: auth, Region: region}
 	}
-	return &STS{auth, aws.Regions["us-east-1"], 0}
+	return &STS{Auth: auth, Region: aws.Regions["us-east-1"]}
+}
+
+// NewWithProvider creates a new STS whose requests are signed with the
+// credentials supplied by provider at the time they are made.
+func NewWithProvider(provider aws.CredentialsProvider, region aws.Region) *STS {
+	c := New(aws.Auth{}, region)
+	c.provider = provider
+	return c
+}
+
+// credentials returns the credentials to sign the next request with.
+func (sts *STS) credentials() (aws.Auth, error) {
+	if sts.provider == nil {
+		return sts.Auth.Current(), nil
+	}
+	return sts.provider.Credentials()
+}
+
+// WithContext returns a copy of sts whose requests are made with ctx.
+// Cancelling ctx, or reaching its deadline, aborts any request in
+// flight.
+func (sts *STS) WithContext(ctx context.Context) *STS {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *sts
+	c.ctx = ctx
+	return &c
 }
 
 const debug = false
@@ -68,7 +99,17 @@ type xmlErrors struct {
 	Errors    []Error `xml:"Error"`
 }
 
+// query makes the request described by params, retrying it once with
+// fresh credentials if the ones it was signed with had expired.
 func (sts *STS) query(params map[string]string, resp interface{}) error {
+	err := sts.queryOnce(params, resp)
+	if e, ok := err.(*Error); ok && aws.ExpireCredentials(e.Code, sts.provider, sts.Auth) {
+		err = sts.queryOnce(params, resp)
+	}
+	return err
+}
+
+func (sts *STS) queryOnce(params map[string]string, resp interface{}) error {
 	params["Version"] = "2011-06-15"
 
 	data := strings.NewReader(multimap(params).Encode())
@@ -80,17 +121,24 @@ func (sts *STS) query(params map[string]string, resp interface{}) error {
 
 	hreq.Header.Set("Content-Type", "application/x-www-form-urlencoded; param=value")
 
-	token := sts.Auth.Token()
+	auth, err := sts.credentials()
+	if err != nil {
+		return err
+	}
+	token := auth.Token()
 	if token != "" {
 		hreq.Header.Set("X-Amz-Security-Token", token)
 	}
 
-	signer := aws.NewV4Signer(sts.Auth, "sts", sts.Region)
+	signer := aws.NewV4Signer(auth, "sts", sts.Region)
 	signer.Sign(hreq)
 
 	if debug {
 		log.Printf("%v -> {\n", hreq)
 	}
+	if sts.ctx != nil {
+		hreq = hreq.WithContext(sts.ctx)
+	}
 	r, err := http.DefaultClient.Do(hreq)
 
 	if err != nil {
@@ -200,6 +248,97 @@ func (sts *STS) AssumeRole(options *AssumeRoleParams) (resp *AssumeRoleResult, e
 	return resp, nil
 }
 
+// AssumeRoleProvider is an aws.CredentialsProvider supplying the
+// temporary credentials obtained by assuming a role.
+//
+// The role is assumed anew every time credentials are requested, so the
+// provider is best used through the aws.RefreshingProvider returned by
+// NewAssumeRoleProvider.
+type AssumeRoleProvider struct {
+	STS    *STS
+	Params AssumeRoleParams
+}
+
+// NewAssumeRoleProvider returns a provider assuming the role described by
+// params through sts, whose own credentials must allow it to do so. The
+// credentials obtained are cached and renewed before they expire.
+func NewAssumeRoleProvider(sts *STS, params AssumeRoleParams) *aws.RefreshingProvider {
+	return aws.NewRefreshingProvider(&AssumeRoleProvider{STS: sts, Params: params})
+}
+
+func (p *AssumeRoleProvider) Credentials() (aws.Auth, error) {
+	params := p.Params
+	if params.RoleSessionName == "" {
+		params.RoleSessionName = fmt.Sprintf("goamz-%d", time.Now().UnixNano())
+	}
+	resp, err := p.STS.AssumeRole(&params)
+	if err != nil {
+		return aws.Auth{}, err
+	}
+	c := resp.Credentials
+	return *aws.NewAuth(c.AccessKeyId, c.SecretAccessKey, c.SessionToken, c.Expiration), nil
+}
+
+// NewProfileProvider returns a provider supplying the credentials of the
+// named profile in the shared credentials and config files; see
+// aws.LoadProfile.
+//
+// A profile with a role_arn gets its credentials by assuming that role
+// with the credentials of its source_profile, which may itself assume a
+// role. The credentials obtained are renewed before they expire, and
+// those of profiles holding their own are read again on every call, as
+// aws.SharedCredentialsProvider does. A region set but unknown is an
+// error; without one, roles are assumed through us-east-1.
+func NewProfileProvider(name string) (aws.CredentialsProvider, error) {
+	return newProfileProvider(name, nil)
+}
+
+// newProfileProvider implements NewProfileProvider, following the chain
+// of source profiles through seen.
+func newProfileProvider(name string, seen []string) (aws.CredentialsProvider, error) {
+	profile, err := aws.LoadProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range seen {
+		if n == profile.Name {
+			return nil, fmt.Errorf("profile %q: source_profile loop: %s -> %s", seen[0], strings.Join(seen, " -> "), n)
+		}
+	}
+	if profile.RoleARN == "" {
+		if !profile.HasCredentials() {
+			return nil, fmt.Errorf("profile %q has no credentials and no role_arn", profile.Name)
+		}
+		return &aws.SharedCredentialsProvider{Profile: profile.Name}, nil
+	}
+
+	var source aws.CredentialsProvider
+	if profile.SourceProfile == profile.Name && profile.HasCredentials() {
+		// The profile assumes the role with its own credentials.
+		source = &aws.SharedCredentialsProvider{Profile: profile.Name}
+	} else {
+		source, err = newProfileProvider(profile.SourceProfile, append(seen, profile.Name))
+		if err != nil {
+			return nil, err
+		}
+	}
+	// STS is global, so us-east-1 will do if no region is set.
+	region := aws.USEast
+	if profile.RegionName() != "" {
+		region, err = profile.DefaultRegion()
+		if err != nil {
+			return nil, err
+		}
+	}
+	params := AssumeRoleParams{
+		DurationSeconds: profile.DurationSeconds,
+		ExternalId:      profile.ExternalId,
+		RoleArn:         profile.RoleARN,
+		RoleSessionName: profile.RoleSessionName,
+	}
+	return NewAssumeRoleProvider(NewWithProvider(source, region), params), nil
+}
+
 // FederatedUser presents dentifiers for the federated user that is associated with the credentials.
 //
 // See http://goo.gl/uPtr7V for more details